@@ -0,0 +1,199 @@
+package arf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// ErrNotFeedbackReport is returned when the message is not a multipart/report
+// of report-type feedback-report.
+var ErrNotFeedbackReport = errors.New("not a feedback report")
+
+// ErrNotAuthFailure is returned when the feedback report is not an
+// authentication failure report, e.g. an abuse or fraud report.
+var ErrNotAuthFailure = errors.New("not an authentication failure report")
+
+// ParseFailureReport parses an email message containing a DMARC failure report.
+func ParseFailureReport(r io.Reader) (*FailureReport, error) {
+	message, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading message: %w", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing content type: %w", err)
+	}
+
+	if mediaType != "multipart/report" || !strings.EqualFold(params["report-type"], "feedback-report") {
+		return nil, fmt.Errorf("%w: content type is %s", ErrNotFeedbackReport, mediaType)
+	}
+
+	var report FailureReport
+	var foundFeedback bool
+
+	reader := multipart.NewReader(message.Body, params["boundary"])
+	for {
+		part, err := reader.NextRawPart()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return nil, fmt.Errorf("reading part: %w", err)
+		}
+
+		partType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if err != nil {
+			// RFC 2045 says a missing content type defaults to text/plain.
+			partType = "text/plain"
+		}
+
+		content, err := io.ReadAll(decodeTransferEncoding(part))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s part: %w", partType, err)
+		}
+
+		switch partType {
+		case "text/plain":
+			if report.Description == "" {
+				report.Description = strings.TrimSpace(string(content))
+			}
+		case "message/feedback-report":
+			err = parseFeedbackFields(content, &report)
+			if err != nil {
+				return nil, err
+			}
+			foundFeedback = true
+		case "message/rfc822", "text/rfc822-headers":
+			report.OriginalHeaders, report.RawOriginalHeaders = parseOriginalHeaders(content)
+		}
+	}
+
+	if !foundFeedback {
+		return nil, fmt.Errorf("%w: missing message/feedback-report part", ErrNotFeedbackReport)
+	}
+
+	if !strings.EqualFold(report.FeedbackType, FeedbackTypeAuthFailure) {
+		return nil, fmt.Errorf("%w: feedback type is %s", ErrNotAuthFailure, report.FeedbackType)
+	}
+
+	return &report, nil
+}
+
+func decodeTransferEncoding(part *multipart.Part) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(part.Header.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: part})
+	case "quoted-printable":
+		return quotedprintable.NewReader(part)
+	default:
+		return part
+	}
+}
+
+// newlineStripper removes the line breaks of base64 content, which
+// encoding/base64 does not tolerate in the middle of a quantum.
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (n *newlineStripper) Read(p []byte) (int, error) {
+	for {
+		read, err := n.r.Read(p)
+		kept := 0
+		for _, b := range p[:read] {
+			if b != '\r' && b != '\n' {
+				p[kept] = b
+				kept++
+			}
+		}
+
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
+
+func parseFeedbackFields(content []byte, report *FailureReport) error {
+	// The fields are formatted like a message header that ends with the part.
+	content = append(bytes.TrimRight(content, "\r\n"), "\r\n\r\n"...)
+	fields, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(content))).ReadMIMEHeader()
+	if err != nil {
+		return fmt.Errorf("parsing feedback report fields: %w", err)
+	}
+
+	report.FeedbackType = fields.Get("Feedback-Type")
+	report.UserAgent = fields.Get("User-Agent")
+	report.Version = fields.Get("Version")
+	report.OriginalMailFrom = trimAngleBrackets(fields.Get("Original-Mail-From"))
+	for _, rcptTo := range fields.Values("Original-Rcpt-To") {
+		report.OriginalRcptTo = append(report.OriginalRcptTo, trimAngleBrackets(rcptTo))
+	}
+	report.ReportingMTA = fields.Get("Reporting-Mta")
+	report.SourceIP = fields.Get("Source-Ip")
+	report.AuthFailure = strings.ToLower(fields.Get("Auth-Failure"))
+	report.DeliveryResult = strings.ToLower(fields.Get("Delivery-Result"))
+	report.ReportedDomain = fields.Values("Reported-Domain")
+	report.ReportedURI = fields.Values("Reported-Uri")
+	report.AuthenticationResults = fields.Values("Authentication-Results")
+	report.DKIMDomain = fields.Get("Dkim-Domain")
+	report.DKIMIdentity = fields.Get("Dkim-Identity")
+	report.DKIMSelector = fields.Get("Dkim-Selector")
+	report.DKIMCanonicalizedHeader = fields.Get("Dkim-Canonicalized-Header")
+	report.DKIMCanonicalizedBody = fields.Get("Dkim-Canonicalized-Body")
+	report.SPFDNS = fields.Get("Spf-Dns")
+
+	for _, alignment := range strings.Split(fields.Get("Identity-Alignment"), ",") {
+		if alignment = strings.ToLower(strings.TrimSpace(alignment)); alignment != "" {
+			report.IdentityAlignment = append(report.IdentityAlignment, alignment)
+		}
+	}
+
+	report.Incidents = 1
+	if incidents := fields.Get("Incidents"); incidents != "" {
+		report.Incidents, err = strconv.ParseInt(incidents, 10, 64)
+		if err != nil {
+			return fmt.Errorf("parsing Incidents: %w", err)
+		}
+	}
+
+	if arrivalDate := fields.Get("Arrival-Date"); arrivalDate != "" {
+		report.ArrivalDate, err = mail.ParseDate(arrivalDate)
+		if err != nil {
+			return fmt.Errorf("parsing Arrival-Date: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// parseOriginalHeaders parses the header section of the original message,
+// ignoring the body of a message/rfc822 part.
+func parseOriginalHeaders(content []byte) (mail.Header, string) {
+	content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	raw, _, _ := bytes.Cut(content, []byte("\n\n"))
+	raw = bytes.TrimSpace(raw)
+
+	message, err := mail.ReadMessage(bytes.NewReader(append(bytes.Clone(raw), "\n\n"...)))
+	if err != nil {
+		return mail.Header{}, string(raw)
+	}
+
+	return message.Header, string(raw)
+}
+
+func trimAngleBrackets(address string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(address), "<"), ">")
+}
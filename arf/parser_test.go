@@ -0,0 +1,144 @@
+package arf_test
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aldy505/mailweave/arf"
+)
+
+func TestParseFailureReport(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("dkim failure", func(t *testing.T) {
+		f, err := os.Open(path.Join(pwd, "../testdata/arf/dkim-failure.eml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		report, err := arf.ParseFailureReport(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if report.FeedbackType != arf.FeedbackTypeAuthFailure {
+			t.Errorf("FeedbackType = %s, want %s", report.FeedbackType, arf.FeedbackTypeAuthFailure)
+		}
+		if !strings.HasPrefix(report.Description, "This is an authentication failure report") {
+			t.Errorf("Description = %s, want the human readable part", report.Description)
+		}
+		if report.AuthFailure != "dkim" {
+			t.Errorf("AuthFailure = %s, want dkim", report.AuthFailure)
+		}
+		if len(report.IdentityAlignment) != 1 || report.IdentityAlignment[0] != "spf" {
+			t.Errorf("IdentityAlignment = %v, want [spf]", report.IdentityAlignment)
+		}
+		if report.SourceIP != "192.0.2.5" {
+			t.Errorf("SourceIP = %s, want 192.0.2.5", report.SourceIP)
+		}
+		if len(report.ReportedDomain) != 1 || report.ReportedDomain[0] != "example.com" {
+			t.Errorf("ReportedDomain = %v, want [example.com]", report.ReportedDomain)
+		}
+		if report.DKIMDomain != "example.com" {
+			t.Errorf("DKIMDomain = %s, want example.com", report.DKIMDomain)
+		}
+		if report.DKIMSelector != "outgoing-smtp-1" {
+			t.Errorf("DKIMSelector = %s, want outgoing-smtp-1", report.DKIMSelector)
+		}
+		if report.OriginalMailFrom != "sender@example.com" {
+			t.Errorf("OriginalMailFrom = %s, want sender@example.com", report.OriginalMailFrom)
+		}
+		if report.ReportingMTA != "dns; mx.example.net" {
+			t.Errorf("ReportingMTA = %s, want dns; mx.example.net", report.ReportingMTA)
+		}
+		if report.DeliveryResult != "reject" {
+			t.Errorf("DeliveryResult = %s, want reject", report.DeliveryResult)
+		}
+		if report.Incidents != 1 {
+			t.Errorf("Incidents = %d, want 1", report.Incidents)
+		}
+		if !report.ArrivalDate.Equal(time.Date(2025, time.May, 14, 7, 59, 51, 0, time.UTC)) {
+			t.Errorf("ArrivalDate = %s, want 2025-05-14 07:59:51 UTC", report.ArrivalDate)
+		}
+		if len(report.AuthenticationResults) != 1 {
+			t.Errorf("len(AuthenticationResults) = %d, want 1", len(report.AuthenticationResults))
+		}
+		if report.OriginalHeaders.Get("Subject") != "Earn money" {
+			t.Errorf("original Subject = %s, want Earn money", report.OriginalHeaders.Get("Subject"))
+		}
+		if !strings.HasPrefix(report.RawOriginalHeaders, "Received: from smtp.example.com") {
+			t.Errorf("RawOriginalHeaders = %s, want the original header section", report.RawOriginalHeaders)
+		}
+	})
+
+	t.Run("base64 feedback report and message/rfc822", func(t *testing.T) {
+		const message = "From: dmarc-failure@example.net\r\n" +
+			"Content-Type: multipart/report; report-type=feedback-report; boundary=b\r\n" +
+			"\r\n" +
+			"--b\r\n" +
+			"Content-Type: message/feedback-report\r\n" +
+			"Content-Transfer-Encoding: base64\r\n" +
+			"\r\n" +
+			// Feedback-Type: auth-failure\r\nAuth-Failure: spf\r\nIncidents: 3\r\n
+			"RmVlZGJhY2stVHlwZTogYXV0aC1mYWlsdXJlDQpBdXRoLUZhaWx1cmU6IHNwZg0KSW5jaWRl\r\n" +
+			"bnRzOiAzDQo=\r\n" +
+			"--b\r\n" +
+			"Content-Type: message/rfc822\r\n" +
+			"\r\n" +
+			"From: sender@example.com\r\n" +
+			"Subject: Hello\r\n" +
+			"\r\n" +
+			"The body is ignored.\r\n" +
+			"--b--\r\n"
+
+		report, err := arf.ParseFailureReport(strings.NewReader(message))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if report.AuthFailure != "spf" {
+			t.Errorf("AuthFailure = %s, want spf", report.AuthFailure)
+		}
+		if report.Incidents != 3 {
+			t.Errorf("Incidents = %d, want 3", report.Incidents)
+		}
+		if report.OriginalHeaders.Get("Subject") != "Hello" {
+			t.Errorf("original Subject = %s, want Hello", report.OriginalHeaders.Get("Subject"))
+		}
+		if strings.Contains(report.RawOriginalHeaders, "body") {
+			t.Errorf("RawOriginalHeaders = %s, want the header section only", report.RawOriginalHeaders)
+		}
+	})
+
+	t.Run("abuse report", func(t *testing.T) {
+		const message = "Content-Type: multipart/report; report-type=feedback-report; boundary=b\r\n" +
+			"\r\n" +
+			"--b\r\n" +
+			"Content-Type: message/feedback-report\r\n" +
+			"\r\n" +
+			"Feedback-Type: abuse\r\n" +
+			"--b--\r\n"
+
+		_, err := arf.ParseFailureReport(strings.NewReader(message))
+		if !errors.Is(err, arf.ErrNotAuthFailure) {
+			t.Errorf("err = %v, want %v", err, arf.ErrNotAuthFailure)
+		}
+	})
+
+	t.Run("not a report", func(t *testing.T) {
+		const message = "Content-Type: text/plain\r\n\r\nHello\r\n"
+
+		_, err := arf.ParseFailureReport(strings.NewReader(message))
+		if !errors.Is(err, arf.ErrNotFeedbackReport) {
+			t.Errorf("err = %v, want %v", err, arf.ErrNotFeedbackReport)
+		}
+	})
+}
@@ -0,0 +1,71 @@
+// Package arf parses DMARC failure reports, also known as forensic reports,
+// sent in the Abuse Reporting Format described in RFC 5965 and RFC 6591.
+package arf
+
+import (
+	"net/mail"
+	"time"
+)
+
+// FeedbackTypeAuthFailure is the Feedback-Type of DMARC failure reports.
+const FeedbackTypeAuthFailure = "auth-failure"
+
+// FailureReport is a parsed message/feedback-report of type auth-failure.
+type FailureReport struct {
+	// The human readable part of the report.
+	Description string
+
+	// The type of feedback being reported, always "auth-failure" for DMARC.
+	FeedbackType string
+	// The name and version of the software that generated the report.
+	UserAgent string
+	// The version of the feedback report format, currently "1".
+	Version string
+
+	// The RFC5321.MailFrom of the original message.
+	OriginalMailFrom string
+	// The RFC5321.RcptTo of the original message.
+	OriginalRcptTo []string
+	// The time the original message was received by the reporter.
+	ArrivalDate time.Time
+	// The MTA that generated the report, e.g. "dns; mx.example.net".
+	ReportingMTA string
+	// The IP address of the host that sent the original message.
+	SourceIP string
+	// The number of incidents this report represents.
+	Incidents int64
+
+	// The authentication mechanism that failed: "adsp", "bodyhash", "dkim",
+	// "dmarc", "revoked", "signature" or "spf".
+	AuthFailure string
+	// The mechanisms whose identifiers were aligned, e.g. "dkim", "spf" or "none".
+	IdentityAlignment []string
+	// The final disposition of the message: "delivered", "spam", "policy",
+	// "reject" or "other".
+	DeliveryResult string
+	// The domains the report is about.
+	ReportedDomain []string
+	// The URIs found in the original message that the report is about.
+	ReportedURI []string
+	// The Authentication-Results header fields of the original message.
+	AuthenticationResults []string
+
+	// The "d=" tag of the failed DKIM signature.
+	DKIMDomain string
+	// The "i=" tag of the failed DKIM signature.
+	DKIMIdentity string
+	// The "s=" tag of the failed DKIM signature.
+	DKIMSelector string
+	// The canonicalized header of the failed DKIM signature, base64 encoded.
+	DKIMCanonicalizedHeader string
+	// The canonicalized body of the failed DKIM signature, base64 encoded.
+	DKIMCanonicalizedBody string
+	// The SPF record the SPF failure was evaluated against.
+	SPFDNS string
+
+	// The header of the original message, either from a message/rfc822 or a
+	// text/rfc822-headers part.
+	OriginalHeaders mail.Header
+	// The raw header section of the original message, as attached to the report.
+	RawOriginalHeaders string
+}
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"os"
 
+	"github.com/aldy505/mailweave/mailer"
 	"github.com/kelseyhightower/envconfig"
 )
 
@@ -43,6 +44,20 @@ type Config struct {
 	IMAPInsecureSkipVerify bool   `envconfig:"IMAP_INSECURE_SKIP_VERIFY" default:"false"`
 }
 
+// Mailer returns the SMTP client configured by the SMTP_* settings.
+func (c Config) Mailer() *mailer.SMTP {
+	return &mailer.SMTP{
+		Hostname:           c.SmtpHostname,
+		Port:               c.SmtpPort,
+		Username:           c.SmtpUsername,
+		Password:           c.SmtpPassword,
+		From:               c.SmtpFrom,
+		StartTLS:           c.SmtpStartTLS,
+		TLS:                c.SmtpTLS,
+		InsecureSkipVerify: c.SmtpInsecureSkipVerify,
+	}
+}
+
 func main() {
 	var config Config
 	err := envconfig.Process("", &config)
@@ -1,8 +1,12 @@
 // Package datastore provides interfaces in which is used to persist the data of DMARC and TLS-RPT reports.
 // The implementation should implement:
 //  1. For TLS-RPT reports: mailweave.TlsRptMonitoringReports and mailweave.TlsRptMonitoringSources
-//  2. For DMARC reports: mailweave.DmarcMonitoringReports and mailweave.DmarcMonitoringSources
-//  3. If they require a certain database migration to be executed: Migrator
+//  2. For DMARC reports: mailweave.DmarcMonitoringReports, mailweave.DmarcMonitoringReportRows and mailweave.DmarcMonitoringSources
+//  3. For DMARC failure reports: mailweave.DmarcFailureMonitoringReports
+//  4. For the domains being monitored: mailweave.ManagedDomains
+//  5. For the history of the published DNS records: mailweave.DnsRecordSnapshots
+//  6. For the MTA-STS policies served for the managed domains: mailweave.MtaStsPolicies
+//  7. If they require a certain database migration to be executed: Migrator
 package datastore
 
 import "context"
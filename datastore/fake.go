@@ -3,6 +3,8 @@ package datastore
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/aldy505/mailweave"
@@ -10,18 +12,31 @@ import (
 
 // FakeDatastore implements mailweave.TlsRptMonitoringReports,
 // mailweave.TlsRptMonitoringSources, mailweave.DmarcMonitoringReports,
-// and mailweave.DmarcMonitoringSources. It should be used for testing purposes.
+// mailweave.DmarcMonitoringReportRows, mailweave.DmarcMonitoringSources,
+// mailweave.DmarcFailureMonitoringReports, mailweave.ManagedDomains,
+// mailweave.DnsRecordSnapshots and mailweave.MtaStsPolicies. It should be used
+// for testing purposes.
 type FakeDatastore struct {
-	TlsRptReports []mailweave.TlsRptReport
-	TlsRptSources []mailweave.TlsRptSources
-	DmarcReports  []mailweave.DmarcReport
-	DmarcSources  []mailweave.DmarcSources
+	TlsRptReports       []mailweave.TlsRptReport
+	TlsRptSources       []mailweave.TlsRptSources
+	DmarcReports        []mailweave.DmarcReport
+	DmarcSources        []mailweave.DmarcSources
+	DmarcFailureReports []mailweave.DmarcFailureReport
+	// Domains maps the managed domains to their owner.
+	Domains            map[string]string
+	DnsRecordSnapshots []mailweave.DnsRecordSnapshot
+	MtaStsPolicies     []mailweave.MtaStsPolicy
 }
 
 var _ mailweave.TlsRptMonitoringReports = (*FakeDatastore)(nil)
 var _ mailweave.TlsRptMonitoringSources = (*FakeDatastore)(nil)
 var _ mailweave.DmarcMonitoringReports = (*FakeDatastore)(nil)
 var _ mailweave.DmarcMonitoringSources = (*FakeDatastore)(nil)
+var _ mailweave.DmarcMonitoringReportRows = (*FakeDatastore)(nil)
+var _ mailweave.DmarcFailureMonitoringReports = (*FakeDatastore)(nil)
+var _ mailweave.ManagedDomains = (*FakeDatastore)(nil)
+var _ mailweave.DnsRecordSnapshots = (*FakeDatastore)(nil)
+var _ mailweave.MtaStsPolicies = (*FakeDatastore)(nil)
 
 // GetDmarcSources implements mailweave.DmarcMonitoringSources.
 func (f *FakeDatastore) GetDmarcSources(ctx context.Context, domain string) ([]mailweave.DmarcSources, error) {
@@ -297,6 +312,52 @@ func (f *FakeDatastore) WriteDmarcReport(ctx context.Context, domain string, rep
 	return nil
 }
 
+// WriteDmarcReportRows implements mailweave.DmarcMonitoringReportRows.
+func (f *FakeDatastore) WriteDmarcReportRows(ctx context.Context, domain string, reportId string, rows []mailweave.DmarcReportRow) error {
+	for i, report := range f.DmarcReports {
+		if report.DomainOwner == domain && report.ReportId == reportId {
+			f.DmarcReports[i].Rows = append(f.DmarcReports[i].Rows, rows...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("report not found")
+}
+
+// GetDmarcFailureReports implements mailweave.DmarcFailureMonitoringReports.
+func (f *FakeDatastore) GetDmarcFailureReports(ctx context.Context, domain string) ([]mailweave.DmarcFailureReport, error) {
+	var reports []mailweave.DmarcFailureReport
+
+	for _, report := range f.DmarcFailureReports {
+		if report.DomainOwner == domain {
+			reports = append(reports, report)
+		}
+	}
+
+	return reports, nil
+}
+
+// GetDmarcFailureReportById implements mailweave.DmarcFailureMonitoringReports.
+func (f *FakeDatastore) GetDmarcFailureReportById(ctx context.Context, domain string, reportId string) (mailweave.DmarcFailureReport, error) {
+	for _, report := range f.DmarcFailureReports {
+		if report.DomainOwner == domain && report.ReportId == reportId {
+			return report, nil
+		}
+	}
+
+	return mailweave.DmarcFailureReport{}, fmt.Errorf("report not found")
+}
+
+// WriteDmarcFailureReport implements mailweave.DmarcFailureMonitoringReports.
+func (f *FakeDatastore) WriteDmarcFailureReport(ctx context.Context, domain string, report mailweave.DmarcFailureReport) error {
+	if len(f.DmarcFailureReports) == 0 {
+		f.DmarcFailureReports = make([]mailweave.DmarcFailureReport, 0)
+	}
+	report.DomainOwner = domain
+	f.DmarcFailureReports = append(f.DmarcFailureReports, report)
+	return nil
+}
+
 // GetTlsRptSources implements mailweave.TlsRptMonitoringSources.
 func (f *FakeDatastore) GetTlsRptSources(ctx context.Context, domain string) ([]mailweave.TlsRptSources, error) {
 	var sources []mailweave.TlsRptSources
@@ -423,3 +484,80 @@ func (f *FakeDatastore) WriteTlsRptReport(ctx context.Context, domain string, re
 	f.TlsRptReports = append(f.TlsRptReports, report)
 	return nil
 }
+
+// GetDomainOwner implements mailweave.ManagedDomains.
+func (f *FakeDatastore) GetDomainOwner(ctx context.Context, domain string) (string, error) {
+	owner, ok := f.Domains[strings.ToLower(strings.TrimSuffix(domain, "."))]
+	if !ok {
+		return "", mailweave.ErrDomainNotManaged
+	}
+
+	return owner, nil
+}
+
+// GetManagedDomains implements mailweave.ManagedDomains.
+func (f *FakeDatastore) GetManagedDomains(ctx context.Context) ([]string, error) {
+	domains := make([]string, 0, len(f.Domains))
+	for domain := range f.Domains {
+		domains = append(domains, domain)
+	}
+	slices.Sort(domains)
+
+	return domains, nil
+}
+
+// GetLatestDnsRecordSnapshot implements mailweave.DnsRecordSnapshots.
+func (f *FakeDatastore) GetLatestDnsRecordSnapshot(ctx context.Context, domain string, recordType mailweave.DnsRecordType) (mailweave.DnsRecordSnapshot, error) {
+	for i := len(f.DnsRecordSnapshots) - 1; i >= 0; i-- {
+		snapshot := f.DnsRecordSnapshots[i]
+		if snapshot.Domain == domain && snapshot.RecordType == recordType {
+			return snapshot, nil
+		}
+	}
+
+	return mailweave.DnsRecordSnapshot{}, mailweave.ErrNoDnsRecordSnapshot
+}
+
+// GetDnsRecordSnapshots implements mailweave.DnsRecordSnapshots.
+func (f *FakeDatastore) GetDnsRecordSnapshots(ctx context.Context, domain string) ([]mailweave.DnsRecordSnapshot, error) {
+	var snapshots []mailweave.DnsRecordSnapshot
+	for _, snapshot := range f.DnsRecordSnapshots {
+		if snapshot.Domain == domain {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+
+	return snapshots, nil
+}
+
+// WriteDnsRecordSnapshot implements mailweave.DnsRecordSnapshots.
+func (f *FakeDatastore) WriteDnsRecordSnapshot(ctx context.Context, domain string, snapshot mailweave.DnsRecordSnapshot) error {
+	snapshot.Domain = domain
+	f.DnsRecordSnapshots = append(f.DnsRecordSnapshots, snapshot)
+	return nil
+}
+
+// GetMtaStsPolicy implements mailweave.MtaStsPolicies.
+func (f *FakeDatastore) GetMtaStsPolicy(ctx context.Context, domain string) (mailweave.MtaStsPolicy, error) {
+	for _, policy := range f.MtaStsPolicies {
+		if policy.Domain == domain {
+			return policy, nil
+		}
+	}
+
+	return mailweave.MtaStsPolicy{}, mailweave.ErrNoMtaStsPolicy
+}
+
+// WriteMtaStsPolicy implements mailweave.MtaStsPolicies.
+func (f *FakeDatastore) WriteMtaStsPolicy(ctx context.Context, domain string, policy mailweave.MtaStsPolicy) error {
+	policy.Domain = domain
+	for i := range f.MtaStsPolicies {
+		if f.MtaStsPolicies[i].Domain == domain {
+			f.MtaStsPolicies[i] = policy
+			return nil
+		}
+	}
+
+	f.MtaStsPolicies = append(f.MtaStsPolicies, policy)
+	return nil
+}
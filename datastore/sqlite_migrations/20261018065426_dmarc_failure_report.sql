@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE mailweave_dmarc_failure_report (
+    id INTEGER PRIMARY KEY,
+    raw_report TEXT NOT NULL,
+    domain_owner TEXT NOT NULL,
+    report_id TEXT,
+    reporting_mta TEXT,
+    user_agent TEXT,
+    reported_domain TEXT,
+    arrival_date TEXT,
+    incidents INTEGER,
+    received_at TEXT,
+    email_sender TEXT,
+    email_subject TEXT,
+    source_ip TEXT,
+    auth_failure TEXT,
+    identity_alignment TEXT,
+    delivery_result TEXT,
+    dkim_domain TEXT,
+    dkim_selector TEXT,
+    dkim_identity TEXT,
+    spf_dns TEXT,
+    original_mail_from TEXT,
+    original_rcpt_to TEXT,
+    original_header_from TEXT,
+    original_subject TEXT,
+    original_message_id TEXT,
+    original_headers TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX mailweave_dmarc_failure_report_domain_owner_report_id_idx
+    ON mailweave_dmarc_failure_report (domain_owner, report_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE mailweave_dmarc_failure_report;
+-- +goose StatementEnd
@@ -0,0 +1,52 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE mailweave_dmarc_report (
+    id INTEGER PRIMARY KEY,
+    raw_report TEXT NOT NULL,
+    domain_owner TEXT,
+    organization_name TEXT,
+    domain_name TEXT,
+    policy_domain TEXT,
+    report_id TEXT,
+    extra_contact_info TEXT,
+    range_start TEXT,
+    range_end TEXT,
+    email_sender TEXT,
+    email_subject TEXT,
+    report_file_name TEXT,
+    total_emails INTEGER,
+    received_at TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE mailweave_dmarc_report_row (
+    id INTEGER PRIMARY KEY,
+    report_id INTEGER NOT NULL,
+    email_count INTEGER,
+    source_ip TEXT,
+    resolved_hostname TEXT,
+    envelope_to TEXT,
+    envelope_from TEXT,
+    header_from TEXT,
+    spf_domain TEXT,
+    spf_result TEXT,
+    spf_scope TEXT,
+    dkim_domain TEXT,
+    dkim_selector TEXT,
+    dkim_result TEXT,
+    dmarc_spf_aligned BOOLEAN,
+    dmarc_dkim_aligned BOOLEAN,
+    dmarc_inferred_aligned BOOLEAN,
+    dmarc_disposition TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (report_id) REFERENCES mailweave_dmarc_report(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE mailweave_dmarc_report_row;
+DROP TABLE mailweave_dmarc_report;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE mailweave_tls_rpt_report ADD COLUMN report_file_name_mismatches TEXT;
+ALTER TABLE mailweave_dmarc_report ADD COLUMN report_file_name_mismatches TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE mailweave_dmarc_report DROP COLUMN report_file_name_mismatches;
+ALTER TABLE mailweave_tls_rpt_report DROP COLUMN report_file_name_mismatches;
+-- +goose StatementEnd
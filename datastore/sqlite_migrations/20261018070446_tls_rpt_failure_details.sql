@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE mailweave_tls_rpt_report_row ADD COLUMN failure_details TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE mailweave_tls_rpt_report_row DROP COLUMN failure_details;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE mailweave_dns_record_snapshot (
+    id INTEGER PRIMARY KEY,
+    domain TEXT NOT NULL,
+    record_type TEXT NOT NULL,
+    name TEXT NOT NULL,
+    record_values TEXT,
+    fetched_at TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX mailweave_dns_record_snapshot_domain_record_type_idx
+    ON mailweave_dns_record_snapshot (domain, record_type, fetched_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE mailweave_dns_record_snapshot;
+-- +goose StatementEnd
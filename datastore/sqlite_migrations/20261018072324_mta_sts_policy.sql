@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE mailweave_mta_sts_policy (
+    id INTEGER PRIMARY KEY,
+    domain_owner TEXT NOT NULL,
+    domain TEXT NOT NULL UNIQUE,
+    mode TEXT NOT NULL,
+    mx TEXT,
+    max_age INTEGER NOT NULL,
+    policy_id TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE mailweave_mta_sts_policy;
+-- +goose StatementEnd
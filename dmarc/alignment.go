@@ -0,0 +1,65 @@
+package dmarc
+
+import (
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// AlignmentMode is the identifier alignment mode published in the adkim and
+// aspf tags of a DMARC record.
+type AlignmentMode string
+
+const (
+	// AlignmentRelaxed requires the identifiers to share the same organizational domain.
+	AlignmentRelaxed AlignmentMode = "r"
+	// AlignmentStrict requires the identifiers to be the exact same domain.
+	AlignmentStrict AlignmentMode = "s"
+)
+
+// ParseAlignmentMode parses the value of adkim or aspf. As per RFC 7489
+// Section 6.3, an empty or unknown value means relaxed alignment.
+func ParseAlignmentMode(mode string) AlignmentMode {
+	if strings.EqualFold(strings.TrimSpace(mode), string(AlignmentStrict)) {
+		return AlignmentStrict
+	}
+
+	return AlignmentRelaxed
+}
+
+// OrganizationalDomain returns the organizational domain of domain as described
+// in RFC 7489 Section 3.2, using the Public Suffix List embedded in
+// golang.org/x/net/publicsuffix. A domain that is itself a public suffix is
+// returned as-is.
+func OrganizationalDomain(domain string) string {
+	domain = normalizeDomain(domain)
+	if domain == "" {
+		return ""
+	}
+
+	organizationalDomain, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	if err != nil {
+		return domain
+	}
+
+	return organizationalDomain
+}
+
+// Aligned reports whether the authenticated identifier (the DKIM d= domain or
+// the SPF checked domain) is aligned with the RFC5322.From domain.
+func Aligned(mode AlignmentMode, identifier string, headerFrom string) bool {
+	identifier, headerFrom = normalizeDomain(identifier), normalizeDomain(headerFrom)
+	if identifier == "" || headerFrom == "" {
+		return false
+	}
+
+	if mode == AlignmentStrict {
+		return identifier == headerFrom
+	}
+
+	return OrganizationalDomain(identifier) == OrganizationalDomain(headerFrom)
+}
+
+func normalizeDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
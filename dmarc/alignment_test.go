@@ -0,0 +1,63 @@
+package dmarc_test
+
+import (
+	"testing"
+
+	"github.com/aldy505/mailweave/dmarc"
+)
+
+func TestOrganizationalDomain(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   string
+	}{
+		{domain: "example.com", want: "example.com"},
+		{domain: "outgoing-smtp-1.example.com", want: "example.com"},
+		{domain: "ap-southeast-1.amazonses.com", want: "amazonses.com"},
+		{domain: "mail.example.co.uk", want: "example.co.uk"},
+		{domain: "Mail.Example.COM.", want: "example.com"},
+		{domain: "co.uk", want: "co.uk"},
+		{domain: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			if got := dmarc.OrganizationalDomain(tt.domain); got != tt.want {
+				t.Errorf("OrganizationalDomain(%s) = %s, want %s", tt.domain, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAligned(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       dmarc.AlignmentMode
+		identifier string
+		headerFrom string
+		want       bool
+	}{
+		{name: "relaxed same domain", mode: dmarc.AlignmentRelaxed, identifier: "example.com", headerFrom: "example.com", want: true},
+		{name: "relaxed subdomain", mode: dmarc.AlignmentRelaxed, identifier: "outgoing-smtp-1.example.com", headerFrom: "example.com", want: true},
+		{name: "relaxed other domain", mode: dmarc.AlignmentRelaxed, identifier: "amazonses.com", headerFrom: "example.com", want: false},
+		{name: "strict same domain", mode: dmarc.AlignmentStrict, identifier: "Example.com", headerFrom: "example.com", want: true},
+		{name: "strict subdomain", mode: dmarc.AlignmentStrict, identifier: "outgoing-smtp-1.example.com", headerFrom: "example.com", want: false},
+		{name: "public suffix", mode: dmarc.AlignmentRelaxed, identifier: "example.co.uk", headerFrom: "other.co.uk", want: false},
+		{name: "empty identifier", mode: dmarc.AlignmentRelaxed, identifier: "", headerFrom: "example.com", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dmarc.Aligned(tt.mode, tt.identifier, tt.headerFrom); got != tt.want {
+				t.Errorf("Aligned(%s, %s, %s) = %v, want %v", tt.mode, tt.identifier, tt.headerFrom, got, tt.want)
+			}
+		})
+	}
+
+	if got := dmarc.ParseAlignmentMode("s"); got != dmarc.AlignmentStrict {
+		t.Errorf("ParseAlignmentMode(s) = %s, want s", got)
+	}
+	if got := dmarc.ParseAlignmentMode(""); got != dmarc.AlignmentRelaxed {
+		t.Errorf("ParseAlignmentMode() = %s, want r", got)
+	}
+}
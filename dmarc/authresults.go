@@ -0,0 +1,182 @@
+package dmarc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AuthenticationResults is a parsed Authentication-Results header field, as
+// described in RFC 8601.
+type AuthenticationResults struct {
+	// The identifier of the host that performed the authentication checks.
+	AuthServID string
+	Results    []AuthenticationResult
+}
+
+// AuthenticationResult is the result of a single authentication method.
+type AuthenticationResult struct {
+	// The authentication method, e.g. "dkim", "spf" or "dmarc".
+	Method string
+	// The result of the method, e.g. "pass" or "fail".
+	Result string
+	// The optional reason of the result.
+	Reason string
+	// The properties of the result, keyed by "ptype.property", e.g.
+	// "header.d" or "smtp.mailfrom".
+	Properties map[string]string
+}
+
+// ParseAuthenticationResults parses the value of an Authentication-Results
+// header field. Comments are ignored.
+func ParseAuthenticationResults(value string) (AuthenticationResults, error) {
+	statements := splitOutsideQuotes(stripComments(value), ';')
+	if len(statements) == 0 || strings.TrimSpace(statements[0]) == "" {
+		return AuthenticationResults{}, fmt.Errorf("missing authserv-id")
+	}
+
+	// The authserv-id may be followed by a version number.
+	authServID, _, _ := strings.Cut(strings.TrimSpace(statements[0]), " ")
+	results := AuthenticationResults{AuthServID: authServID}
+
+	for _, statement := range statements[1:] {
+		tokens := splitOutsideQuotes(normalizeEquals(statement), ' ', '\t', '\r', '\n')
+		if len(tokens) == 0 {
+			continue
+		}
+
+		if len(tokens) == 1 && strings.EqualFold(tokens[0], "none") {
+			continue
+		}
+
+		methodSpec, resultValue, ok := strings.Cut(tokens[0], "=")
+		if !ok {
+			return AuthenticationResults{}, fmt.Errorf("invalid resinfo %q", strings.TrimSpace(statement))
+		}
+
+		// The method may be followed by a version number, e.g. "dkim/1".
+		method, _, _ := strings.Cut(methodSpec, "/")
+		result := AuthenticationResult{
+			Method:     strings.ToLower(method),
+			Result:     strings.ToLower(unquote(resultValue)),
+			Properties: make(map[string]string),
+		}
+
+		for _, token := range tokens[1:] {
+			key, value, ok := strings.Cut(token, "=")
+			if !ok {
+				continue
+			}
+
+			key = strings.ToLower(key)
+			if key == "reason" {
+				result.Reason = unquote(value)
+				continue
+			}
+
+			result.Properties[key] = unquote(value)
+		}
+
+		results.Results = append(results.Results, result)
+	}
+
+	return results, nil
+}
+
+// stripComments removes the parenthesized comments of a header field value.
+// Comments may be nested, and are not recognized inside quoted strings.
+func stripComments(value string) string {
+	var b strings.Builder
+	depth := 0
+	quoted := false
+	escaped := false
+	for _, r := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"' && depth == 0:
+			quoted = !quoted
+		case r == '(' && !quoted:
+			depth++
+			continue
+		case r == ')' && !quoted && depth > 0:
+			depth--
+			continue
+		}
+
+		if depth == 0 {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// splitOutsideQuotes splits value on any of the separators that are not part
+// of a quoted string. Empty fields are dropped.
+func splitOutsideQuotes(value string, separators ...rune) []string {
+	var fields []string
+	var b strings.Builder
+	quoted := false
+	for _, r := range value {
+		if r == '"' {
+			quoted = !quoted
+		}
+
+		if !quoted && strings.ContainsRune(string(separators), r) {
+			if field := strings.TrimSpace(b.String()); field != "" {
+				fields = append(fields, field)
+			}
+			b.Reset()
+			continue
+		}
+
+		b.WriteRune(r)
+	}
+
+	if field := strings.TrimSpace(b.String()); field != "" {
+		fields = append(fields, field)
+	}
+
+	return fields
+}
+
+// normalizeEquals removes the whitespace around "=" outside of quoted strings.
+func normalizeEquals(value string) string {
+	var b strings.Builder
+	quoted := false
+	runes := []rune(value)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '"' {
+			quoted = !quoted
+		}
+
+		if !quoted && (r == ' ' || r == '\t') {
+			next := i
+			for next < len(runes) && (runes[next] == ' ' || runes[next] == '\t') {
+				next++
+			}
+
+			previousIsEquals := b.Len() > 0 && strings.HasSuffix(b.String(), "=")
+			nextIsEquals := next < len(runes) && runes[next] == '='
+			if previousIsEquals || nextIsEquals {
+				i = next - 1
+				continue
+			}
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		return strings.ReplaceAll(value[1:len(value)-1], `\"`, `"`)
+	}
+
+	return value
+}
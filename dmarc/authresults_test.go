@@ -0,0 +1,82 @@
+package dmarc_test
+
+import (
+	"testing"
+
+	"github.com/aldy505/mailweave/dmarc"
+)
+
+func TestParseAuthenticationResults(t *testing.T) {
+	t.Run("multiple methods", func(t *testing.T) {
+		results, err := dmarc.ParseAuthenticationResults(`mx.example.net 1;
+			dkim=pass (2048-bit key) header.d=example.com header.s=selector1 header.b=abcdef;
+			spf=pass (sender IP is 192.0.2.1) smtp.mailfrom=bounce@mail.example.com;
+			dmarc=pass (p=reject dis=none) header.from=example.com`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if results.AuthServID != "mx.example.net" {
+			t.Errorf("AuthServID = %s, want mx.example.net", results.AuthServID)
+		}
+		if len(results.Results) != 3 {
+			t.Fatalf("len(Results) = %d, want 3", len(results.Results))
+		}
+
+		dkim := results.Results[0]
+		if dkim.Method != "dkim" || dkim.Result != "pass" {
+			t.Errorf("Results[0] = %s=%s, want dkim=pass", dkim.Method, dkim.Result)
+		}
+		if dkim.Properties["header.d"] != "example.com" {
+			t.Errorf("header.d = %s, want example.com", dkim.Properties["header.d"])
+		}
+		if dkim.Properties["header.s"] != "selector1" {
+			t.Errorf("header.s = %s, want selector1", dkim.Properties["header.s"])
+		}
+
+		spf := results.Results[1]
+		if spf.Properties["smtp.mailfrom"] != "bounce@mail.example.com" {
+			t.Errorf("smtp.mailfrom = %s, want bounce@mail.example.com", spf.Properties["smtp.mailfrom"])
+		}
+	})
+
+	t.Run("reason and spacing", func(t *testing.T) {
+		results, err := dmarc.ParseAuthenticationResults(`mx.example.net; DKIM = fail reason="signature \"did\" not verify" header.d = example.com`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(results.Results) != 1 {
+			t.Fatalf("len(Results) = %d, want 1", len(results.Results))
+		}
+		result := results.Results[0]
+		if result.Method != "dkim" || result.Result != "fail" {
+			t.Errorf("Results[0] = %s=%s, want dkim=fail", result.Method, result.Result)
+		}
+		if result.Reason != `signature "did" not verify` {
+			t.Errorf("Reason = %s, want signature \"did\" not verify", result.Reason)
+		}
+		if result.Properties["header.d"] != "example.com" {
+			t.Errorf("header.d = %s, want example.com", result.Properties["header.d"])
+		}
+	})
+
+	t.Run("none", func(t *testing.T) {
+		results, err := dmarc.ParseAuthenticationResults("mx.example.net; none")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results.Results) != 0 {
+			t.Errorf("len(Results) = %d, want 0", len(results.Results))
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := dmarc.ParseAuthenticationResults(""); err == nil {
+			t.Error("expected an error for an empty value")
+		}
+		if _, err := dmarc.ParseAuthenticationResults("mx.example.net; dkim"); err == nil {
+			t.Error("expected an error for a result without a value")
+		}
+	})
+}
@@ -0,0 +1,220 @@
+package dmarc
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/aldy505/mailweave/internal/limits"
+)
+
+// CompressionType represents the container a DMARC aggregate report is delivered in.
+type CompressionType uint8
+
+const (
+	// CompressionTypeNone is a plain XML report.
+	CompressionTypeNone CompressionType = iota
+	// CompressionTypeGZIP is a gzip compressed XML report.
+	CompressionTypeGZIP
+	// CompressionTypeZIP is a zip archive containing one or more XML reports.
+	CompressionTypeZIP
+)
+
+func (c CompressionType) String() string {
+	switch c {
+	case CompressionTypeNone:
+		return "none"
+	case CompressionTypeGZIP:
+		return "gzip"
+	case CompressionTypeZIP:
+		return "zip"
+	default:
+		return "unknown"
+	}
+}
+
+// MediaType returns the MIME type of a report attachment in this container,
+// as listed in RFC 7489 Section 7.2.1.1.
+func (c CompressionType) MediaType() string {
+	switch c {
+	case CompressionTypeGZIP:
+		return "application/gzip"
+	case CompressionTypeZIP:
+		return "application/zip"
+	default:
+		return "text/xml"
+	}
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte{'P', 'K', 0x03, 0x04}
+)
+
+// DetectCompression sniffs the magic bytes at the start of header.
+// Anything that is neither gzip nor zip is treated as plain XML.
+func DetectCompression(header []byte) CompressionType {
+	switch {
+	case bytes.HasPrefix(header, zipMagic):
+		return CompressionTypeZIP
+	case bytes.HasPrefix(header, gzipMagic):
+		return CompressionTypeGZIP
+	default:
+		return CompressionTypeNone
+	}
+}
+
+// ParseFeedbacks parses a DMARC aggregate report attachment without requiring
+// the caller to know how it is packaged. The compression is detected from the
+// magic bytes: zip archives (as sent by Google and Microsoft), gzip files and
+// plain XML are supported.
+//
+// One Feedback is returned per XML document found. Zip members that are not
+// XML documents are skipped. The options are applied to every document.
+//
+// The attachment is subject to the Limits set with WithLimits, or to the
+// DefaultLimits. Exceeding a limit fails with the matching error, such as
+// ErrDecompressedSizeExceeded, which can be checked with errors.Is.
+func ParseFeedbacks(r io.Reader, opts ...ParseOption) ([]Feedback, error) {
+	parseLimits := newParseOptions(opts).limits
+
+	br := bufio.NewReader(r)
+	header, err := br.Peek(len(zipMagic))
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	compression := DetectCompression(header)
+	if compression == CompressionTypeNone {
+		feedback, err := ParseFeedback(br, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Feedback{feedback}, nil
+	}
+
+	compressedCounter := limits.NewCounter(parseLimits.MaxCompressedSize, ErrCompressedSizeExceeded)
+	compressed := compressedCounter.Reader(br)
+	decompressed := limits.NewCounter(parseLimits.MaxDecompressedSize, ErrDecompressedSizeExceeded)
+	decompressed.LimitRatio(compressedCounter, parseLimits.MaxCompressionRatio)
+
+	if compression == CompressionTypeZIP {
+		return parseZipFeedbacks(compressed, decompressed, parseLimits.MaxZipMembers, opts)
+	}
+
+	reader, err := gzip.NewReader(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing gzip: %w", err)
+	}
+	defer reader.Close()
+
+	feedback, err := ParseFeedback(decompressed.Reader(reader), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return []Feedback{feedback}, nil
+}
+
+// parseZipFeedbacks parses the members of the zip archive read from
+// compressed. Every byte decompressed from the archive, including the gzip
+// files nested in it, is counted by decompressed, so the decompressed size
+// limit applies to the whole archive.
+func parseZipFeedbacks(compressed io.Reader, decompressed *limits.Counter, maxMembers int, opts []ParseOption) ([]Feedback, error) {
+	// archive/zip needs random access to read the central directory.
+	content, err := io.ReadAll(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("reading zip: %w", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("opening zip: %w", err)
+	}
+
+	if maxMembers >= 0 && len(archive.File) > maxMembers {
+		return nil, fmt.Errorf("opening zip: %w: %d members, limit is %d", ErrZipMembersExceeded, len(archive.File), maxMembers)
+	}
+
+	var feedbacks []Feedback
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		feedback, ok, err := parseZipMember(file, decompressed, opts)
+		if err != nil {
+			return nil, fmt.Errorf("zip member %s: %w", file.Name, err)
+		}
+
+		if ok {
+			feedback.FromFile = path.Base(file.Name)
+			feedbacks = append(feedbacks, feedback)
+		}
+	}
+
+	if len(feedbacks) == 0 {
+		return nil, fmt.Errorf("zip archive does not contain any XML report")
+	}
+
+	return feedbacks, nil
+}
+
+func parseZipMember(file *zip.File, decompressed *limits.Counter, opts []ParseOption) (Feedback, bool, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return Feedback{}, false, fmt.Errorf("opening: %w", err)
+	}
+	defer rc.Close()
+
+	br := bufio.NewReader(decompressed.Reader(rc))
+
+	// Some reporters put a gzip compressed report inside the zip archive.
+	header, err := br.Peek(len(gzipMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return Feedback{}, false, fmt.Errorf("reading: %w", err)
+	}
+	if DetectCompression(header) == CompressionTypeGZIP {
+		reader, err := gzip.NewReader(br)
+		if err != nil {
+			return Feedback{}, false, fmt.Errorf("decompressing gzip: %w", err)
+		}
+		defer reader.Close()
+
+		br = bufio.NewReader(decompressed.Reader(reader))
+	}
+
+	ok, err := looksLikeXML(br)
+	if err != nil {
+		return Feedback{}, false, fmt.Errorf("reading: %w", err)
+	}
+	if !ok {
+		return Feedback{}, false, nil
+	}
+
+	feedback, err := ParseFeedback(br, opts...)
+	if err != nil {
+		return Feedback{}, false, err
+	}
+
+	return feedback, true, nil
+}
+
+// looksLikeXML reports whether the first non-whitespace character, ignoring
+// a UTF-8 byte order mark, is the start of an XML tag.
+func looksLikeXML(r *bufio.Reader) (bool, error) {
+	header, err := r.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+
+	header = bytes.TrimPrefix(header, []byte("\xef\xbb\xbf"))
+	header = bytes.TrimLeft(header, " \t\r\n")
+	return len(header) > 0 && header[0] == '<', nil
+}
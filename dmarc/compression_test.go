@@ -0,0 +1,150 @@
+package dmarc_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/aldy505/mailweave/dmarc"
+)
+
+func TestDetectCompression(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []byte
+		want   dmarc.CompressionType
+	}{
+		{name: "zip", header: []byte{'P', 'K', 0x03, 0x04}, want: dmarc.CompressionTypeZIP},
+		{name: "gzip", header: []byte{0x1f, 0x8b, 0x08, 0x00}, want: dmarc.CompressionTypeGZIP},
+		{name: "xml", header: []byte("<?xm"), want: dmarc.CompressionTypeNone},
+		{name: "empty", header: nil, want: dmarc.CompressionTypeNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dmarc.DetectCompression(tt.header); got != tt.want {
+				t.Errorf("DetectCompression = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFeedbacks(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	google, err := os.ReadFile(path.Join(pwd, "../testdata/dmarc/google.com!example.com!1747008000!1747094399"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zoho, err := os.ReadFile(path.Join(pwd, "../testdata/dmarc/zoho.com!example.com!1746860400!1746946800.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("plain xml", func(t *testing.T) {
+		feedbacks, err := dmarc.ParseFeedbacks(bytes.NewReader(google))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(feedbacks) != 1 {
+			t.Fatalf("len(feedbacks) = %d, want 1", len(feedbacks))
+		}
+		if feedbacks[0].ReportMetadata.OrgName != "google.com" {
+			t.Errorf("OrgName = %s, want google.com", feedbacks[0].ReportMetadata.OrgName)
+		}
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(zoho); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		feedbacks, err := dmarc.ParseFeedbacks(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(feedbacks) != 1 {
+			t.Fatalf("len(feedbacks) = %d, want 1", len(feedbacks))
+		}
+		if feedbacks[0].ReportMetadata.OrgName != "zoho.com" {
+			t.Errorf("OrgName = %s, want zoho.com", feedbacks[0].ReportMetadata.OrgName)
+		}
+	})
+
+	t.Run("zip with multiple members", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		members := []struct {
+			name    string
+			content []byte
+		}{
+			{name: "google.com!example.com!1747008000!1747094399.xml", content: google},
+			{name: "README.txt", content: []byte("not a report")},
+			{name: "zoho.com!example.com!1746860400!1746946800.xml", content: zoho},
+		}
+		for _, member := range members {
+			f, err := w.Create(member.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.Write(member.content); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		feedbacks, err := dmarc.ParseFeedbacks(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(feedbacks) != 2 {
+			t.Fatalf("len(feedbacks) = %d, want 2", len(feedbacks))
+		}
+		if feedbacks[0].ReportMetadata.OrgName != "google.com" {
+			t.Errorf("OrgName = %s, want google.com", feedbacks[0].ReportMetadata.OrgName)
+		}
+		if feedbacks[0].FromFile != "google.com!example.com!1747008000!1747094399.xml" {
+			t.Errorf("FromFile = %s, want google.com!example.com!1747008000!1747094399.xml", feedbacks[0].FromFile)
+		}
+		if feedbacks[1].ReportMetadata.OrgName != "zoho.com" {
+			t.Errorf("OrgName = %s, want zoho.com", feedbacks[1].ReportMetadata.OrgName)
+		}
+	})
+
+	t.Run("zip without reports", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		f, err := w.Create("README.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte("not a report")); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = dmarc.ParseFeedbacks(&buf)
+		if err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+}
@@ -0,0 +1,376 @@
+package dmarc
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidDNSRecord is returned when a DMARC record cannot be parsed.
+var ErrInvalidDNSRecord = errors.New("invalid dmarc record")
+
+// ReportURI is a destination of the rua or ruf tag of a DMARC record, as
+// defined in RFC 7489 Section 6.2.
+type ReportURI struct {
+	// The URI without the size limit, e.g. "mailto:dmarc@example.com".
+	URI string
+	// The maximum size in bytes of the reports sent to the URI, or zero when
+	// there is no limit.
+	MaxSize int64
+}
+
+// String formats the URI with its size limit, e.g. "mailto:dmarc@example.com!10m".
+func (u ReportURI) String() string {
+	if u.MaxSize == 0 {
+		return u.URI
+	}
+
+	size, unit := u.MaxSize, ""
+	for _, candidate := range []string{"k", "m", "g", "t"} {
+		if size%1024 != 0 {
+			break
+		}
+		size, unit = size/1024, candidate
+	}
+
+	return u.URI + "!" + strconv.FormatInt(size, 10) + unit
+}
+
+// Address returns the email address of a mailto URI, or an empty string for
+// other schemes.
+func (u ReportURI) Address() string {
+	address, ok := strings.CutPrefix(u.URI, "mailto:")
+	if !ok {
+		return ""
+	}
+
+	if unescaped, err := url.PathUnescape(address); err == nil {
+		address = unescaped
+	}
+
+	return address
+}
+
+// ParseReportURI parses a single destination of the rua or ruf tag, with an
+// optional size limit such as "!10m".
+func ParseReportURI(value string) (ReportURI, error) {
+	uri, err := parseReportURI(value)
+	if err != nil {
+		return ReportURI{}, fmt.Errorf("%w: %w", ErrInvalidDNSRecord, err)
+	}
+
+	return uri, nil
+}
+
+func parseReportURI(value string) (ReportURI, error) {
+	value = strings.TrimSpace(value)
+	uri := ReportURI{URI: value}
+
+	if index := strings.LastIndex(value, "!"); index >= 0 {
+		size, err := parseSize(value[index+1:])
+		if err != nil {
+			return ReportURI{}, fmt.Errorf("invalid size limit of %q", value)
+		}
+
+		uri = ReportURI{URI: value[:index], MaxSize: size}
+	}
+
+	parsed, err := url.Parse(uri.URI)
+	if err != nil || parsed.Scheme == "" {
+		return ReportURI{}, fmt.Errorf("%q is not a URI", uri.URI)
+	}
+
+	if strings.EqualFold(parsed.Scheme, "mailto") {
+		uri.URI = "mailto:" + parsed.Opaque
+		address, err := mail.ParseAddress(uri.Address())
+		if err != nil || address.Name != "" || address.Address != uri.Address() {
+			return ReportURI{}, fmt.Errorf("%q is not a valid mailto URI", uri.URI)
+		}
+	}
+
+	return uri, nil
+}
+
+func parseSize(value string) (int64, error) {
+	multiplier := int64(1)
+	if value != "" {
+		switch strings.ToLower(value[len(value)-1:]) {
+		case "k":
+			multiplier = 1 << 10
+		case "m":
+			multiplier = 1 << 20
+		case "g":
+			multiplier = 1 << 30
+		case "t":
+			multiplier = 1 << 40
+		}
+		if multiplier != 1 {
+			value = value[:len(value)-1]
+		}
+	}
+
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size <= 0 || size > (1<<62)/multiplier {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+
+	return size * multiplier, nil
+}
+
+// DNSRecord is a parsed DMARC record, as published in the TXT record of
+// _dmarc.<domain> and defined in RFC 7489 Section 6.3. Tags that are not
+// published hold their default value.
+type DNSRecord struct {
+	// The version of the record, always "DMARC1".
+	Version string
+	// The policy of the domain, one of "none", "quarantine" or "reject".
+	Policy string
+	// The policy of the subdomains, which defaults to Policy.
+	SubdomainPolicy string
+	// The policy of the non-existent subdomains, which defaults to
+	// SubdomainPolicy. Defined by RFC 9091.
+	NonExistentSubdomainPolicy string
+	// The percentage of messages the policy applies to, which defaults to 100.
+	Percentage int
+	// Where aggregate reports are sent to.
+	AggregateReportURIs []ReportURI
+	// Where failure reports are sent to.
+	FailureReportURIs []ReportURI
+	// The DKIM alignment mode, which defaults to relaxed.
+	DKIMAlignment AlignmentMode
+	// The SPF alignment mode, which defaults to relaxed.
+	SPFAlignment AlignmentMode
+	// When failure reports are generated, any of "0", "1", "d" and "s". It
+	// defaults to "0".
+	FailureOptions []string
+	// The formats of the failure reports, which defaults to "afrf".
+	ReportFormats []string
+	// The interval between aggregate reports, which defaults to a day.
+	ReportInterval time.Duration
+}
+
+// ParseDNSRecord parses the value of a DMARC TXT record, e.g.
+// "v=DMARC1; p=reject; rua=mailto:dmarc@example.com". Unknown tags are
+// ignored as required by RFC 7489 Section 6.3, and only the first occurrence
+// of a duplicate tag is used. Use LintDNSRecord to get every problem of a record.
+func ParseDNSRecord(value string) (DNSRecord, error) {
+	var v ValidationResult
+	record := parseDNSRecord(value, &v)
+	if err := v.Err(); err != nil {
+		return DNSRecord{}, fmt.Errorf("%w: %w", ErrInvalidDNSRecord, err)
+	}
+
+	return record, nil
+}
+
+// LintDNSRecord checks the value of a DMARC TXT record. Errors are syntax errors
+// and invalid values that make receivers ignore the record, while Warnings
+// are duplicate tags and settings that are likely mistakes, such as a pct
+// below 100 with p=none or a missing rua.
+func LintDNSRecord(value string) ValidationResult {
+	var v ValidationResult
+	record := parseDNSRecord(value, &v)
+	if !v.Valid() {
+		return v
+	}
+
+	if record.Policy == "none" && record.Percentage < 100 {
+		v.AddWarning("pct", "pct=%d has no effect with p=none", record.Percentage)
+	}
+	if len(record.AggregateReportURIs) == 0 {
+		v.AddWarning("rua", "missing, no aggregate reports will be sent")
+	}
+	if len(record.FailureReportURIs) == 0 && !slices.Equal(record.FailureOptions, []string{"0"}) {
+		v.AddWarning("fo", "has no effect without ruf")
+	}
+
+	return v
+}
+
+func parseDNSRecord(value string, v *ValidationResult) DNSRecord {
+	record := DNSRecord{
+		Percentage:     100,
+		DKIMAlignment:  AlignmentRelaxed,
+		SPFAlignment:   AlignmentRelaxed,
+		FailureOptions: []string{"0"},
+		ReportFormats:  []string{"afrf"},
+		ReportInterval: 24 * time.Hour,
+	}
+
+	// p is checked once rua is known, as a valid rua turns a missing or
+	// invalid p into p=none.
+	var policy string
+
+	seen := make(map[string]bool)
+	position := 0
+	for _, part := range strings.Split(value, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		position++
+
+		name, tagValue, ok := strings.Cut(part, "=")
+		if !ok {
+			v.AddError("record", "%q is not a tag=value pair", part)
+			continue
+		}
+
+		name = strings.ToLower(strings.TrimSpace(name))
+		tagValue = strings.TrimSpace(tagValue)
+
+		if seen[name] {
+			v.AddWarning(name, "duplicate tag, only the first one is used")
+			continue
+		}
+		seen[name] = true
+
+		switch name {
+		case "v":
+			if position != 1 {
+				v.AddError("v", "must be the first tag")
+			}
+			if tagValue != "DMARC1" {
+				v.AddError("v", "%q is not DMARC1", tagValue)
+			}
+			record.Version = tagValue
+		case "p":
+			policy = tagValue
+		case "sp":
+			record.SubdomainPolicy = parsePolicy(v, name, tagValue)
+		case "np":
+			record.NonExistentSubdomainPolicy = parsePolicy(v, name, tagValue)
+		case "pct":
+			percentage, err := strconv.Atoi(tagValue)
+			if err != nil || percentage < 0 || percentage > 100 {
+				v.AddError(name, "%q is not a number between 0 and 100", tagValue)
+				continue
+			}
+			record.Percentage = percentage
+		case "rua":
+			record.AggregateReportURIs = parseReportURIs(v, name, tagValue)
+		case "ruf":
+			record.FailureReportURIs = parseReportURIs(v, name, tagValue)
+		case "adkim":
+			record.DKIMAlignment = parseAlignment(v, name, tagValue)
+		case "aspf":
+			record.SPFAlignment = parseAlignment(v, name, tagValue)
+		case "fo":
+			options := splitColon(tagValue)
+			for _, option := range options {
+				if !slices.Contains([]string{"0", "1", "d", "s"}, option) {
+					v.AddError(name, "%q is not one of 0, 1, d or s", option)
+				}
+			}
+			record.FailureOptions = options
+		case "rf":
+			formats := splitColon(tagValue)
+			for _, format := range formats {
+				if format != "afrf" {
+					v.AddWarning(name, "unknown report format %q", format)
+				}
+			}
+			record.ReportFormats = formats
+		case "ri":
+			seconds, err := strconv.ParseUint(tagValue, 10, 32)
+			if err != nil {
+				v.AddError(name, "%q is not a number of seconds", tagValue)
+				continue
+			}
+			record.ReportInterval = time.Duration(seconds) * time.Second
+		}
+	}
+
+	if !seen["v"] {
+		v.AddError("v", "missing")
+	}
+	// RFC 7489 Section 6.6.3 treats a record with a missing or invalid p, but
+	// with a valid rua, as p=none, so that its owner still gets aggregate
+	// reports.
+	switch {
+	case !seen["p"] && len(record.AggregateReportURIs) > 0:
+		record.Policy = "none"
+		v.AddWarning("p", "missing, receivers treat the record as p=none")
+	case !seen["p"]:
+		v.AddError("p", "missing")
+	case len(record.AggregateReportURIs) > 0:
+		var pv ValidationResult
+		record.Policy = parsePolicy(&pv, "p", policy)
+		if !pv.Valid() {
+			record.Policy = "none"
+			v.AddWarning("p", "%q is not one of none, quarantine or reject, receivers treat the record as p=none", policy)
+		}
+	default:
+		record.Policy = parsePolicy(v, "p", policy)
+	}
+
+	if record.SubdomainPolicy == "" {
+		record.SubdomainPolicy = record.Policy
+	}
+	if record.NonExistentSubdomainPolicy == "" {
+		record.NonExistentSubdomainPolicy = record.SubdomainPolicy
+	}
+
+	return record
+}
+
+func parsePolicy(v *ValidationResult, name string, value string) string {
+	policy := strings.ToLower(value)
+	switch policy {
+	case "none", "quarantine", "reject":
+		return policy
+	default:
+		v.AddError(name, "%q is not one of none, quarantine or reject", value)
+		return ""
+	}
+}
+
+func parseAlignment(v *ValidationResult, name string, value string) AlignmentMode {
+	switch strings.ToLower(value) {
+	case string(AlignmentRelaxed):
+		return AlignmentRelaxed
+	case string(AlignmentStrict):
+		return AlignmentStrict
+	default:
+		v.AddError(name, "%q is not one of r or s", value)
+		return AlignmentRelaxed
+	}
+}
+
+func parseReportURIs(v *ValidationResult, name string, value string) []ReportURI {
+	var uris []ReportURI
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			v.AddError(name, "empty URI")
+			continue
+		}
+
+		uri, err := parseReportURI(entry)
+		if err != nil {
+			v.AddError(name, "%s", err)
+			continue
+		}
+
+		if uri.Address() == "" {
+			v.AddWarning(name, "%q is not a mailto URI, which most reporters do not support", uri.URI)
+		}
+
+		uris = append(uris, uri)
+	}
+
+	return uris
+}
+
+func splitColon(value string) []string {
+	var values []string
+	for _, entry := range strings.Split(value, ":") {
+		values = append(values, strings.ToLower(strings.TrimSpace(entry)))
+	}
+
+	return values
+}
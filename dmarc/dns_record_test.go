@@ -0,0 +1,204 @@
+package dmarc_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aldy505/mailweave/dmarc"
+)
+
+func TestParseDNSRecord(t *testing.T) {
+	t.Run("full record", func(t *testing.T) {
+		record, err := dmarc.ParseDNSRecord("v=DMARC1; p=reject; sp=quarantine; np=reject; pct=50; rua=mailto:dmarc@example.com!10m,mailto:reports@example.net; ruf=mailto:forensic@example.com; adkim=s; aspf=r; fo=1:d; rf=afrf; ri=3600")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if record.Policy != "reject" {
+			t.Errorf("Policy = %s, want reject", record.Policy)
+		}
+		if record.SubdomainPolicy != "quarantine" {
+			t.Errorf("SubdomainPolicy = %s, want quarantine", record.SubdomainPolicy)
+		}
+		if record.NonExistentSubdomainPolicy != "reject" {
+			t.Errorf("NonExistentSubdomainPolicy = %s, want reject", record.NonExistentSubdomainPolicy)
+		}
+		if record.Percentage != 50 {
+			t.Errorf("Percentage = %d, want 50", record.Percentage)
+		}
+		if len(record.AggregateReportURIs) != 2 {
+			t.Fatalf("len(AggregateReportURIs) = %d, want 2", len(record.AggregateReportURIs))
+		}
+		if record.AggregateReportURIs[0].URI != "mailto:dmarc@example.com" || record.AggregateReportURIs[0].MaxSize != 10<<20 {
+			t.Errorf("AggregateReportURIs[0] = %+v, want mailto:dmarc@example.com with 10 MiB", record.AggregateReportURIs[0])
+		}
+		if record.AggregateReportURIs[0].String() != "mailto:dmarc@example.com!10m" {
+			t.Errorf("AggregateReportURIs[0].String() = %s, want mailto:dmarc@example.com!10m", record.AggregateReportURIs[0].String())
+		}
+		if record.AggregateReportURIs[1].Address() != "reports@example.net" {
+			t.Errorf("AggregateReportURIs[1].Address() = %s, want reports@example.net", record.AggregateReportURIs[1].Address())
+		}
+		if len(record.FailureReportURIs) != 1 {
+			t.Errorf("len(FailureReportURIs) = %d, want 1", len(record.FailureReportURIs))
+		}
+		if record.DKIMAlignment != dmarc.AlignmentStrict {
+			t.Errorf("DKIMAlignment = %s, want s", record.DKIMAlignment)
+		}
+		if record.SPFAlignment != dmarc.AlignmentRelaxed {
+			t.Errorf("SPFAlignment = %s, want r", record.SPFAlignment)
+		}
+		if !slices.Equal(record.FailureOptions, []string{"1", "d"}) {
+			t.Errorf("FailureOptions = %v, want [1 d]", record.FailureOptions)
+		}
+		if record.ReportInterval != time.Hour {
+			t.Errorf("ReportInterval = %s, want 1h", record.ReportInterval)
+		}
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		record, err := dmarc.ParseDNSRecord("v=DMARC1; p=quarantine")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if record.SubdomainPolicy != "quarantine" {
+			t.Errorf("SubdomainPolicy = %s, want quarantine", record.SubdomainPolicy)
+		}
+		if record.NonExistentSubdomainPolicy != "quarantine" {
+			t.Errorf("NonExistentSubdomainPolicy = %s, want quarantine", record.NonExistentSubdomainPolicy)
+		}
+		if record.Percentage != 100 {
+			t.Errorf("Percentage = %d, want 100", record.Percentage)
+		}
+		if record.DKIMAlignment != dmarc.AlignmentRelaxed || record.SPFAlignment != dmarc.AlignmentRelaxed {
+			t.Errorf("alignment = %s/%s, want r/r", record.DKIMAlignment, record.SPFAlignment)
+		}
+		if !slices.Equal(record.FailureOptions, []string{"0"}) {
+			t.Errorf("FailureOptions = %v, want [0]", record.FailureOptions)
+		}
+		if !slices.Equal(record.ReportFormats, []string{"afrf"}) {
+			t.Errorf("ReportFormats = %v, want [afrf]", record.ReportFormats)
+		}
+		if record.ReportInterval != 24*time.Hour {
+			t.Errorf("ReportInterval = %s, want 24h", record.ReportInterval)
+		}
+	})
+
+	t.Run("missing p with rua", func(t *testing.T) {
+		record, err := dmarc.ParseDNSRecord("v=DMARC1; rua=mailto:dmarc@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if record.Policy != "none" || record.SubdomainPolicy != "none" {
+			t.Errorf("Policy = %s/%s, want none/none", record.Policy, record.SubdomainPolicy)
+		}
+	})
+
+	t.Run("invalid p with rua", func(t *testing.T) {
+		record, err := dmarc.ParseDNSRecord("v=DMARC1; p=block; rua=mailto:dmarc@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if record.Policy != "none" {
+			t.Errorf("Policy = %s, want none", record.Policy)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, value := range []string{
+			"",
+			"p=reject; v=DMARC1",
+			"v=DMARC1; rua=dmarc@example.com",
+			"v=DMARC2; p=reject",
+			"v=DMARC1",
+			"v=DMARC1; p=block",
+			"v=DMARC1; p=none; pct=150",
+			"v=DMARC1; p=none; rua=dmarc@example.com",
+			"v=DMARC1; p=none; rua=mailto:dmarc@example.com!10x",
+			"v=DMARC1; p=none; adkim=x",
+			"v=DMARC1; p=none; ri=-1",
+			"v=DMARC1; p=none; fo=2",
+			"v=DMARC1; p",
+		} {
+			_, err := dmarc.ParseDNSRecord(value)
+			if !errors.Is(err, dmarc.ErrInvalidDNSRecord) {
+				t.Errorf("ParseDNSRecord(%q) error = %v, want ErrInvalidDNSRecord", value, err)
+			}
+		}
+	})
+}
+
+func TestLintDNSRecord(t *testing.T) {
+	hasIssue := func(issues []dmarc.FieldError, field string) bool {
+		return slices.ContainsFunc(issues, func(issue dmarc.FieldError) bool { return issue.Field == field })
+	}
+
+	t.Run("clean record", func(t *testing.T) {
+		result := dmarc.LintDNSRecord("v=DMARC1; p=reject; rua=mailto:dmarc@example.com")
+		if !result.Valid() || len(result.Warnings) != 0 {
+			t.Errorf("LintDNSRecord() = %+v, want no issues", result)
+		}
+	})
+
+	t.Run("syntax error", func(t *testing.T) {
+		result := dmarc.LintDNSRecord("v=DMARC1; p=reject; rua")
+		if !hasIssue(result.Errors, "record") {
+			t.Errorf("Errors = %v, want a record error", result.Errors)
+		}
+	})
+
+	t.Run("duplicate tag", func(t *testing.T) {
+		result := dmarc.LintDNSRecord("v=DMARC1; p=reject; p=none; rua=mailto:dmarc@example.com")
+		if !hasIssue(result.Warnings, "p") {
+			t.Errorf("Warnings = %v, want a p warning", result.Warnings)
+		}
+	})
+
+	t.Run("bad mailto", func(t *testing.T) {
+		result := dmarc.LintDNSRecord("v=DMARC1; p=reject; rua=mailto:not an address")
+		if !hasIssue(result.Errors, "rua") {
+			t.Errorf("Errors = %v, want a rua error", result.Errors)
+		}
+	})
+
+	t.Run("pct with p=none", func(t *testing.T) {
+		result := dmarc.LintDNSRecord("v=DMARC1; p=none; pct=20; rua=mailto:dmarc@example.com")
+		if !result.Valid() {
+			t.Fatal(result.Err())
+		}
+		if !hasIssue(result.Warnings, "pct") {
+			t.Errorf("Warnings = %v, want a pct warning", result.Warnings)
+		}
+	})
+
+	t.Run("missing p", func(t *testing.T) {
+		result := dmarc.LintDNSRecord("v=DMARC1; rua=mailto:dmarc@example.com")
+		if !result.Valid() {
+			t.Fatal(result.Err())
+		}
+		if !hasIssue(result.Warnings, "p") {
+			t.Errorf("Warnings = %v, want a p warning", result.Warnings)
+		}
+	})
+
+	t.Run("invalid p", func(t *testing.T) {
+		result := dmarc.LintDNSRecord("v=DMARC1; p=block; rua=mailto:dmarc@example.com")
+		if !result.Valid() {
+			t.Fatal(result.Err())
+		}
+		if !hasIssue(result.Warnings, "p") {
+			t.Errorf("Warnings = %v, want a p warning", result.Warnings)
+		}
+	})
+
+	t.Run("missing rua", func(t *testing.T) {
+		result := dmarc.LintDNSRecord("v=DMARC1; p=none")
+		if !hasIssue(result.Warnings, "rua") {
+			t.Errorf("Warnings = %v, want a rua warning", result.Warnings)
+		}
+	})
+}
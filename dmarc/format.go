@@ -0,0 +1,46 @@
+package dmarc
+
+// NamespaceDMARCbis is the XML namespace declared on the root element of
+// DMARCbis aggregate reports.
+const NamespaceDMARCbis = "urn:ietf:params:xml:ns:dmarc-2.0"
+
+// Format represents the aggregate report format a Feedback was decoded from.
+type Format uint8
+
+const (
+	// FormatRFC7489 is the aggregate report format described in RFC 7489 Appendix C.
+	FormatRFC7489 Format = iota
+
+	// FormatDMARCbis is the aggregate report format described in the DMARCbis
+	// aggregate reporting draft. It adds an XML namespace, the np, testing and
+	// discovery_method policy fields and the generator metadata field, and drops pct.
+	FormatDMARCbis
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatRFC7489:
+		return "RFC 7489"
+	case FormatDMARCbis:
+		return "DMARCbis"
+	default:
+		return "unknown"
+	}
+}
+
+// detectFormat guesses the report format from the namespace of the root element,
+// falling back to fields that only exist on DMARCbis reports for reporters that
+// omit the namespace declaration.
+func detectFormat(feedback Feedback) Format {
+	if feedback.XMLName.Space == NamespaceDMARCbis {
+		return FormatDMARCbis
+	}
+
+	if feedback.ReportMetadata.Generator != "" ||
+		feedback.PolicyPublished.Testing != "" ||
+		feedback.PolicyPublished.DiscoveryMethod != "" {
+		return FormatDMARCbis
+	}
+
+	return FormatRFC7489
+}
@@ -0,0 +1,428 @@
+package dmarc
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aldy505/mailweave/reportname"
+)
+
+// Evaluation is the DMARC evaluation of a single message received by our own
+// inbound MTA, as fed to a ReportGenerator. It can be built from an
+// Authentication-Results header field with NewEvaluation, or directly from the
+// result log of the MTA.
+type Evaluation struct {
+	// The IP address of the host that sent the message.
+	SourceIP string
+	// The RFC5322.From domain.
+	HeaderFrom string
+	// The RFC5321.MailFrom domain.
+	EnvelopeFrom string
+	// The envelope recipient domain.
+	EnvelopeTo string
+	// The DMARC policy of the RFC5322.From domain at the time of evaluation.
+	Policy PolicyPublished
+	// Whether the RFC5322.From domain does not exist, in which case its np
+	// policy applies, as defined in RFC 9091.
+	NonExistentDomain bool
+	// Whether the message was left out of the pct sample of the policy by
+	// the inbound MTA, which then applies the next less strict policy, as
+	// described in RFC 7489 Section 6.6.4.
+	SampledOut bool
+	// The action taken on the message. When nil, it is derived from the
+	// DMARC result and the published policy, taking NonExistentDomain and
+	// SampledOut into account.
+	Disposition *Disposition
+	Reasons     []PolicyOverrideReason
+	DKIM        []DKIMAuthResult
+	SPF         []SPFAuthResult
+}
+
+// NewEvaluation builds an Evaluation from the Authentication-Results header
+// field added by our inbound MTA. The policy is the DMARC record of the
+// RFC5322.From domain, which is not part of the header field.
+func NewEvaluation(sourceIP string, policy PolicyPublished, results AuthenticationResults) Evaluation {
+	evaluation := Evaluation{
+		SourceIP: sourceIP,
+		Policy:   policy,
+	}
+
+	for _, result := range results.Results {
+		switch result.Method {
+		case "dmarc":
+			evaluation.HeaderFrom = result.Properties["header.from"]
+		case "dkim":
+			domain := result.Properties["header.d"]
+			if domain == "" {
+				// header.i is the AUID, which is the signing domain or one of its subdomains.
+				_, domain, _ = strings.Cut(result.Properties["header.i"], "@")
+			}
+
+			evaluation.DKIM = append(evaluation.DKIM, DKIMAuthResult{
+				Domain:      domain,
+				Selector:    result.Properties["header.s"],
+				Result:      ParseDKIMResult(result.Result),
+				HumanResult: result.Reason,
+			})
+		case "spf":
+			spf := SPFAuthResult{Result: ParseSPFResult(result.Result)}
+			if mailFrom, ok := result.Properties["smtp.mailfrom"]; ok {
+				spf.Scope = "mfrom"
+				spf.Domain = mailFrom
+				if _, domain, ok := strings.Cut(mailFrom, "@"); ok {
+					spf.Domain = domain
+				}
+				evaluation.EnvelopeFrom = spf.Domain
+			} else {
+				spf.Scope = "helo"
+				spf.Domain = result.Properties["smtp.helo"]
+			}
+
+			evaluation.SPF = append(evaluation.SPF, spf)
+		}
+	}
+
+	if evaluation.HeaderFrom == "" {
+		evaluation.HeaderFrom = policy.Domain
+	}
+
+	return evaluation
+}
+
+// ReportGenerator accumulates the DMARC evaluations of received messages and
+// emits one aggregate report per policy domain. It is safe for concurrent use.
+type ReportGenerator struct {
+	// The domain of the organization generating the reports, used as the
+	// receiver part of the report filename.
+	Receiver string
+	// The name of the organization generating the reports. Defaults to Receiver.
+	OrgName string
+	// The email address reporters can be contacted at.
+	Email string
+	// Additional contact details, e.g. a URL.
+	ExtraContactInfo string
+
+	mu sync.Mutex
+	// the key is the policy domain
+	policies map[string]*generatorPolicy
+	sequence uint64
+}
+
+type generatorPolicy struct {
+	policy  PolicyPublished
+	records map[string]*Record
+	order   []string
+}
+
+// Add accumulates a single message evaluation.
+func (g *ReportGenerator) Add(evaluation Evaluation) {
+	evaluation.Policy = withPolicyDefaults(evaluation.Policy)
+	record := evaluatedRecord(evaluation)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.policies == nil {
+		g.policies = make(map[string]*generatorPolicy)
+	}
+
+	domain := strings.ToLower(evaluation.Policy.Domain)
+	policy, ok := g.policies[domain]
+	if !ok {
+		policy = &generatorPolicy{records: make(map[string]*Record)}
+		g.policies[domain] = policy
+	}
+	// The latest published policy wins.
+	policy.policy = evaluation.Policy
+
+	key := recordKey(record)
+	if existing, ok := policy.records[key]; ok {
+		existing.Row.Count++
+		return
+	}
+
+	policy.records[key] = &record
+	policy.order = append(policy.order, key)
+}
+
+// Flush returns the accumulated reports for the given date range, one per
+// policy domain sorted by domain, and resets the generator.
+func (g *ReportGenerator) Flush(begin time.Time, end time.Time) []Feedback {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	domains := make([]string, 0, len(g.policies))
+	for domain := range g.policies {
+		domains = append(domains, domain)
+	}
+	slices.Sort(domains)
+
+	orgName := g.OrgName
+	if orgName == "" {
+		orgName = g.Receiver
+	}
+
+	feedbacks := make([]Feedback, 0, len(domains))
+	for _, domain := range domains {
+		policy := g.policies[domain]
+		g.sequence++
+
+		feedback := Feedback{
+			Version: "1.0",
+			ReportMetadata: ReportMetadata{
+				OrgName:          orgName,
+				Email:            g.Email,
+				ExtraContactInfo: g.ExtraContactInfo,
+				ReportID:         fmt.Sprintf("%s.%d.%d", domain, begin.Unix(), g.sequence),
+				DateRange: DateRange{
+					Begin: begin.Unix(),
+					End:   end.Unix(),
+				},
+			},
+			PolicyPublished: policy.policy,
+			Records:         make([]Record, 0, len(policy.order)),
+		}
+
+		for _, key := range policy.order {
+			feedback.Records = append(feedback.Records, *policy.records[key])
+		}
+
+		feedbacks = append(feedbacks, feedback)
+	}
+
+	g.policies = nil
+
+	return feedbacks
+}
+
+// withPolicyDefaults fills the policy_published elements that the RFC 7489
+// schema requires with the defaults of RFC 7489 Section 6.3, as the DMARC
+// record they come from may omit them.
+func withPolicyDefaults(policy PolicyPublished) PolicyPublished {
+	if policy.P == "" {
+		policy.P = "none"
+	}
+	if policy.SP == "" {
+		policy.SP = policy.P
+	}
+	if policy.PCT == "" {
+		policy.PCT = "100"
+	}
+	if policy.FO == "" {
+		policy.FO = "0"
+	}
+
+	return policy
+}
+
+func evaluatedRecord(evaluation Evaluation) Record {
+	headerFrom := evaluation.HeaderFrom
+	policy := evaluation.Policy
+
+	dkimResult := DMARCResultFail
+	for _, dkim := range evaluation.DKIM {
+		if dkim.Result.Key() == DKIMResultPass.Key() && Aligned(ParseAlignmentMode(policy.ADKIM), dkim.Domain, headerFrom) {
+			dkimResult = DMARCResultPass
+			break
+		}
+	}
+
+	spfResult := DMARCResultFail
+	for _, spf := range evaluation.SPF {
+		if spf.Scope != "helo" && spf.Result.Key() == SPFResultPass.Key() && Aligned(ParseAlignmentMode(policy.ASPF), spf.Domain, headerFrom) {
+			spfResult = DMARCResultPass
+			break
+		}
+	}
+
+	disposition := evaluation.Disposition
+	reasons := evaluation.Reasons
+	if disposition == nil {
+		disposition = DispositionNone
+		if dkimResult == DMARCResultFail && spfResult == DMARCResultFail {
+			applied := effectivePolicy(policy, headerFrom, evaluation.NonExistentDomain)
+			if evaluation.SampledOut && applied != "none" {
+				applied = sampledOutPolicy(applied)
+				reasons = append(slices.Clip(reasons), PolicyOverrideReason{Type: PolicyOverrideSampledOut})
+			}
+			disposition = ParseDisposition(applied)
+		}
+	}
+
+	// RFC 7489 requires at least one SPF result.
+	spf := evaluation.SPF
+	if len(spf) == 0 {
+		spf = []SPFAuthResult{{Domain: evaluation.EnvelopeFrom, Scope: "mfrom", Result: SPFResultNone}}
+	}
+
+	return Record{
+		Row: RecordRow{
+			SourceIP: evaluation.SourceIP,
+			Count:    1,
+			PolicyEvaluated: PolicyEvaluated{
+				Disposition: disposition,
+				DKIM:        dkimResult,
+				SPF:         spfResult,
+				Reasons:     reasons,
+			},
+		},
+		Identifiers: Identifiers{
+			EnvelopeTo:   evaluation.EnvelopeTo,
+			EnvelopeFrom: evaluation.EnvelopeFrom,
+			HeaderFrom:   headerFrom,
+		},
+		AuthResults: AuthResults{
+			DKIM: evaluation.DKIM,
+			SPF:  spf,
+		},
+	}
+}
+
+// effectivePolicy returns the policy that applies to the RFC5322.From domain,
+// which is the subdomain policy when it differs from the policy domain, and
+// the non-existent subdomain policy when it does not exist. np falls back to
+// sp, and sp to p, as they do in the DMARC record.
+func effectivePolicy(policy PolicyPublished, headerFrom string, nonExistent bool) string {
+	if normalizeDomain(headerFrom) == normalizeDomain(policy.Domain) {
+		return policy.P
+	}
+
+	if nonExistent && policy.NP != "" {
+		return policy.NP
+	}
+	if policy.SP != "" {
+		return policy.SP
+	}
+
+	return policy.P
+}
+
+// sampledOutPolicy returns the policy applied to a message left out of the
+// pct sample: quarantine instead of reject, and none instead of quarantine.
+func sampledOutPolicy(policy string) string {
+	switch policy {
+	case "reject":
+		return "quarantine"
+	default:
+		return "none"
+	}
+}
+
+// recordKey identifies the records that can be merged into a single row.
+func recordKey(record Record) string {
+	var b strings.Builder
+	row := record.Row
+	fields := []string{
+		row.SourceIP,
+		row.PolicyEvaluated.Disposition.Key(),
+		row.PolicyEvaluated.DKIM.Key(),
+		row.PolicyEvaluated.SPF.Key(),
+		strings.ToLower(record.Identifiers.EnvelopeTo),
+		strings.ToLower(record.Identifiers.EnvelopeFrom),
+		strings.ToLower(record.Identifiers.HeaderFrom),
+	}
+	for _, reason := range row.PolicyEvaluated.Reasons {
+		fields = append(fields, "reason", reason.Type.Key(), reason.Comment)
+	}
+	for _, dkim := range record.AuthResults.DKIM {
+		fields = append(fields, "dkim", strings.ToLower(dkim.Domain), dkim.Selector, dkim.Result.Key())
+	}
+	for _, spf := range record.AuthResults.SPF {
+		fields = append(fields, "spf", strings.ToLower(spf.Domain), spf.Scope, spf.Result.Key())
+	}
+
+	for _, field := range fields {
+		b.WriteString(strconv.Quote(field))
+	}
+
+	return b.String()
+}
+
+// MarshalFeedback encodes feedback as an aggregate report XML document.
+func MarshalFeedback(feedback Feedback) ([]byte, error) {
+	for i, record := range feedback.Records {
+		if _, err := netip.ParseAddr(record.Row.SourceIP); err != nil {
+			return nil, fmt.Errorf("record %d: invalid source IP %q", i, record.Row.SourceIP)
+		}
+	}
+
+	content, err := xml.MarshalIndent(feedback, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshaling feedback: %w", err)
+	}
+
+	return append([]byte(xml.Header), content...), nil
+}
+
+// PackageFeedback encodes feedback and packages it for delivery, returning the
+// report filename and content. The filename follows RFC 7489 Section 7.2.1.1:
+// receiver!policy-domain!begin-timestamp!end-timestamp.extension, where the
+// receiver is the given receiver domain.
+func PackageFeedback(receiver string, feedback Feedback, compression CompressionType) (string, []byte, error) {
+	content, err := MarshalFeedback(feedback)
+	if err != nil {
+		return "", nil, err
+	}
+
+	name := reportname.Name{
+		Receiver:     receiver,
+		PolicyDomain: feedback.PolicyPublished.Domain,
+		Begin:        feedback.ReportMetadata.DateRange.BeginTime(),
+		End:          feedback.ReportMetadata.DateRange.EndTime(),
+		Extension:    "xml",
+	}
+
+	var buf bytes.Buffer
+	switch compression {
+	case CompressionTypeNone:
+		return name.String(), content, nil
+	case CompressionTypeGZIP:
+		w := gzip.NewWriter(&buf)
+		w.Name = name.String()
+		if _, err := w.Write(content); err != nil {
+			return "", nil, fmt.Errorf("compressing gzip: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return "", nil, fmt.Errorf("compressing gzip: %w", err)
+		}
+
+		name.Extension = "xml.gz"
+		return name.String(), buf.Bytes(), nil
+	case CompressionTypeZIP:
+		w := zip.NewWriter(&buf)
+		f, err := w.Create(name.String())
+		if err != nil {
+			return "", nil, fmt.Errorf("compressing zip: %w", err)
+		}
+		if _, err := f.Write(content); err != nil {
+			return "", nil, fmt.Errorf("compressing zip: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return "", nil, fmt.Errorf("compressing zip: %w", err)
+		}
+
+		name.Extension = "zip"
+		return name.String(), buf.Bytes(), nil
+	default:
+		return "", nil, fmt.Errorf("unsupported compression type %s", compression)
+	}
+}
+
+// ReportSubject returns the subject of the email carrying an aggregate report,
+// as suggested by RFC 7489 Section 7.2.1.1.
+func ReportSubject(receiver string, feedback Feedback) string {
+	return fmt.Sprintf("Report Domain: %s Submitter: %s Report-ID: <%s>",
+		feedback.PolicyPublished.Domain,
+		receiver,
+		feedback.ReportMetadata.ReportID,
+	)
+}
@@ -0,0 +1,190 @@
+package dmarc_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aldy505/mailweave/dmarc"
+)
+
+func TestReportGenerator(t *testing.T) {
+	policy := dmarc.PolicyPublished{Domain: "example.com", ADKIM: "r", ASPF: "r", P: "reject", SP: "quarantine", PCT: "100"}
+
+	passing, err := dmarc.ParseAuthenticationResults("mx.example.net; dkim=pass header.d=mail.example.com header.s=s1; spf=pass smtp.mailfrom=bounce@example.com; dmarc=pass header.from=example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	failing, err := dmarc.ParseAuthenticationResults("mx.example.net; dkim=fail header.d=example.com header.s=s1; spf=pass smtp.mailfrom=bounce@spammer.example; dmarc=fail header.from=news.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := dmarc.ParseAuthenticationResults("mx.example.net; spf=pass smtp.mailfrom=other.example; dmarc=pass header.from=other.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	generator := &dmarc.ReportGenerator{Receiver: "example.net", Email: "dmarc@example.net"}
+	generator.Add(dmarc.NewEvaluation("192.0.2.1", policy, passing))
+	generator.Add(dmarc.NewEvaluation("192.0.2.1", policy, passing))
+	generator.Add(dmarc.NewEvaluation("198.51.100.1", policy, failing))
+	generator.Add(dmarc.NewEvaluation("2001:db8::1", dmarc.PolicyPublished{Domain: "other.example", P: "none"}, other))
+
+	begin := time.Date(2025, 5, 14, 0, 0, 0, 0, time.UTC)
+	end := begin.Add(24*time.Hour - time.Second)
+	feedbacks := generator.Flush(begin, end)
+	if len(feedbacks) != 2 {
+		t.Fatalf("len(feedbacks) = %d, want 2", len(feedbacks))
+	}
+
+	feedback := feedbacks[0]
+	if feedback.PolicyPublished.Domain != "example.com" {
+		t.Errorf("PolicyPublished.Domain = %s, want example.com", feedback.PolicyPublished.Domain)
+	}
+	if feedback.ReportMetadata.OrgName != "example.net" {
+		t.Errorf("OrgName = %s, want example.net", feedback.ReportMetadata.OrgName)
+	}
+	if len(feedback.Records) != 2 {
+		t.Fatalf("len(Records) = %d, want 2", len(feedback.Records))
+	}
+
+	t.Run("aligned records are merged", func(t *testing.T) {
+		row := feedback.Records[0].Row
+		if row.Count != 2 {
+			t.Errorf("Count = %d, want 2", row.Count)
+		}
+		if row.PolicyEvaluated.DKIM.Key() != "pass" || row.PolicyEvaluated.SPF.Key() != "pass" {
+			t.Errorf("PolicyEvaluated = %s/%s, want pass/pass", row.PolicyEvaluated.DKIM, row.PolicyEvaluated.SPF)
+		}
+		if row.PolicyEvaluated.Disposition.Key() != "none" {
+			t.Errorf("Disposition = %s, want none", row.PolicyEvaluated.Disposition)
+		}
+	})
+
+	t.Run("subdomain policy applies on failure", func(t *testing.T) {
+		record := feedback.Records[1]
+		if record.Row.PolicyEvaluated.DKIM.Key() != "fail" || record.Row.PolicyEvaluated.SPF.Key() != "fail" {
+			t.Errorf("PolicyEvaluated = %s/%s, want fail/fail", record.Row.PolicyEvaluated.DKIM, record.Row.PolicyEvaluated.SPF)
+		}
+		if record.Row.PolicyEvaluated.Disposition.Key() != "quarantine" {
+			t.Errorf("Disposition = %s, want quarantine", record.Row.PolicyEvaluated.Disposition)
+		}
+		if record.Identifiers.HeaderFrom != "news.example.com" {
+			t.Errorf("HeaderFrom = %s, want news.example.com", record.Identifiers.HeaderFrom)
+		}
+	})
+
+	t.Run("flush resets the generator", func(t *testing.T) {
+		if got := generator.Flush(begin, end); len(got) != 0 {
+			t.Errorf("len(Flush()) = %d, want 0", len(got))
+		}
+	})
+
+	for _, compression := range []dmarc.CompressionType{dmarc.CompressionTypeNone, dmarc.CompressionTypeGZIP, dmarc.CompressionTypeZIP} {
+		t.Run("round trip "+compression.String(), func(t *testing.T) {
+			filename, content, err := dmarc.PackageFeedback("example.net", feedback, compression)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.HasPrefix(filename, "example.net!example.com!1747180800!1747267199.") {
+				t.Errorf("filename = %s, want example.net!example.com!1747180800!1747267199.*", filename)
+			}
+			if got := dmarc.DetectCompression(content); got != compression {
+				t.Errorf("DetectCompression() = %s, want %s", got, compression)
+			}
+
+			parsed, err := dmarc.ParseFeedbacks(bytes.NewReader(content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(parsed) != 1 {
+				t.Fatalf("len(parsed) = %d, want 1", len(parsed))
+			}
+
+			if parsed[0].ReportMetadata.ReportID != feedback.ReportMetadata.ReportID {
+				t.Errorf("ReportID = %s, want %s", parsed[0].ReportMetadata.ReportID, feedback.ReportMetadata.ReportID)
+			}
+			if len(parsed[0].Records) != 2 || parsed[0].Records[0].Row.Count != 2 {
+				t.Errorf("Records = %+v, want 2 records with the first counting 2 messages", parsed[0].Records)
+			}
+			if result := dmarc.Validate(parsed[0]); !result.Valid() {
+				t.Errorf("Validate() = %v, want no errors", result.Err())
+			}
+		})
+	}
+}
+
+func TestReportGeneratorPolicyDefaults(t *testing.T) {
+	failing, err := dmarc.ParseAuthenticationResults("mx.example.net; spf=fail smtp.mailfrom=spammer.example; dmarc=fail header.from=example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	generator := &dmarc.ReportGenerator{Receiver: "example.net", Email: "dmarc@example.net"}
+	generator.Add(dmarc.NewEvaluation("192.0.2.1", dmarc.PolicyPublished{Domain: "example.com"}, failing))
+
+	begin := time.Date(2025, 5, 14, 0, 0, 0, 0, time.UTC)
+	feedbacks := generator.Flush(begin, begin.Add(24*time.Hour-time.Second))
+	if len(feedbacks) != 1 {
+		t.Fatalf("len(feedbacks) = %d, want 1", len(feedbacks))
+	}
+
+	content, err := dmarc.MarshalFeedback(feedbacks[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, element := range []string{"<p>none</p>", "<sp>none</sp>", "<pct>100</pct>", "<fo>0</fo>", "<disposition>none</disposition>"} {
+		if !bytes.Contains(content, []byte(element)) {
+			t.Errorf("report does not contain %s:\n%s", element, content)
+		}
+	}
+}
+
+func TestReportGeneratorDerivedDisposition(t *testing.T) {
+	failing, err := dmarc.ParseAuthenticationResults("mx.example.net; spf=fail smtp.mailfrom=spammer.example; dmarc=fail header.from=ghost.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := dmarc.PolicyPublished{Domain: "example.com", P: "reject", SP: "quarantine", NP: "reject", PCT: "50"}
+	tests := []struct {
+		name        string
+		nonExistent bool
+		sampledOut  bool
+		want        string
+		reason      bool
+	}{
+		{name: "existing subdomain", want: "quarantine"},
+		{name: "non-existent subdomain", nonExistent: true, want: "reject"},
+		{name: "non-existent subdomain sampled out", nonExistent: true, sampledOut: true, want: "quarantine", reason: true},
+		{name: "existing subdomain sampled out", sampledOut: true, want: "none", reason: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluation := dmarc.NewEvaluation("192.0.2.1", policy, failing)
+			evaluation.NonExistentDomain = tt.nonExistent
+			evaluation.SampledOut = tt.sampledOut
+
+			generator := &dmarc.ReportGenerator{Receiver: "example.net", Email: "dmarc@example.net"}
+			generator.Add(evaluation)
+
+			begin := time.Date(2025, 5, 14, 0, 0, 0, 0, time.UTC)
+			feedbacks := generator.Flush(begin, begin.Add(24*time.Hour-time.Second))
+			if len(feedbacks) != 1 || len(feedbacks[0].Records) != 1 {
+				t.Fatalf("feedbacks = %+v, want one record", feedbacks)
+			}
+
+			evaluated := feedbacks[0].Records[0].Row.PolicyEvaluated
+			if got := evaluated.Disposition.Key(); got != tt.want {
+				t.Errorf("Disposition = %s, want %s", got, tt.want)
+			}
+			if hasReason := len(evaluated.Reasons) == 1 && evaluated.Reasons[0].Type == dmarc.PolicyOverrideSampledOut; hasReason != tt.reason {
+				t.Errorf("Reasons = %+v, want a sampled_out reason: %t", evaluated.Reasons, tt.reason)
+			}
+		})
+	}
+}
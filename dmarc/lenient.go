@@ -0,0 +1,265 @@
+package dmarc
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// RepairKind identifies the kind of repair applied by the lenient parser.
+type RepairKind string
+
+const (
+	// RepairByteOrderMark means a leading byte order mark was removed.
+	RepairByteOrderMark RepairKind = "byte-order-mark"
+	// RepairCharset means the document was transcoded to UTF-8.
+	RepairCharset RepairKind = "charset"
+	// RepairEmbeddedSchema means an embedded xs:schema block was removed.
+	RepairEmbeddedSchema RepairKind = "embedded-schema"
+	// RepairEntity means an invalid or unknown entity reference was replaced.
+	RepairEntity RepairKind = "entity"
+	// RepairTruncated means the document ended early and its open elements were closed.
+	RepairTruncated RepairKind = "truncated"
+)
+
+// Repair describes a single repair applied to a malformed report.
+type Repair struct {
+	Kind    RepairKind
+	Message string
+}
+
+func (r Repair) String() string {
+	return string(r.Kind) + ": " + r.Message
+}
+
+var xmlDeclarationEncoding = regexp.MustCompile(`^(<\?xml[^>]*?encoding\s*=\s*["'])([A-Za-z0-9._-]+)(["'][^>]*\?>)`)
+
+var entityReference = regexp.MustCompile(`&(#[0-9]+;|#[xX][0-9a-fA-F]+;|[A-Za-z][A-Za-z0-9]*;)?`)
+
+// windows1252 maps the 0x80-0x9F range of windows-1252 to Unicode. The rest
+// of the code page is identical to ISO-8859-1. Undefined bytes map to U+FFFD.
+var windows1252 = [32]rune{
+	'€', '�', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '�', 'Ž', '�',
+	'�', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '�', 'ž', 'Ÿ',
+}
+
+// repairFeedback reads the whole report from r and fixes the malformations
+// commonly found in aggregate reports sent in the wild.
+func repairFeedback(r io.Reader) ([]byte, []Repair, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading feedback: %w", err)
+	}
+
+	var repairs []Repair
+
+	if trimmed, ok := bytes.CutPrefix(content, []byte("\xef\xbb\xbf")); ok {
+		content = trimmed
+		repairs = append(repairs, Repair{Kind: RepairByteOrderMark, Message: "removed UTF-8 byte order mark"})
+	}
+
+	content, repairs = repairCharset(content, repairs)
+	content, repairs = repairEmbeddedSchema(content, repairs)
+	content, repairs = repairEntities(content, repairs)
+	content, repairs = repairTruncation(content, repairs)
+
+	return content, repairs, nil
+}
+
+func repairCharset(content []byte, repairs []Repair) ([]byte, []Repair) {
+	content = bytes.TrimLeft(content, " \t\r\n")
+
+	declared := ""
+	if match := xmlDeclarationEncoding.FindSubmatch(content); match != nil {
+		declared = string(match[2])
+	}
+
+	switch strings.ToLower(declared) {
+	case "iso-8859-1", "iso8859-1", "latin1", "latin-1", "windows-1252", "cp1252", "us-ascii", "ascii":
+		// The declaration is rewritten either way, as the decoder only reads
+		// UTF-8, but only a transcoded body counts as a repair.
+		content = xmlDeclarationEncoding.ReplaceAll(content, []byte("${1}UTF-8${3}"))
+		if !isASCII(content) {
+			content = decodeWindows1252(content)
+			repairs = append(repairs, Repair{Kind: RepairCharset, Message: fmt.Sprintf("transcoded from declared encoding %s to UTF-8", declared)})
+		}
+	case "", "utf-8", "utf8":
+		if !utf8.Valid(content) {
+			content = decodeWindows1252(content)
+			repairs = append(repairs, Repair{Kind: RepairCharset, Message: "transcoded invalid UTF-8 as windows-1252"})
+		}
+	}
+
+	return content, repairs
+}
+
+func isASCII(content []byte) bool {
+	for _, b := range content {
+		if b >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
+
+// decodeWindows1252 decodes windows-1252, which is a superset of the printable
+// characters of ISO-8859-1, into UTF-8.
+func decodeWindows1252(content []byte) []byte {
+	var buf bytes.Buffer
+	buf.Grow(len(content))
+	for _, b := range content {
+		switch {
+		case b < utf8.RuneSelf:
+			buf.WriteByte(b)
+		case b >= 0x80 && b <= 0x9f:
+			buf.WriteRune(windows1252[b-0x80])
+		default:
+			buf.WriteRune(rune(b))
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func repairEmbeddedSchema(content []byte, repairs []Repair) ([]byte, []Repair) {
+	for {
+		start := brokenschema.FindIndex(content)
+		if start == nil {
+			return content, repairs
+		}
+
+		end := matchschema.FindIndex(content[start[1]:])
+		if end == nil {
+			// Without a closing tag, only the opening tag can be dropped safely.
+			content = append(content[:start[0]:start[0]], content[start[1]:]...)
+			repairs = append(repairs, Repair{Kind: RepairEmbeddedSchema, Message: "removed unterminated xs:schema opening tag"})
+			continue
+		}
+
+		content = append(content[:start[0]:start[0]], content[start[1]+end[1]:]...)
+		repairs = append(repairs, Repair{Kind: RepairEmbeddedSchema, Message: "removed embedded xs:schema block"})
+	}
+}
+
+// unparsedSection matches the CDATA sections and comments, whose content is
+// not parsed for entity references.
+var unparsedSection = regexp.MustCompile(`(?s)<!\[CDATA\[.*?\]\]>|<!--.*?-->`)
+
+func repairEntities(content []byte, repairs []Repair) ([]byte, []Repair) {
+	var fixed []string
+	var repaired []byte
+	for {
+		section := unparsedSection.FindIndex(content)
+		if section == nil {
+			repaired = append(repaired, repairEntityReferences(content, &fixed)...)
+			break
+		}
+
+		repaired = append(repaired, repairEntityReferences(content[:section[0]], &fixed)...)
+		repaired = append(repaired, content[section[0]:section[1]]...)
+		content = content[section[1]:]
+	}
+
+	if len(fixed) > 0 {
+		repairs = append(repairs, Repair{Kind: RepairEntity, Message: fmt.Sprintf("replaced %d invalid entity reference(s): %s", len(fixed), strings.Join(fixed, ", "))})
+	}
+
+	return repaired, repairs
+}
+
+// repairEntityReferences replaces the invalid entity references of content,
+// which must not contain CDATA sections or comments, and appends them to fixed.
+func repairEntityReferences(content []byte, fixed *[]string) []byte {
+	return entityReference.ReplaceAllFunc(content, func(match []byte) []byte {
+		switch string(match) {
+		case "&amp;", "&lt;", "&gt;", "&quot;", "&apos;":
+			return match
+		}
+
+		if len(match) > 2 && match[1] == '#' {
+			// Numeric references are valid as long as they refer to a valid XML character.
+			if r := []rune(html.UnescapeString(string(match))); len(r) == 1 && r[0] != utf8.RuneError && isXMLChar(r[0]) {
+				return match
+			}
+
+			*fixed = append(*fixed, string(match))
+			return []byte("&amp;" + string(match[1:]))
+		}
+
+		if len(match) > 1 {
+			// Named HTML entities such as &nbsp; are not defined in XML.
+			if unescaped := html.UnescapeString(string(match)); unescaped != string(match) {
+				*fixed = append(*fixed, string(match))
+				var buf bytes.Buffer
+				_ = xml.EscapeText(&buf, []byte(unescaped))
+				return buf.Bytes()
+			}
+		}
+
+		*fixed = append(*fixed, string(match))
+		return []byte("&amp;" + string(match[1:]))
+	})
+}
+
+func isXMLChar(r rune) bool {
+	return r == 0x09 || r == 0x0a || r == 0x0d ||
+		(r >= 0x20 && r <= 0xd7ff) ||
+		(r >= 0xe000 && r <= 0xfffd) ||
+		(r >= 0x10000 && r <= 0x10ffff)
+}
+
+// repairTruncation closes the elements left open by a document that ends early.
+// Anything after the last complete token is dropped.
+func repairTruncation(content []byte, repairs []Repair) ([]byte, []Repair) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	var stack []string
+	var offset int64
+
+	for {
+		// RawToken keeps the namespace prefixes as written, which is what the
+		// closing tags need.
+		token, err := decoder.RawToken()
+		if err != nil {
+			if len(stack) == 0 {
+				return content, repairs
+			}
+
+			var syntaxErr *xml.SyntaxError
+			if !errors.Is(err, io.EOF) && !(errors.As(err, &syntaxErr) && strings.Contains(syntaxErr.Msg, "unexpected EOF")) {
+				return content, repairs
+			}
+
+			break
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := t.Name.Local
+			if t.Name.Space != "" {
+				name = t.Name.Space + ":" + name
+			}
+			stack = append(stack, name)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+
+		offset = decoder.InputOffset()
+	}
+
+	repaired := append([]byte(nil), content[:offset]...)
+	for i := len(stack) - 1; i >= 0; i-- {
+		repaired = append(repaired, "</"+stack[i]+">"...)
+	}
+
+	repairs = append(repairs, Repair{Kind: RepairTruncated, Message: fmt.Sprintf("closed %d element(s) left open at the end of a truncated document: %s", len(stack), strings.Join(stack, ", "))})
+	return repaired, repairs
+}
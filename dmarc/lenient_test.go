@@ -0,0 +1,136 @@
+package dmarc_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aldy505/mailweave/dmarc"
+)
+
+const lenientReport = `<feedback>
+  <report_metadata>
+    <org_name>%ORG%</org_name>
+    <report_id>1</report_id>
+  </report_metadata>
+  <policy_published>
+    <domain>example.com</domain>
+    <p>none</p>
+  </policy_published>
+  <record>
+    <row>
+      <source_ip>192.0.2.1</source_ip>
+      <count>2</count>
+    </row>
+    <identifiers>
+      <header_from>example.com</header_from>
+    </identifiers>
+  </record>
+</feedback>`
+
+func TestParseFeedbackLenient(t *testing.T) {
+	tests := []struct {
+		name    string
+		report  string
+		orgName string
+		repairs []dmarc.RepairKind
+		// strict is true when the strict parser copes with the report as well.
+		strict bool
+	}{
+		{
+			name:    "well formed",
+			report:  `<?xml version="1.0" encoding="UTF-8"?>` + strings.Replace(lenientReport, "%ORG%", "example.org", 1),
+			orgName: "example.org",
+			strict:  true,
+		},
+		{
+			name:    "byte order mark",
+			report:  "\xef\xbb\xbf" + `<?xml version="1.0" encoding="UTF-8"?>` + strings.Replace(lenientReport, "%ORG%", "example.org", 1),
+			orgName: "example.org",
+			repairs: []dmarc.RepairKind{dmarc.RepairByteOrderMark},
+			strict:  true,
+		},
+		{
+			name:    "iso-8859-1",
+			report:  `<?xml version="1.0" encoding="ISO-8859-1"?>` + strings.Replace(lenientReport, "%ORG%", "Soci\xe9t\xe9", 1),
+			orgName: "Société",
+			repairs: []dmarc.RepairKind{dmarc.RepairCharset},
+		},
+		{
+			name:    "windows-1252",
+			report:  `<?xml version="1.0" encoding="windows-1252"?>` + strings.Replace(lenientReport, "%ORG%", "Reporter\x92s \x80", 1),
+			orgName: "Reporter’s €",
+			repairs: []dmarc.RepairKind{dmarc.RepairCharset},
+		},
+		{
+			name:    "undeclared windows-1252",
+			report:  strings.Replace(lenientReport, "%ORG%", "Soci\xe9t\xe9", 1),
+			orgName: "Société",
+			repairs: []dmarc.RepairKind{dmarc.RepairCharset},
+		},
+		{
+			name: "embedded schema",
+			report: strings.Replace(
+				strings.Replace(lenientReport, "%ORG%", "example.org", 1),
+				"<feedback>",
+				`<feedback><xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="feedback"></xs:schema>`,
+				1,
+			),
+			orgName: "example.org",
+			repairs: []dmarc.RepairKind{dmarc.RepairEmbeddedSchema},
+		},
+		{
+			name:    "bad entities",
+			report:  strings.Replace(lenientReport, "%ORG%", "AT&T&nbsp;Mail &#0; &amp; co", 1),
+			orgName: "AT&T Mail &#0; & co",
+			repairs: []dmarc.RepairKind{dmarc.RepairEntity},
+		},
+		{
+			name:    "us-ascii",
+			report:  `<?xml version="1.0" encoding="us-ascii"?>` + strings.Replace(lenientReport, "%ORG%", "example.org", 1),
+			orgName: "example.org",
+		},
+		{
+			name:    "entities in cdata and comments",
+			report:  strings.Replace(lenientReport, "%ORG%", "<![CDATA[AT&T &nbsp;]]><!-- R&D -->", 1),
+			orgName: "AT&T &nbsp;",
+			strict:  true,
+		},
+		{
+			name:    "truncated",
+			report:  lenientReport[:strings.Index(lenientReport, "</record>")+3],
+			orgName: "%ORG%",
+			repairs: []dmarc.RepairKind{dmarc.RepairTruncated},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feedback, err := dmarc.ParseFeedback(strings.NewReader(tt.report), dmarc.WithLenient())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if feedback.ReportMetadata.OrgName != tt.orgName {
+				t.Errorf("OrgName = %q, want %q", feedback.ReportMetadata.OrgName, tt.orgName)
+			}
+			if len(feedback.Records) != 1 || feedback.Records[0].Row.Count != 2 {
+				t.Errorf("Records = %+v, want a single record with a count of 2", feedback.Records)
+			}
+
+			if len(feedback.Repairs) != len(tt.repairs) {
+				t.Fatalf("Repairs = %v, want %v", feedback.Repairs, tt.repairs)
+			}
+			for i, repair := range feedback.Repairs {
+				if repair.Kind != tt.repairs[i] {
+					t.Errorf("Repairs[%d] = %s, want %s", i, repair.Kind, tt.repairs[i])
+				}
+			}
+
+			if !tt.strict {
+				if _, err := dmarc.ParseFeedback(strings.NewReader(tt.report)); err == nil {
+					t.Error("expected the strict parser to fail, got nil")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package dmarc
+
+import (
+	"github.com/aldy505/mailweave/internal/limits"
+)
+
+var (
+	// ErrCompressedSizeExceeded is returned when a compressed report is larger
+	// than Limits.MaxCompressedSize.
+	ErrCompressedSizeExceeded = limits.ErrCompressedSizeExceeded
+	// ErrDecompressedSizeExceeded is returned when a report decompresses to
+	// more than Limits.MaxDecompressedSize, or an uncompressed report is larger
+	// than it.
+	ErrDecompressedSizeExceeded = limits.ErrDecompressedSizeExceeded
+	// ErrCompressionRatioExceeded is returned when a report decompresses to
+	// more than Limits.MaxCompressionRatio times its compressed size.
+	ErrCompressionRatioExceeded = limits.ErrCompressionRatioExceeded
+	// ErrZipMembersExceeded is returned when a zip archive has more than
+	// Limits.MaxZipMembers members.
+	ErrZipMembersExceeded = limits.ErrZipMembersExceeded
+	// ErrRecordsExceeded is returned when a report has more than
+	// Limits.MaxRecords records.
+	ErrRecordsExceeded = limits.ErrRecordsExceeded
+)
+
+// Limits caps the resources spent on parsing a single report attachment, so a
+// decompression bomb mailed to the rua address fails with an error instead of
+// exhausting the memory. A zero field uses the value of DefaultLimits, and a
+// negative field disables the limit.
+type Limits = limits.Limits
+
+// DefaultLimits are the limits applied when parsing without WithLimits.
+var DefaultLimits = Limits{
+	MaxCompressedSize:   10 << 20,
+	MaxDecompressedSize: 100 << 20,
+	MaxCompressionRatio: 200,
+	MaxZipMembers:       32,
+	MaxRecords:          500_000,
+}
+
+// WithLimits overrides the DefaultLimits.
+func WithLimits(limits Limits) ParseOption {
+	return func(o *parseOptions) {
+		o.limits = limits
+	}
+}
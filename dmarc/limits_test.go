@@ -0,0 +1,195 @@
+package dmarc_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/aldy505/mailweave/dmarc"
+)
+
+func gzipBytes(t *testing.T, content []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestParseFeedbacksLimits(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := os.ReadFile(path.Join(pwd, "../testdata/dmarc/example.org!example.com!1747180800!1747267199.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Whitespace is valid XML and compresses extremely well.
+	bomb := append([]byte("<feedback>"), bytes.Repeat([]byte(" "), 20<<20)...)
+	bomb = append(bomb, "</feedback>"...)
+
+	zipped := func(members int) []byte {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		for i := 0; i < members; i++ {
+			f, err := w.Create(path.Join("reports", string(rune('a'+i))+".xml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.Write(report); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		return buf.Bytes()
+	}
+
+	// Some reporters put gzip compressed reports inside the zip archive.
+	zippedGzip := func(members int) []byte {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		for i := 0; i < members; i++ {
+			f, err := w.Create(string(rune('a'+i)) + ".xml.gz")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.Write(gzipBytes(t, report)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		return buf.Bytes()
+	}
+
+	// The record limit is hit before the parser reaches the malformed end.
+	malformed := bytes.Replace(report, []byte("</feedback>"), []byte("<record><<<"), 1)
+
+	tests := []struct {
+		name    string
+		content []byte
+		limits  dmarc.Limits
+		want    error
+	}{
+		{
+			name:    "compression ratio",
+			content: gzipBytes(t, bomb),
+			want:    dmarc.ErrCompressionRatioExceeded,
+		},
+		{
+			name:    "decompressed size",
+			content: gzipBytes(t, bomb),
+			limits:  dmarc.Limits{MaxDecompressedSize: 1 << 20, MaxCompressionRatio: -1},
+			want:    dmarc.ErrDecompressedSizeExceeded,
+		},
+		{
+			name:    "decompressed size of plain xml",
+			content: report,
+			limits:  dmarc.Limits{MaxDecompressedSize: 512},
+			want:    dmarc.ErrDecompressedSizeExceeded,
+		},
+		{
+			name:    "compressed size",
+			content: gzipBytes(t, report),
+			limits:  dmarc.Limits{MaxCompressedSize: 128},
+			want:    dmarc.ErrCompressedSizeExceeded,
+		},
+		{
+			name:    "zip members",
+			content: zipped(3),
+			limits:  dmarc.Limits{MaxZipMembers: 2},
+			want:    dmarc.ErrZipMembersExceeded,
+		},
+		{
+			name:    "decompressed size across zip members",
+			content: zipped(3),
+			limits:  dmarc.Limits{MaxDecompressedSize: int64(len(report)) * 2},
+			want:    dmarc.ErrDecompressedSizeExceeded,
+		},
+		{
+			name:    "decompressed size across nested gzip members",
+			content: zippedGzip(3),
+			limits:  dmarc.Limits{MaxDecompressedSize: int64(len(report)) * 2},
+			want:    dmarc.ErrDecompressedSizeExceeded,
+		},
+		{
+			name:    "records before the end of the report",
+			content: malformed,
+			limits:  dmarc.Limits{MaxRecords: 1},
+			want:    dmarc.ErrRecordsExceeded,
+		},
+		{
+			name:    "records",
+			content: gzipBytes(t, report),
+			limits:  dmarc.Limits{MaxRecords: 1},
+			want:    dmarc.ErrRecordsExceeded,
+		},
+		{
+			name:    "within limits",
+			content: zipped(2),
+			limits:  dmarc.Limits{MaxZipMembers: 2, MaxRecords: 2},
+		},
+		{
+			name:    "disabled limits",
+			content: gzipBytes(t, bomb),
+			limits:  dmarc.Limits{MaxDecompressedSize: -1, MaxCompressionRatio: -1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := dmarc.ParseFeedbacks(bytes.NewReader(tt.content), dmarc.WithLimits(tt.limits))
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("ParseFeedbacks() error = %v, want nil", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ParseFeedbacks() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("feedback reader records", func(t *testing.T) {
+		reader, err := dmarc.NewFeedbackReader(bytes.NewReader(report), dmarc.WithLimits(dmarc.Limits{MaxRecords: 1}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var count int
+		for _, err := range reader.Records() {
+			if err != nil {
+				if !errors.Is(err, dmarc.ErrRecordsExceeded) {
+					t.Errorf("Records() error = %v, want %v", err, dmarc.ErrRecordsExceeded)
+				}
+				break
+			}
+			count++
+		}
+
+		if count != 1 {
+			t.Errorf("count = %d, want 1", count)
+		}
+	})
+}
@@ -1,17 +1,83 @@
 package dmarc
 
 import (
-	"encoding/xml"
+	"bytes"
 	"fmt"
 	"io"
+
+	"github.com/aldy505/mailweave/internal/limits"
 )
 
-func ParseFeedback(r io.Reader) (Feedback, error) {
-	var feedback Feedback
-	err := xml.NewDecoder(r).Decode(&feedback)
+type parseOptions struct {
+	lenient bool
+	limits  Limits
+}
+
+// ParseOption configures how a report is parsed.
+type ParseOption func(*parseOptions)
+
+// WithLenient enables the lenient parsing mode. Instead of failing on reports
+// with a byte order mark, an ISO-8859-1 or windows-1252 encoding, embedded
+// xs:schema blocks, invalid entity references or truncated trailing tags, the
+// report is repaired before being decoded. The applied repairs are listed in
+// Feedback.Repairs.
+func WithLenient() ParseOption {
+	return func(o *parseOptions) {
+		o.lenient = true
+	}
+}
+
+func newParseOptions(opts []ParseOption) parseOptions {
+	var options parseOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	options.limits = options.limits.WithDefaults(DefaultLimits)
+
+	return options
+}
+
+// ParseFeedback parses a single uncompressed DMARC aggregate report.
+func ParseFeedback(r io.Reader, opts ...ParseOption) (Feedback, error) {
+	options := newParseOptions(opts)
+	r = limits.NewCounter(options.limits.MaxDecompressedSize, ErrDecompressedSizeExceeded).Reader(r)
+
+	var repairs []Repair
+	if options.lenient {
+		content, applied, err := repairFeedback(r)
+		if err != nil {
+			return Feedback{}, fmt.Errorf("failed to parse feedback: %w", err)
+		}
+
+		r = bytes.NewReader(content)
+		repairs = applied
+	}
+
+	// The records are decoded one at a time, so the record limit fails the
+	// parsing before the records past it are held in memory.
+	reader, err := newFeedbackReader(r, options.limits)
 	if err != nil {
-		return Feedback{}, fmt.Errorf("failed to parse feedback: %w", err)
+		return Feedback{}, err
+	}
+
+	var records []Record
+	for record, err := range reader.Records() {
+		if err != nil {
+			return Feedback{}, err
+		}
+
+		records = append(records, record)
+	}
+
+	feedback := Feedback{
+		XMLName:         reader.root,
+		Version:         reader.Version,
+		ReportMetadata:  reader.ReportMetadata,
+		PolicyPublished: reader.PolicyPublished,
+		Records:         records,
+		Repairs:         repairs,
 	}
+	feedback.Format = detectFormat(feedback)
 
 	return feedback, nil
 }
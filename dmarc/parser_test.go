@@ -3,6 +3,7 @@ package dmarc_test
 import (
 	"os"
 	"path"
+	"strings"
 	"testing"
 
 	"github.com/aldy505/mailweave/dmarc"
@@ -37,3 +38,307 @@ func TestParseFeedback(t *testing.T) {
 		}
 	})
 }
+
+func TestParseFeedbackFixtures(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		file          string
+		version       string
+		orgName       string
+		reportID      string
+		begin         int64
+		end           int64
+		fo            string
+		records       int
+		totalMessages int64
+		check         func(t *testing.T, feedback dmarc.Feedback)
+	}{
+		{
+			name:          "amazonses.com",
+			file:          "amazonses.com!example.com!1747180800!1747267200.xml",
+			version:       "0.1",
+			orgName:       "AMAZON-SES",
+			reportID:      "b378e25d-7019-4073-a0d1-084b4a59cac3",
+			begin:         1747180800,
+			end:           1747267200,
+			fo:            "1:0",
+			records:       3,
+			totalMessages: 14,
+			check: func(t *testing.T, feedback dmarc.Feedback) {
+				record := feedback.Records[0]
+				if record.Identifiers.EnvelopeFrom != "postman.com" {
+					t.Errorf("EnvelopeFrom = %s, want postman.com", record.Identifiers.EnvelopeFrom)
+				}
+				if len(record.AuthResults.SPF) != 1 || record.AuthResults.SPF[0].Domain != "postman.com" {
+					t.Errorf("SPF = %+v, want a single result for postman.com", record.AuthResults.SPF)
+				}
+				if len(record.AuthResults.DKIM) != 1 || record.AuthResults.DKIM[0].Domain != "example.com" {
+					t.Errorf("DKIM = %+v, want a single result for example.com", record.AuthResults.DKIM)
+				}
+			},
+		},
+		{
+			name:          "cisco.com",
+			file:          "cisco.com!example.com!1745884803!1745971203.xml",
+			version:       "1.0",
+			orgName:       "cisco.com",
+			reportID:      "1bbdd6$aada01a=576ec7deeb58e253@cisco.com",
+			begin:         1745884803,
+			end:           1745971203,
+			records:       1,
+			totalMessages: 1,
+			check: func(t *testing.T, feedback dmarc.Feedback) {
+				record := feedback.Records[0]
+				if record.Row.SourceIP != "192.0.2.4" {
+					t.Errorf("SourceIP = %s, want 192.0.2.4", record.Row.SourceIP)
+				}
+				if record.AuthResults.DKIM[0].Selector != "outgoing-smtp-2" {
+					t.Errorf("DKIM Selector = %s, want outgoing-smtp-2", record.AuthResults.DKIM[0].Selector)
+				}
+				if record.AuthResults.SPF[0].Scope != "mfrom" {
+					t.Errorf("SPF Scope = %s, want mfrom", record.AuthResults.SPF[0].Scope)
+				}
+			},
+		},
+		{
+			name:          "enterprise.protection.outlook.com",
+			file:          "enterprise.protection.outlook.com!example.com!1747180800!1747267200.xml",
+			version:       "1.0",
+			orgName:       "Enterprise Outlook",
+			reportID:      "59ac00c80bf34a958c9b455b9613c1a3",
+			begin:         1747180800,
+			end:           1747267200,
+			fo:            "0:1",
+			records:       167,
+			totalMessages: 994,
+			check: func(t *testing.T, feedback dmarc.Feedback) {
+				record := feedback.Records[0]
+				if record.Identifiers.EnvelopeTo != "nsgroup.com" {
+					t.Errorf("EnvelopeTo = %s, want nsgroup.com", record.Identifiers.EnvelopeTo)
+				}
+				if record.Identifiers.EnvelopeFrom != "example.com" {
+					t.Errorf("EnvelopeFrom = %s, want example.com", record.Identifiers.EnvelopeFrom)
+				}
+				if record.AuthResults.DKIM[0].Selector != "outgoing-smtp-1" {
+					t.Errorf("DKIM Selector = %s, want outgoing-smtp-1", record.AuthResults.DKIM[0].Selector)
+				}
+			},
+		},
+		{
+			name:          "example.org",
+			file:          "example.org!example.com!1747180800!1747267199.xml",
+			version:       "1.0",
+			orgName:       "example.org",
+			reportID:      "f1c2b6a0-6c2e-4a55-9d7b-2a1f3f0e8c11",
+			begin:         1747180800,
+			end:           1747267199,
+			records:       2,
+			totalMessages: 45,
+			check: func(t *testing.T, feedback dmarc.Feedback) {
+				record := feedback.Records[1]
+				if record.AuthResults.DKIM[0].HumanResult != "signature did not verify" {
+					t.Errorf("DKIM HumanResult = %s, want signature did not verify", record.AuthResults.DKIM[0].HumanResult)
+				}
+				if record.AuthResults.SPF[0].HumanResult != "sender not in SPF record" {
+					t.Errorf("SPF HumanResult = %s, want sender not in SPF record", record.AuthResults.SPF[0].HumanResult)
+				}
+			},
+		},
+		{
+			name:          "google.com",
+			file:          "google.com!example.com!1747008000!1747094399",
+			version:       "1.0",
+			orgName:       "google.com",
+			reportID:      "8639335954371369510",
+			begin:         1747008000,
+			end:           1747094399,
+			records:       16,
+			totalMessages: 115,
+			check: func(t *testing.T, feedback dmarc.Feedback) {
+				record := feedback.Records[1]
+				if len(record.AuthResults.DKIM) != 2 {
+					t.Fatalf("len(DKIM) = %d, want 2", len(record.AuthResults.DKIM))
+				}
+				if record.AuthResults.DKIM[1].Domain != "amazonses.com" {
+					t.Errorf("DKIM Domain = %s, want amazonses.com", record.AuthResults.DKIM[1].Domain)
+				}
+				if record.AuthResults.DKIM[1].Selector != "iowwo7fd7wqffpmrry5t52h55zq2wg7s" {
+					t.Errorf("DKIM Selector = %s, want iowwo7fd7wqffpmrry5t52h55zq2wg7s", record.AuthResults.DKIM[1].Selector)
+				}
+				if record.AuthResults.SPF[0].Domain != "ap-southeast-1.amazonses.com" {
+					t.Errorf("SPF Domain = %s, want ap-southeast-1.amazonses.com", record.AuthResults.SPF[0].Domain)
+				}
+			},
+		},
+		{
+			name:          "zoho.com",
+			file:          "zoho.com!example.com!1746860400!1746946800.xml",
+			version:       "1.0",
+			orgName:       "zoho.com",
+			reportID:      "39fbd056-4829-4075-9704-04f3b59964e9",
+			begin:         1746860400,
+			end:           1746946800,
+			fo:            "0:1",
+			records:       1,
+			totalMessages: 1,
+			check: func(t *testing.T, feedback dmarc.Feedback) {
+				record := feedback.Records[0]
+				if record.AuthResults.DKIM[0].Selector != "outgoing-smtp-2" {
+					t.Errorf("DKIM Selector = %s, want outgoing-smtp-2", record.AuthResults.DKIM[0].Selector)
+				}
+				if record.AuthResults.SPF[0].Scope != "mfrom" {
+					t.Errorf("SPF Scope = %s, want mfrom", record.AuthResults.SPF[0].Scope)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(path.Join(pwd, "../testdata/dmarc", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			feedback, err := dmarc.ParseFeedback(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if feedback.Version != tt.version {
+				t.Errorf("Version = %s, want %s", feedback.Version, tt.version)
+			}
+			if feedback.ReportMetadata.OrgName != tt.orgName {
+				t.Errorf("OrgName = %s, want %s", feedback.ReportMetadata.OrgName, tt.orgName)
+			}
+			if feedback.ReportMetadata.ReportID != tt.reportID {
+				t.Errorf("ReportID = %s, want %s", feedback.ReportMetadata.ReportID, tt.reportID)
+			}
+			if feedback.ReportMetadata.DateRange.Begin != tt.begin {
+				t.Errorf("Begin = %d, want %d", feedback.ReportMetadata.DateRange.Begin, tt.begin)
+			}
+			if feedback.ReportMetadata.DateRange.End != tt.end {
+				t.Errorf("End = %d, want %d", feedback.ReportMetadata.DateRange.End, tt.end)
+			}
+			if feedback.PolicyPublished.Domain != "example.com" {
+				t.Errorf("Domain = %s, want example.com", feedback.PolicyPublished.Domain)
+			}
+			if feedback.PolicyPublished.FO != tt.fo {
+				t.Errorf("FO = %s, want %s", feedback.PolicyPublished.FO, tt.fo)
+			}
+			if len(feedback.Records) != tt.records {
+				t.Fatalf("len(Records) = %d, want %d", len(feedback.Records), tt.records)
+			}
+
+			var totalMessages int64
+			for _, record := range feedback.Records {
+				totalMessages += record.Row.Count
+				if record.Row.SourceIP == "" {
+					t.Errorf("SourceIP is empty")
+				}
+				if !strings.HasSuffix(record.Identifiers.HeaderFrom, "example.com") {
+					t.Errorf("HeaderFrom = %s, want example.com or a subdomain of it", record.Identifiers.HeaderFrom)
+				}
+				if len(record.AuthResults.SPF) == 0 {
+					t.Errorf("SPF auth results are empty")
+				}
+			}
+			if totalMessages != tt.totalMessages {
+				t.Errorf("total messages = %d, want %d", totalMessages, tt.totalMessages)
+			}
+
+			tt.check(t, feedback)
+		})
+	}
+}
+
+func TestParseFeedbackFormat(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("rfc 7489", func(t *testing.T) {
+		f, err := os.Open(path.Join(pwd, "../testdata/dmarc/google.com!example.com!1747008000!1747094399"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		feedback, err := dmarc.ParseFeedback(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if feedback.Format != dmarc.FormatRFC7489 {
+			t.Errorf("Format = %s, want %s", feedback.Format, dmarc.FormatRFC7489)
+		}
+		if feedback.PolicyPublished.PCT != "100" {
+			t.Errorf("PCT = %s, want 100", feedback.PolicyPublished.PCT)
+		}
+		if feedback.PolicyPublished.NP != "reject" {
+			t.Errorf("NP = %s, want reject", feedback.PolicyPublished.NP)
+		}
+	})
+
+	t.Run("dmarcbis", func(t *testing.T) {
+		f, err := os.Open(path.Join(pwd, "../testdata/dmarc/example.org!example.com!1747180800!1747267199.xml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		feedback, err := dmarc.ParseFeedback(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if feedback.Format != dmarc.FormatDMARCbis {
+			t.Errorf("Format = %s, want %s", feedback.Format, dmarc.FormatDMARCbis)
+		}
+		if feedback.ReportMetadata.Generator != "Example DMARC Aggregate Reporter v1.2" {
+			t.Errorf("Generator = %s, want Example DMARC Aggregate Reporter v1.2", feedback.ReportMetadata.Generator)
+		}
+		if feedback.PolicyPublished.PCT != "" {
+			t.Errorf("PCT = %s, want empty", feedback.PolicyPublished.PCT)
+		}
+		if feedback.PolicyPublished.NP != "reject" {
+			t.Errorf("NP = %s, want reject", feedback.PolicyPublished.NP)
+		}
+		if feedback.PolicyPublished.Testing != "n" {
+			t.Errorf("Testing = %s, want n", feedback.PolicyPublished.Testing)
+		}
+		if feedback.PolicyPublished.DiscoveryMethod != "treewalk" {
+			t.Errorf("DiscoveryMethod = %s, want treewalk", feedback.PolicyPublished.DiscoveryMethod)
+		}
+	})
+
+	t.Run("dmarcbis without namespace", func(t *testing.T) {
+		const report = `<feedback>
+  <report_metadata>
+    <org_name>example.org</org_name>
+    <report_id>1</report_id>
+    <generator>Example DMARC Aggregate Reporter v1.2</generator>
+  </report_metadata>
+  <policy_published>
+    <domain>example.com</domain>
+    <p>none</p>
+  </policy_published>
+</feedback>`
+
+		feedback, err := dmarc.ParseFeedback(strings.NewReader(report))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if feedback.Format != dmarc.FormatDMARCbis {
+			t.Errorf("Format = %s, want %s", feedback.Format, dmarc.FormatDMARCbis)
+		}
+	})
+}
@@ -0,0 +1,473 @@
+package dmarc
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// Disposition is the policy actually applied to the messages of a record, as reported in policy_evaluated.
+// Values not defined by the specification are kept as-is, see Disposition.Known.
+type Disposition struct {
+	key    string
+	detail string
+}
+
+func (r *Disposition) Key() string {
+	if r == nil {
+		return ""
+	}
+	return r.key
+}
+
+func (r *Disposition) Detail() string {
+	if r == nil {
+		return ""
+	}
+	return r.detail
+}
+
+// Known reports whether the disposition is defined by the specification.
+func (r *Disposition) Known() bool {
+	return r != nil && r.detail != ""
+}
+
+func (r *Disposition) String() string {
+	return r.Key()
+}
+
+func (r Disposition) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(r.key, start)
+}
+
+func (r *Disposition) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var key string
+	err := d.DecodeElement(&key, &start)
+	if err != nil {
+		return err
+	}
+
+	*r = *ParseDisposition(key)
+	return nil
+}
+
+var DispositionNone = &Disposition{
+	key:    "none",
+	detail: "No specific action was taken regarding delivery of the message.",
+}
+
+var DispositionQuarantine = &Disposition{
+	key:    "quarantine",
+	detail: "The message was treated as suspicious, e.g. delivered to the spam folder.",
+}
+
+var DispositionReject = &Disposition{
+	key:    "reject",
+	detail: "The message was rejected during the SMTP transaction.",
+}
+
+var DispositionPass = &Disposition{
+	key:    "pass",
+	detail: "The message passed DMARC. Only reported on DMARCbis reports.",
+}
+
+// ParseDisposition returns the disposition for key. Matching is case-insensitive
+// and ignores surrounding whitespace. Unknown keys are kept as-is.
+func ParseDisposition(key string) *Disposition {
+	switch strings.ToLower(strings.TrimSpace(key)) {
+	case "none":
+		return DispositionNone
+	case "quarantine":
+		return DispositionQuarantine
+	case "reject":
+		return DispositionReject
+	case "pass":
+		return DispositionPass
+	default:
+		return &Disposition{
+			key:    key,
+			detail: "",
+		}
+	}
+}
+
+// DMARCResult is the DMARC-aligned DKIM or SPF result, as reported in policy_evaluated.
+// Values not defined by the specification are kept as-is, see DMARCResult.Known.
+type DMARCResult struct {
+	key    string
+	detail string
+}
+
+func (r *DMARCResult) Key() string {
+	if r == nil {
+		return ""
+	}
+	return r.key
+}
+
+func (r *DMARCResult) Detail() string {
+	if r == nil {
+		return ""
+	}
+	return r.detail
+}
+
+// Known reports whether the DMARC result is defined by the specification.
+func (r *DMARCResult) Known() bool {
+	return r != nil && r.detail != ""
+}
+
+func (r *DMARCResult) String() string {
+	return r.Key()
+}
+
+func (r DMARCResult) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(r.key, start)
+}
+
+func (r *DMARCResult) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var key string
+	err := d.DecodeElement(&key, &start)
+	if err != nil {
+		return err
+	}
+
+	*r = *ParseDMARCResult(key)
+	return nil
+}
+
+var DMARCResultPass = &DMARCResult{
+	key:    "pass",
+	detail: "The mechanism passed and its identifier is aligned with the RFC5322.From domain.",
+}
+
+var DMARCResultFail = &DMARCResult{
+	key:    "fail",
+	detail: "The mechanism failed or its identifier is not aligned with the RFC5322.From domain.",
+}
+
+// ParseDMARCResult returns the DMARC result for key. Matching is case-insensitive
+// and ignores surrounding whitespace. Unknown keys are kept as-is.
+func ParseDMARCResult(key string) *DMARCResult {
+	switch strings.ToLower(strings.TrimSpace(key)) {
+	case "pass":
+		return DMARCResultPass
+	case "fail":
+		return DMARCResultFail
+	default:
+		return &DMARCResult{
+			key:    key,
+			detail: "",
+		}
+	}
+}
+
+// DKIMResult is the raw DKIM verification result of a signature, as reported in auth_results.
+// Values not defined by the specification are kept as-is, see DKIMResult.Known.
+type DKIMResult struct {
+	key    string
+	detail string
+}
+
+func (r *DKIMResult) Key() string {
+	if r == nil {
+		return ""
+	}
+	return r.key
+}
+
+func (r *DKIMResult) Detail() string {
+	if r == nil {
+		return ""
+	}
+	return r.detail
+}
+
+// Known reports whether the DKIM result is defined by the specification.
+func (r *DKIMResult) Known() bool {
+	return r != nil && r.detail != ""
+}
+
+func (r *DKIMResult) String() string {
+	return r.Key()
+}
+
+func (r DKIMResult) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(r.key, start)
+}
+
+func (r *DKIMResult) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var key string
+	err := d.DecodeElement(&key, &start)
+	if err != nil {
+		return err
+	}
+
+	*r = *ParseDKIMResult(key)
+	return nil
+}
+
+var DKIMResultNone = &DKIMResult{
+	key:    "none",
+	detail: "The message was not signed.",
+}
+
+var DKIMResultPass = &DKIMResult{
+	key:    "pass",
+	detail: "The message was signed, the signature or signatures were acceptable to the ADMD, and the signature(s) passed verification tests.",
+}
+
+var DKIMResultFail = &DKIMResult{
+	key:    "fail",
+	detail: "The message was signed and the signature or signatures were acceptable to the ADMD, but they failed the verification test(s).",
+}
+
+var DKIMResultPolicy = &DKIMResult{
+	key:    "policy",
+	detail: "The message was signed, but some aspect of the signature or signatures was not acceptable to the ADMD.",
+}
+
+var DKIMResultNeutral = &DKIMResult{
+	key:    "neutral",
+	detail: "The message was signed, but the signature or signatures contained syntax errors or were not otherwise able to be processed.",
+}
+
+var DKIMResultTempError = &DKIMResult{
+	key:    "temperror",
+	detail: "The message could not be verified due to some error that is likely transient in nature, such as a temporary inability to retrieve a public key.",
+}
+
+var DKIMResultPermError = &DKIMResult{
+	key:    "permerror",
+	detail: "The message could not be verified due to some error that is unrecoverable, such as a required header field being absent.",
+}
+
+// ParseDKIMResult returns the DKIM result for key. Matching is case-insensitive
+// and ignores surrounding whitespace. Unknown keys are kept as-is.
+func ParseDKIMResult(key string) *DKIMResult {
+	switch strings.ToLower(strings.TrimSpace(key)) {
+	case "none":
+		return DKIMResultNone
+	case "pass":
+		return DKIMResultPass
+	case "fail":
+		return DKIMResultFail
+	case "policy":
+		return DKIMResultPolicy
+	case "neutral":
+		return DKIMResultNeutral
+	case "temperror":
+		return DKIMResultTempError
+	case "permerror":
+		return DKIMResultPermError
+	default:
+		return &DKIMResult{
+			key:    key,
+			detail: "",
+		}
+	}
+}
+
+// SPFResult is the raw SPF evaluation result, as reported in auth_results.
+// Values not defined by the specification are kept as-is, see SPFResult.Known.
+type SPFResult struct {
+	key    string
+	detail string
+}
+
+func (r *SPFResult) Key() string {
+	if r == nil {
+		return ""
+	}
+	return r.key
+}
+
+func (r *SPFResult) Detail() string {
+	if r == nil {
+		return ""
+	}
+	return r.detail
+}
+
+// Known reports whether the SPF result is defined by the specification.
+func (r *SPFResult) Known() bool {
+	return r != nil && r.detail != ""
+}
+
+func (r *SPFResult) String() string {
+	return r.Key()
+}
+
+func (r SPFResult) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(r.key, start)
+}
+
+func (r *SPFResult) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var key string
+	err := d.DecodeElement(&key, &start)
+	if err != nil {
+		return err
+	}
+
+	*r = *ParseSPFResult(key)
+	return nil
+}
+
+var SPFResultNone = &SPFResult{
+	key:    "none",
+	detail: "Either no syntactically valid DNS domain name was extracted from the SMTP session, or no SPF records were retrieved from the DNS.",
+}
+
+var SPFResultNeutral = &SPFResult{
+	key:    "neutral",
+	detail: "The ADMD has explicitly stated that it is not asserting whether the IP address is authorized.",
+}
+
+var SPFResultPass = &SPFResult{
+	key:    "pass",
+	detail: "The client is authorized to inject mail with the given identity.",
+}
+
+var SPFResultFail = &SPFResult{
+	key:    "fail",
+	detail: "The client is not authorized to use the domain in the given identity.",
+}
+
+var SPFResultSoftFail = &SPFResult{
+	key:    "softfail",
+	detail: "The ADMD believes the host is not authorized but is not willing to make a strong policy statement.",
+}
+
+var SPFResultTempError = &SPFResult{
+	key:    "temperror",
+	detail: "The SPF verifier encountered a transient error, most often a DNS error, while performing the check.",
+}
+
+var SPFResultPermError = &SPFResult{
+	key:    "permerror",
+	detail: "The domain's published records could not be correctly interpreted.",
+}
+
+// ParseSPFResult returns the SPF result for key. Matching is case-insensitive
+// and ignores surrounding whitespace. Unknown keys are kept as-is.
+func ParseSPFResult(key string) *SPFResult {
+	switch strings.ToLower(strings.TrimSpace(key)) {
+	case "none":
+		return SPFResultNone
+	case "neutral":
+		return SPFResultNeutral
+	case "pass":
+		return SPFResultPass
+	case "fail":
+		return SPFResultFail
+	case "softfail":
+		return SPFResultSoftFail
+	case "temperror":
+		return SPFResultTempError
+	case "permerror":
+		return SPFResultPermError
+	default:
+		return &SPFResult{
+			key:    key,
+			detail: "",
+		}
+	}
+}
+
+// PolicyOverrideType explains why the applied disposition differs from the published policy.
+// Values not defined by the specification are kept as-is, see PolicyOverrideType.Known.
+type PolicyOverrideType struct {
+	key    string
+	detail string
+}
+
+func (r *PolicyOverrideType) Key() string {
+	if r == nil {
+		return ""
+	}
+	return r.key
+}
+
+func (r *PolicyOverrideType) Detail() string {
+	if r == nil {
+		return ""
+	}
+	return r.detail
+}
+
+// Known reports whether the policy override reason is defined by the specification.
+func (r *PolicyOverrideType) Known() bool {
+	return r != nil && r.detail != ""
+}
+
+func (r *PolicyOverrideType) String() string {
+	return r.Key()
+}
+
+func (r PolicyOverrideType) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(r.key, start)
+}
+
+func (r *PolicyOverrideType) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var key string
+	err := d.DecodeElement(&key, &start)
+	if err != nil {
+		return err
+	}
+
+	*r = *ParsePolicyOverrideType(key)
+	return nil
+}
+
+var PolicyOverrideForwarded = &PolicyOverrideType{
+	key:    "forwarded",
+	detail: "The message was relayed via a known forwarder, or local heuristics identified the message as likely having been forwarded. There is no expectation that authentication would pass.",
+}
+
+var PolicyOverrideSampledOut = &PolicyOverrideType{
+	key:    "sampled_out",
+	detail: "The message was exempted from application of policy by the \"pct\" setting in the DMARC policy record.",
+}
+
+var PolicyOverrideTrustedForwarder = &PolicyOverrideType{
+	key:    "trusted_forwarder",
+	detail: "Message authentication failure was anticipated by other evidence linking the message to a locally maintained list of known and trusted forwarders.",
+}
+
+var PolicyOverrideMailingList = &PolicyOverrideType{
+	key:    "mailing_list",
+	detail: "Local heuristics determined that the message arrived via a mailing list, and thus authentication of the original message was not expected to succeed.",
+}
+
+var PolicyOverrideLocalPolicy = &PolicyOverrideType{
+	key:    "local_policy",
+	detail: "The Mail Receiver's local policy exempted the message from being subjected to the Domain Owner's requested policy action.",
+}
+
+var PolicyOverrideOther = &PolicyOverrideType{
+	key:    "other",
+	detail: "Some policy exception not covered by the other entries in this list occurred. Additional detail can be found in the PolicyOverrideReason's \"comment\" field.",
+}
+
+// ParsePolicyOverrideType returns the policy override reason for key. Matching is case-insensitive
+// and ignores surrounding whitespace. Unknown keys are kept as-is.
+func ParsePolicyOverrideType(key string) *PolicyOverrideType {
+	switch strings.ToLower(strings.TrimSpace(key)) {
+	case "forwarded":
+		return PolicyOverrideForwarded
+	case "sampled_out":
+		return PolicyOverrideSampledOut
+	case "trusted_forwarder":
+		return PolicyOverrideTrustedForwarder
+	case "mailing_list":
+		return PolicyOverrideMailingList
+	case "local_policy":
+		return PolicyOverrideLocalPolicy
+	case "other":
+		return PolicyOverrideOther
+	default:
+		return &PolicyOverrideType{
+			key:    key,
+			detail: "",
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package dmarc_test
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/aldy505/mailweave/dmarc"
+)
+
+func TestParseResultTypes(t *testing.T) {
+	t.Run("known values", func(t *testing.T) {
+		if got := dmarc.ParseDisposition("quarantine"); got != dmarc.DispositionQuarantine {
+			t.Errorf("ParseDisposition(quarantine) = %s, want %s", got, dmarc.DispositionQuarantine)
+		}
+		if got := dmarc.ParseDMARCResult("pass"); got != dmarc.DMARCResultPass {
+			t.Errorf("ParseDMARCResult(pass) = %s, want %s", got, dmarc.DMARCResultPass)
+		}
+		if got := dmarc.ParseDKIMResult("TempError"); got != dmarc.DKIMResultTempError {
+			t.Errorf("ParseDKIMResult(TempError) = %s, want %s", got, dmarc.DKIMResultTempError)
+		}
+		if got := dmarc.ParseSPFResult(" softfail "); got != dmarc.SPFResultSoftFail {
+			t.Errorf("ParseSPFResult(softfail) = %s, want %s", got, dmarc.SPFResultSoftFail)
+		}
+		if got := dmarc.ParsePolicyOverrideType("mailing_list"); got != dmarc.PolicyOverrideMailingList {
+			t.Errorf("ParsePolicyOverrideType(mailing_list) = %s, want %s", got, dmarc.PolicyOverrideMailingList)
+		}
+		if !dmarc.SPFResultSoftFail.Known() {
+			t.Error("SPFResultSoftFail.Known() = false, want true")
+		}
+		if dmarc.SPFResultSoftFail.Detail() == "" {
+			t.Error("SPFResultSoftFail.Detail() is empty")
+		}
+	})
+
+	t.Run("unknown values are kept", func(t *testing.T) {
+		got := dmarc.ParseDKIMResult("hardfail")
+		if got.Key() != "hardfail" {
+			t.Errorf("Key() = %s, want hardfail", got.Key())
+		}
+		if got.Known() {
+			t.Error("Known() = true, want false")
+		}
+		if got.Detail() != "" {
+			t.Errorf("Detail() = %s, want empty", got.Detail())
+		}
+	})
+
+	t.Run("nil values", func(t *testing.T) {
+		var disposition *dmarc.Disposition
+		if disposition.Key() != "" {
+			t.Errorf("Key() = %s, want empty", disposition.Key())
+		}
+		if disposition.Known() {
+			t.Error("Known() = true, want false")
+		}
+	})
+}
+
+func TestResultTypesXML(t *testing.T) {
+	const record = `<record>
+  <row>
+    <source_ip>192.0.2.1</source_ip>
+    <count>1</count>
+    <policy_evaluated>
+      <disposition>quarantine</disposition>
+      <dkim>fail</dkim>
+      <spf>pass</spf>
+      <reason>
+        <type>forwarded</type>
+        <comment>known forwarder</comment>
+      </reason>
+    </policy_evaluated>
+  </row>
+  <identifiers>
+    <header_from>example.com</header_from>
+  </identifiers>
+  <auth_results>
+    <dkim>
+      <domain>example.com</domain>
+      <result>Hardfail</result>
+    </dkim>
+    <spf>
+      <domain>example.com</domain>
+      <result>softfail</result>
+    </spf>
+  </auth_results>
+</record>`
+
+	var parsed dmarc.Record
+	err := xml.NewDecoder(strings.NewReader(record)).Decode(&parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evaluated := parsed.Row.PolicyEvaluated
+	if evaluated.Disposition.Key() != dmarc.DispositionQuarantine.Key() {
+		t.Errorf("Disposition = %s, want quarantine", evaluated.Disposition)
+	}
+	if evaluated.DKIM.Key() != dmarc.DMARCResultFail.Key() {
+		t.Errorf("DKIM = %s, want fail", evaluated.DKIM)
+	}
+	if evaluated.Reasons[0].Type.Detail() != dmarc.PolicyOverrideForwarded.Detail() {
+		t.Errorf("Reason Type detail = %s, want %s", evaluated.Reasons[0].Type.Detail(), dmarc.PolicyOverrideForwarded.Detail())
+	}
+	if parsed.AuthResults.DKIM[0].Result.Key() != "Hardfail" {
+		t.Errorf("DKIM Result = %s, want Hardfail", parsed.AuthResults.DKIM[0].Result)
+	}
+	if parsed.AuthResults.SPF[0].Result.Key() != dmarc.SPFResultSoftFail.Key() {
+		t.Errorf("SPF Result = %s, want softfail", parsed.AuthResults.SPF[0].Result)
+	}
+
+	encoded, err := xml.Marshal(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"<disposition>quarantine</disposition>", "<type>forwarded</type>", "<result>Hardfail</result>", "<result>softfail</result>"} {
+		if !strings.Contains(string(encoded), want) {
+			t.Errorf("encoded record does not contain %s: %s", want, encoded)
+		}
+	}
+}
@@ -93,85 +93,154 @@ type Reports struct {
 	Pages      []int
 }
 
-type dateRange struct {
+// DateRange is the time range in UTC covered by messages in this report,
+// specified in seconds since epoch.
+type DateRange struct {
 	XMLName xml.Name `xml:"date_range"`
 	Begin   int64    `xml:"begin"`
 	End     int64    `xml:"end"`
 }
 
-type reportMetadata struct {
+// BeginTime returns Begin as a time.Time in UTC.
+func (d DateRange) BeginTime() time.Time {
+	return time.Unix(d.Begin, 0).UTC()
+}
+
+// EndTime returns End as a time.Time in UTC.
+func (d DateRange) EndTime() time.Time {
+	return time.Unix(d.End, 0).UTC()
+}
+
+// ReportMetadata is the report generator metadata.
+type ReportMetadata struct {
 	XMLName          xml.Name  `xml:"report_metadata"`
 	OrgName          string    `xml:"org_name"`
 	Email            string    `xml:"email"`
 	ExtraContactInfo string    `xml:"extra_contact_info,omitempty"`
 	ReportID         string    `xml:"report_id"`
-	DateRange        dateRange `xml:"date_range"`
+	DateRange        DateRange `xml:"date_range"`
+	// Errors encountered by the report generator while producing the report.
+	Error []string `xml:"error,omitempty"`
+	// The name and version of the software that generated the report.
+	// Only available on DMARCbis reports.
+	Generator string `xml:"generator,omitempty"`
 }
 
-type policyPublished struct {
+// PolicyPublished is the DMARC policy that applied to the messages in this report.
+type PolicyPublished struct {
 	XMLName xml.Name `xml:"policy_published"`
-	Domain  string   `xml:"domain"`
-	ADKIM   string   `xml:"adkim"`
-	ASPF    string   `xml:"aspf"`
-	P       string   `xml:"p"`
-	SP      string   `xml:"sp"`
-	PCT     string   `xml:"pct"`
-}
-
-type reason struct {
-	XMLName xml.Name `xml:"reason"`
-	Type    string   `xml:"type"`
-	Comment string   `xml:"comment"`
-}
-
-type policyEvaluated struct {
-	XMLName     xml.Name `xml:"policy_evaluated"`
-	Disposition string   `xml:"disposition"`
-	DKIM        string   `xml:"dkim"`
-	SPF         string   `xml:"spf"`
-	Reasons     []reason `xml:"reason"`
-}
-
-type row struct {
+	// The domain at which the DMARC record was found.
+	Domain string `xml:"domain"`
+	// The DKIM alignment mode, either "r" (relaxed) or "s" (strict).
+	ADKIM string `xml:"adkim,omitempty"`
+	// The SPF alignment mode, either "r" (relaxed) or "s" (strict).
+	ASPF string `xml:"aspf,omitempty"`
+	// The policy to apply to messages from the domain.
+	P string `xml:"p"`
+	// The policy to apply to messages from subdomains.
+	SP string `xml:"sp,omitempty"`
+	// The policy to apply to messages from non-existent subdomains.
+	NP string `xml:"np,omitempty"`
+	// The percent of messages to which policy applies.
+	// Removed on DMARCbis reports in favour of Testing.
+	PCT string `xml:"pct,omitempty"`
+	// Failure reporting options in effect.
+	FO string `xml:"fo,omitempty"`
+	// Whether the domain owner is testing its DMARC policy, either "y" or "n".
+	// Only available on DMARCbis reports.
+	Testing string `xml:"testing,omitempty"`
+	// The method used to find the DMARC policy, either "psl" or "treewalk".
+	// Only available on DMARCbis reports.
+	DiscoveryMethod string `xml:"discovery_method,omitempty"`
+}
+
+// PolicyOverrideReason explains why the evaluated disposition differs from
+// the published policy.
+type PolicyOverrideReason struct {
+	XMLName xml.Name            `xml:"reason"`
+	Type    *PolicyOverrideType `xml:"type"`
+	Comment string              `xml:"comment,omitempty"`
+}
+
+// PolicyEvaluated is the taken action and the DMARC results applied to the message.
+type PolicyEvaluated struct {
+	XMLName     xml.Name               `xml:"policy_evaluated"`
+	Disposition *Disposition           `xml:"disposition"`
+	DKIM        *DMARCResult           `xml:"dkim"`
+	SPF         *DMARCResult           `xml:"spf"`
+	Reasons     []PolicyOverrideReason `xml:"reason,omitempty"`
+}
+
+// RecordRow contains the source IP, the number of messages and the policy
+// evaluation result of a single record.
+type RecordRow struct {
 	XMLName         xml.Name        `xml:"row"`
 	SourceIP        string          `xml:"source_ip"`
 	Count           int64           `xml:"count"`
-	PolicyEvaluated policyEvaluated `xml:"policy_evaluated"`
+	PolicyEvaluated PolicyEvaluated `xml:"policy_evaluated"`
 }
 
-type identify struct {
-	XMLName    xml.Name `xml:"identifiers"`
-	HeaderFrom string   `xml:"header_from"`
+// Identifiers contains the identifiers of the messages in a single record.
+type Identifiers struct {
+	XMLName xml.Name `xml:"identifiers"`
+	// The envelope recipient domain.
+	EnvelopeTo string `xml:"envelope_to,omitempty"`
+	// The RFC5321.MailFrom domain.
+	EnvelopeFrom string `xml:"envelope_from,omitempty"`
+	// The RFC5322.From domain.
+	HeaderFrom string `xml:"header_from"`
 }
 
-type spf struct {
-	XMLName xml.Name `xml:"spf"`
-	Result  string   `xml:"result"`
+// DKIMAuthResult is a DKIM evaluation result.
+type DKIMAuthResult struct {
+	XMLName xml.Name `xml:"dkim"`
+	// The "d=" parameter in the signature.
+	Domain string `xml:"domain"`
+	// The "s=" parameter in the signature.
+	Selector string      `xml:"selector,omitempty"`
+	Result   *DKIMResult `xml:"result"`
+	// Any extra information (e.g., from Authentication-Results).
+	HumanResult string `xml:"human_result,omitempty"`
 }
 
-type dkim struct {
-	XMLName xml.Name `xml:"dkim"`
-	Result  string   `xml:"result"`
+// SPFAuthResult is an SPF evaluation result.
+type SPFAuthResult struct {
+	XMLName xml.Name `xml:"spf"`
+	// The checked domain.
+	Domain string `xml:"domain"`
+	// The scope of the checked domain, either "helo" or "mfrom".
+	Scope  string     `xml:"scope,omitempty"`
+	Result *SPFResult `xml:"result"`
+	// Any extra information (e.g., from Authentication-Results).
+	// Only available on DMARCbis reports.
+	HumanResult string `xml:"human_result,omitempty"`
 }
 
-type authResult struct {
-	XMLName xml.Name `xml:"auth_results"`
-	SPF     []spf    `xml:"spf"`
-	DKIM    []dkim   `xml:"dkim"`
+// AuthResults contains the DKIM and SPF results, uninterpreted with respect to DMARC.
+type AuthResults struct {
+	XMLName xml.Name         `xml:"auth_results"`
+	DKIM    []DKIMAuthResult `xml:"dkim,omitempty"`
+	SPF     []SPFAuthResult  `xml:"spf"`
 }
 
-type record struct {
-	XMLName     xml.Name   `xml:"record"`
-	Rows        []row      `xml:"row"`
-	Identifiers identify   `xml:"identifiers"`
-	AuthResults authResult `xml:"auth_results"`
+// Record is a single row of the report, grouping messages that share the
+// same source IP, identifiers and authentication results.
+type Record struct {
+	XMLName     xml.Name    `xml:"record"`
+	Row         RecordRow   `xml:"row"`
+	Identifiers Identifiers `xml:"identifiers"`
+	AuthResults AuthResults `xml:"auth_results"`
 }
 
 // Feedback contains the reports and file information
 type Feedback struct {
-	XMLName         xml.Name `xml:"feedback"`
-	FromFile        string
-	ReportMetadata  reportMetadata  `xml:"report_metadata"`
-	PolicyPublished policyPublished `xml:"policy_published"`
-	Records         []record        `xml:"record"`
+	XMLName  xml.Name `xml:"feedback"`
+	FromFile string   `xml:"-"`
+	Format   Format   `xml:"-"`
+	// Repairs lists the repairs applied when parsed in lenient mode.
+	Repairs         []Repair        `xml:"-"`
+	Version         string          `xml:"version,omitempty"`
+	ReportMetadata  ReportMetadata  `xml:"report_metadata"`
+	PolicyPublished PolicyPublished `xml:"policy_published"`
+	Records         []Record        `xml:"record"`
 }
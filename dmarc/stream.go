@@ -0,0 +1,194 @@
+package dmarc
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+
+	"github.com/aldy505/mailweave/internal/limits"
+)
+
+// FeedbackReader reads a DMARC aggregate report incrementally. The report
+// version, metadata and published policy are available as soon as the reader
+// is created, while records are decoded one at a time through Records, so the
+// memory usage does not depend on the number of records in the report.
+type FeedbackReader struct {
+	Version         string
+	Format          Format
+	ReportMetadata  ReportMetadata
+	PolicyPublished PolicyPublished
+
+	decoder *xml.Decoder
+	root    xml.Name
+	limits  Limits
+	records int
+	// next is the start element of the first record, consumed while reading the header.
+	next *xml.StartElement
+	done bool
+}
+
+// NewFeedbackReader reads the report header from r up to the first record.
+// Only the decompressed size and record limits of the options apply, as r is
+// expected to be uncompressed. Lenient mode is not supported.
+func NewFeedbackReader(r io.Reader, opts ...ParseOption) (*FeedbackReader, error) {
+	parseLimits := newParseOptions(opts).limits
+	r = limits.NewCounter(parseLimits.MaxDecompressedSize, ErrDecompressedSizeExceeded).Reader(r)
+
+	return newFeedbackReader(r, parseLimits)
+}
+
+// newFeedbackReader reads the report header from r, which is already limited
+// in size by the caller.
+func newFeedbackReader(r io.Reader, parseLimits Limits) (*FeedbackReader, error) {
+	f := &FeedbackReader{
+		decoder: xml.NewDecoder(r),
+		limits:  parseLimits,
+	}
+
+	for {
+		token, err := f.decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse feedback: %w", err)
+		}
+
+		if start, ok := token.(xml.StartElement); ok {
+			if start.Name.Local != "feedback" {
+				return nil, fmt.Errorf("failed to parse feedback: expected element type <feedback> but have <%s>", start.Name.Local)
+			}
+
+			f.root = start.Name
+			break
+		}
+	}
+
+	start, err := f.nextStartElement()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse feedback: %w", err)
+	}
+	f.next = start
+
+	f.Format = detectFormat(Feedback{
+		XMLName:         f.root,
+		ReportMetadata:  f.ReportMetadata,
+		PolicyPublished: f.PolicyPublished,
+	})
+
+	return f, nil
+}
+
+// nextStartElement advances to the next child element of the root element.
+// Header elements are decoded into f, and unknown elements are skipped.
+// A nil element is returned once the end of the root element is reached.
+func (f *FeedbackReader) nextStartElement() (*xml.StartElement, error) {
+	for {
+		token, err := f.decoder.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.ErrUnexpectedEOF
+			}
+
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.EndElement:
+			f.done = true
+			return nil, nil
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "record":
+				return &t, nil
+			case "version":
+				err = f.decoder.DecodeElement(&f.Version, &t)
+			case "report_metadata":
+				err = f.decoder.DecodeElement(&f.ReportMetadata, &t)
+			case "policy_published":
+				err = f.decoder.DecodeElement(&f.PolicyPublished, &t)
+			default:
+				err = f.decoder.Skip()
+			}
+
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// Records returns an iterator over the records of the report. The iterator
+// stops after yielding the first error. It can only be consumed once.
+func (f *FeedbackReader) Records() iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		for !f.done {
+			start := f.next
+			f.next = nil
+
+			if start == nil {
+				var err error
+				start, err = f.nextStartElement()
+				if err != nil {
+					f.done = true
+					yield(Record{}, fmt.Errorf("failed to parse record: %w", err))
+					return
+				}
+
+				if start == nil {
+					return
+				}
+			}
+
+			f.records++
+			if max := f.limits.MaxRecords; max >= 0 && f.records > max {
+				f.done = true
+				yield(Record{}, fmt.Errorf("failed to parse record: %w: limit is %d", ErrRecordsExceeded, max))
+				return
+			}
+
+			var record Record
+			err := f.decoder.DecodeElement(&record, start)
+			if err != nil {
+				f.done = true
+				yield(Record{}, fmt.Errorf("failed to parse record: %w", err))
+				return
+			}
+
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Batch groups the records yielded by records into slices of at most size
+// records, so they can be written to a datastore in batches. The last batch
+// may be smaller than size. The iterator stops after yielding the first error.
+func Batch(records iter.Seq2[Record, error], size int) iter.Seq2[[]Record, error] {
+	if size < 1 {
+		size = 1
+	}
+
+	return func(yield func([]Record, error) bool) {
+		batch := make([]Record, 0, size)
+		for record, err := range records {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			batch = append(batch, record)
+			if len(batch) == size {
+				if !yield(batch, nil) {
+					return
+				}
+
+				batch = make([]Record, 0, size)
+			}
+		}
+
+		if len(batch) > 0 {
+			yield(batch, nil)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package dmarc_test
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/aldy505/mailweave/dmarc"
+)
+
+func TestFeedbackReader(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("outlook xml", func(t *testing.T) {
+		f, err := os.Open(path.Join(pwd, "../testdata/dmarc/enterprise.protection.outlook.com!example.com!1747180800!1747267200.xml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		reader, err := dmarc.NewFeedbackReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if reader.Version != "1.0" {
+			t.Errorf("Version = %s, want 1.0", reader.Version)
+		}
+		if reader.ReportMetadata.OrgName != "Enterprise Outlook" {
+			t.Errorf("OrgName = %s, want Enterprise Outlook", reader.ReportMetadata.OrgName)
+		}
+		if reader.PolicyPublished.FO != "0:1" {
+			t.Errorf("FO = %s, want 0:1", reader.PolicyPublished.FO)
+		}
+		if reader.Format != dmarc.FormatRFC7489 {
+			t.Errorf("Format = %s, want %s", reader.Format, dmarc.FormatRFC7489)
+		}
+
+		var records int
+		var totalMessages int64
+		for record, err := range reader.Records() {
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			records++
+			totalMessages += record.Row.Count
+		}
+
+		if records != 167 {
+			t.Errorf("records = %d, want 167", records)
+		}
+		if totalMessages != 994 {
+			t.Errorf("total messages = %d, want 994", totalMessages)
+		}
+	})
+
+	t.Run("dmarcbis xml", func(t *testing.T) {
+		f, err := os.Open(path.Join(pwd, "../testdata/dmarc/example.org!example.com!1747180800!1747267199.xml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		reader, err := dmarc.NewFeedbackReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if reader.Format != dmarc.FormatDMARCbis {
+			t.Errorf("Format = %s, want %s", reader.Format, dmarc.FormatDMARCbis)
+		}
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		f, err := os.Open(path.Join(pwd, "../testdata/dmarc/enterprise.protection.outlook.com!example.com!1747180800!1747267200.xml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		reader, err := dmarc.NewFeedbackReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var sizes []int
+		for batch, err := range dmarc.Batch(reader.Records(), 50) {
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sizes = append(sizes, len(batch))
+		}
+
+		want := []int{50, 50, 50, 17}
+		if len(sizes) != len(want) {
+			t.Fatalf("batch sizes = %v, want %v", sizes, want)
+		}
+		for i := range want {
+			if sizes[i] != want[i] {
+				t.Errorf("batch sizes = %v, want %v", sizes, want)
+				break
+			}
+		}
+	})
+
+	t.Run("no records", func(t *testing.T) {
+		reader, err := dmarc.NewFeedbackReader(strings.NewReader(`<feedback><report_metadata><org_name>example.org</org_name></report_metadata></feedback>`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if reader.ReportMetadata.OrgName != "example.org" {
+			t.Errorf("OrgName = %s, want example.org", reader.ReportMetadata.OrgName)
+		}
+
+		for range reader.Records() {
+			t.Error("expected no records")
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		reader, err := dmarc.NewFeedbackReader(strings.NewReader(`<feedback><record><row><source_ip>192.0.2.1</source_ip></row></record><record><row>`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var records int
+		var lastErr error
+		for _, err := range reader.Records() {
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			records++
+		}
+
+		if records != 1 {
+			t.Errorf("records = %d, want 1", records)
+		}
+		if lastErr == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+
+	t.Run("not a feedback", func(t *testing.T) {
+		_, err := dmarc.NewFeedbackReader(strings.NewReader(`<html></html>`))
+		if err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package dmarc
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/aldy505/mailweave/internal/validation"
+)
+
+// FieldError describes a problem found on a single field of a report. Field
+// is the path to the offending element, e.g. "record[3].row.source_ip".
+type FieldError = validation.FieldError
+
+// ValidationResult is the outcome of Validate. Errors are problems that make
+// the report unusable, while Warnings are suspicious but tolerable values.
+type ValidationResult = validation.Result
+
+// Validate checks the semantics of a parsed aggregate report, on top of the
+// syntax checks done by the parser.
+func Validate(feedback Feedback) ValidationResult {
+	var v ValidationResult
+
+	metadata := feedback.ReportMetadata
+	if metadata.OrgName == "" {
+		v.AddError("report_metadata.org_name", "must not be empty")
+	}
+	if metadata.ReportID == "" {
+		v.AddError("report_metadata.report_id", "must not be empty")
+	}
+	if metadata.Email == "" {
+		v.AddWarning("report_metadata.email", "is empty")
+	}
+
+	dateRange := metadata.DateRange
+	switch {
+	case dateRange.Begin <= 0:
+		v.AddError("report_metadata.date_range.begin", "must be a positive timestamp, got %d", dateRange.Begin)
+	case dateRange.End < dateRange.Begin:
+		v.AddError("report_metadata.date_range.end", "%d is before begin %d", dateRange.End, dateRange.Begin)
+	case dateRange.EndTime().Sub(dateRange.BeginTime()) > 7*24*time.Hour:
+		v.AddWarning("report_metadata.date_range", "covers more than 7 days")
+	}
+	if dateRange.BeginTime().After(time.Now()) {
+		v.AddWarning("report_metadata.date_range.begin", "is in the future")
+	}
+
+	policy := feedback.PolicyPublished
+	if policy.Domain == "" {
+		v.AddError("policy_published.domain", "must not be empty")
+	}
+	if !isPolicy(policy.P) {
+		v.AddError("policy_published.p", "unknown policy %q", policy.P)
+	}
+	if policy.SP != "" && !isPolicy(policy.SP) {
+		v.AddWarning("policy_published.sp", "unknown policy %q", policy.SP)
+	}
+	if policy.NP != "" && !isPolicy(policy.NP) {
+		v.AddWarning("policy_published.np", "unknown policy %q", policy.NP)
+	}
+	if policy.ADKIM != "" && policy.ADKIM != "r" && policy.ADKIM != "s" {
+		v.AddWarning("policy_published.adkim", "unknown alignment mode %q", policy.ADKIM)
+	}
+	if policy.ASPF != "" && policy.ASPF != "r" && policy.ASPF != "s" {
+		v.AddWarning("policy_published.aspf", "unknown alignment mode %q", policy.ASPF)
+	}
+	if policy.PCT != "" {
+		pct, err := strconv.Atoi(policy.PCT)
+		if err != nil || pct < 0 || pct > 100 {
+			v.AddWarning("policy_published.pct", "must be an integer between 0 and 100, got %q", policy.PCT)
+		}
+	}
+	if policy.Testing != "" && policy.Testing != "y" && policy.Testing != "n" {
+		v.AddWarning("policy_published.testing", "must be either y or n, got %q", policy.Testing)
+	}
+
+	for i, record := range feedback.Records {
+		validateRecord(&v, fmt.Sprintf("record[%d]", i), record)
+	}
+
+	return v
+}
+
+func validateRecord(v *ValidationResult, field string, record Record) {
+	row := record.Row
+	if _, err := netip.ParseAddr(row.SourceIP); err != nil {
+		v.AddError(field+".row.source_ip", "invalid IP address %q", row.SourceIP)
+	}
+	switch {
+	case row.Count < 0:
+		v.AddError(field+".row.count", "must not be negative, got %d", row.Count)
+	case row.Count == 0:
+		v.AddWarning(field+".row.count", "is zero")
+	}
+
+	evaluated := row.PolicyEvaluated
+	if !evaluated.Disposition.Known() {
+		v.AddError(field+".row.policy_evaluated.disposition", "unknown disposition %q", evaluated.Disposition.Key())
+	}
+	if !evaluated.DKIM.Known() {
+		v.AddError(field+".row.policy_evaluated.dkim", "must be either pass or fail, got %q", evaluated.DKIM.Key())
+	}
+	if !evaluated.SPF.Known() {
+		v.AddError(field+".row.policy_evaluated.spf", "must be either pass or fail, got %q", evaluated.SPF.Key())
+	}
+	for j, reason := range evaluated.Reasons {
+		if !reason.Type.Known() {
+			v.AddWarning(fmt.Sprintf("%s.row.policy_evaluated.reason[%d].type", field, j), "unknown policy override reason %q", reason.Type.Key())
+		}
+	}
+
+	if record.Identifiers.HeaderFrom == "" {
+		v.AddError(field+".identifiers.header_from", "must not be empty")
+	}
+
+	for j, dkim := range record.AuthResults.DKIM {
+		if !dkim.Result.Known() {
+			v.AddWarning(fmt.Sprintf("%s.auth_results.dkim[%d].result", field, j), "unknown DKIM result %q", dkim.Result.Key())
+		}
+		if dkim.Domain == "" {
+			v.AddWarning(fmt.Sprintf("%s.auth_results.dkim[%d].domain", field, j), "is empty")
+		}
+	}
+	if len(record.AuthResults.SPF) == 0 {
+		v.AddWarning(field+".auth_results.spf", "must contain at least one result")
+	}
+	for j, spf := range record.AuthResults.SPF {
+		if !spf.Result.Known() {
+			v.AddWarning(fmt.Sprintf("%s.auth_results.spf[%d].result", field, j), "unknown SPF result %q", spf.Result.Key())
+		}
+		if spf.Scope != "" && spf.Scope != "helo" && spf.Scope != "mfrom" {
+			v.AddWarning(fmt.Sprintf("%s.auth_results.spf[%d].scope", field, j), "unknown SPF scope %q", spf.Scope)
+		}
+	}
+}
+
+func isPolicy(p string) bool {
+	return p == "none" || p == "quarantine" || p == "reject"
+}
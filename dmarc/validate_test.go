@@ -0,0 +1,124 @@
+package dmarc_test
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/aldy505/mailweave/dmarc"
+)
+
+func TestValidate(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("fixtures", func(t *testing.T) {
+		entries, err := os.ReadDir(path.Join(pwd, "../testdata/dmarc"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, entry := range entries {
+			t.Run(entry.Name(), func(t *testing.T) {
+				f, err := os.Open(path.Join(pwd, "../testdata/dmarc", entry.Name()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+
+				feedback, err := dmarc.ParseFeedback(f)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				result := dmarc.Validate(feedback)
+				if !result.Valid() {
+					t.Errorf("Validate() = %v, want no errors", result.Err())
+				}
+			})
+		}
+	})
+
+	t.Run("invalid report", func(t *testing.T) {
+		const report = `<feedback>
+  <report_metadata>
+    <org_name>example.org</org_name>
+    <email>dmarc@example.org</email>
+    <report_id>1</report_id>
+    <date_range>
+      <begin>1747267200</begin>
+      <end>1747180800</end>
+    </date_range>
+  </report_metadata>
+  <policy_published>
+    <domain>example.com</domain>
+    <p>reject</p>
+    <pct>150</pct>
+  </policy_published>
+  <record>
+    <row>
+      <source_ip>192.0.2.300</source_ip>
+      <count>-1</count>
+      <policy_evaluated>
+        <disposition>delete</disposition>
+        <dkim>pass</dkim>
+        <spf>pass</spf>
+      </policy_evaluated>
+    </row>
+    <identifiers>
+      <header_from>example.com</header_from>
+    </identifiers>
+    <auth_results>
+      <spf>
+        <domain>example.com</domain>
+        <result>maybe</result>
+      </spf>
+    </auth_results>
+  </record>
+</feedback>`
+
+		feedback, err := dmarc.ParseFeedback(strings.NewReader(report))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := dmarc.Validate(feedback)
+		if result.Valid() {
+			t.Fatal("Valid() = true, want false")
+		}
+		if result.Err() == nil {
+			t.Fatal("Err() = nil, want an error")
+		}
+
+		wantErrors := []string{
+			"report_metadata.date_range.end",
+			"record[0].row.source_ip",
+			"record[0].row.count",
+			"record[0].row.policy_evaluated.disposition",
+		}
+		if len(result.Errors) != len(wantErrors) {
+			t.Fatalf("Errors = %v, want errors on %v", result.Errors, wantErrors)
+		}
+		for i, field := range wantErrors {
+			if result.Errors[i].Field != field {
+				t.Errorf("Errors[%d].Field = %s, want %s", i, result.Errors[i].Field, field)
+			}
+		}
+
+		wantWarnings := []string{
+			"policy_published.pct",
+			"record[0].auth_results.spf[0].result",
+		}
+		if len(result.Warnings) != len(wantWarnings) {
+			t.Fatalf("Warnings = %v, want warnings on %v", result.Warnings, wantWarnings)
+		}
+		for i, field := range wantWarnings {
+			if result.Warnings[i].Field != field {
+				t.Errorf("Warnings[%d].Field = %s, want %s", i, result.Warnings[i].Field, field)
+			}
+		}
+	})
+}
@@ -0,0 +1,188 @@
+package mailweave
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aldy505/mailweave/dmarc"
+)
+
+// NewDmarcReport converts a parsed aggregate report into a DmarcReport.
+//
+// The fields that do not come from the report itself (DomainOwner, ReceivedAt,
+// EmailSender, EmailSubject and Content) are left for the caller to fill in,
+// and so is the ResolvedHostname of the rows, see ResolveDmarcReportHostnames.
+// When the report was extracted from a zip archive, the member name is used as
+// ReportFileName and cross-checked with ReportFileNameMismatches. Otherwise,
+// the caller should do so with the attachment filename, see SetReportFileName.
+func NewDmarcReport(feedback dmarc.Feedback) DmarcReport {
+	metadata := feedback.ReportMetadata
+
+	report := DmarcReport{
+		OrganizationName: metadata.OrgName,
+		DomainName:       reporterDomain(metadata),
+		ExtraContactInfo: metadata.ExtraContactInfo,
+		ReportId:         metadata.ReportID,
+		RangeStart:       metadata.DateRange.BeginTime(),
+		RangeEnd:         metadata.DateRange.EndTime(),
+		PolicyDomain:     feedback.PolicyPublished.Domain,
+		Rows:             make([]DmarcReportRow, 0, len(feedback.Records)),
+	}
+
+	if feedback.FromFile != "" {
+		report.SetReportFileName(feedback.FromFile)
+	}
+
+	for _, record := range feedback.Records {
+		row := NewDmarcReportRow(feedback.PolicyPublished, record)
+		report.TotalNumberOfEmails += row.EmailCount
+		report.Rows = append(report.Rows, row)
+	}
+
+	return report
+}
+
+// NewDmarcReportRow converts a single record of an aggregate report into a
+// DmarcReportRow. The DMARC alignment is evaluated from the raw DKIM and SPF
+// results against the RFC5322.From domain, using the alignment modes of the
+// published policy.
+func NewDmarcReportRow(policy dmarc.PolicyPublished, record dmarc.Record) DmarcReportRow {
+	headerFrom := record.Identifiers.HeaderFrom
+	if headerFrom == "" {
+		headerFrom = policy.Domain
+	}
+
+	row := DmarcReportRow{
+		EmailCount:       record.Row.Count,
+		SourceIP:         record.Row.SourceIP,
+		EnvelopeTo:       record.Identifiers.EnvelopeTo,
+		EnvelopeFrom:     record.Identifiers.EnvelopeFrom,
+		HeaderFrom:       headerFrom,
+		DMARCDisposition: record.Row.PolicyEvaluated.Disposition.Key(),
+	}
+
+	spfMode := dmarc.ParseAlignmentMode(policy.ASPF)
+	if spf, ok := selectSPFAuthResult(record.AuthResults.SPF); ok {
+		row.SPFDomain = spf.Domain
+		row.SPFResult = spf.Result.Key()
+		row.SPFScope = spf.Scope
+		row.DMARCSPFAligned = spf.Result.Key() == dmarc.SPFResultPass.Key() && dmarc.Aligned(spfMode, spf.Domain, headerFrom)
+	}
+
+	dkimMode := dmarc.ParseAlignmentMode(policy.ADKIM)
+	if dkim, ok := selectDKIMAuthResult(record.AuthResults.DKIM, dkimMode, headerFrom); ok {
+		row.DKIMDomain = dkim.Domain
+		row.DKIMSelector = dkim.Selector
+		row.DKIMResult = dkim.Result.Key()
+		row.DMARCDKIMAligned = dkim.Result.Key() == dmarc.DKIMResultPass.Key() && dmarc.Aligned(dkimMode, dkim.Domain, headerFrom)
+	}
+
+	row.DMARCInferredAligned = row.DMARCSPFAligned || row.DMARCDKIMAligned
+
+	return row
+}
+
+// ReverseResolver looks up the PTR records of an IP address. *net.Resolver
+// and resolver.FakeResolver implement it.
+type ReverseResolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// ResolveDmarcReportHostnames fills the ResolvedHostname of the rows with the
+// first PTR record of their source IP. Each address is looked up once, and the
+// rows whose lookup fails keep an empty ResolvedHostname, as the hostname is
+// only informative.
+func ResolveDmarcReportHostnames(ctx context.Context, resolver ReverseResolver, report *DmarcReport) {
+	hostnames := make(map[string]string)
+	for i := range report.Rows {
+		row := &report.Rows[i]
+		if row.SourceIP == "" {
+			continue
+		}
+
+		hostname, ok := hostnames[row.SourceIP]
+		if !ok {
+			if names, err := resolver.LookupAddr(ctx, row.SourceIP); err == nil && len(names) > 0 {
+				hostname = strings.TrimSuffix(names[0], ".")
+			}
+			hostnames[row.SourceIP] = hostname
+		}
+
+		row.ResolvedHostname = hostname
+	}
+}
+
+// selectSPFAuthResult picks the SPF result DMARC relies on, which is the
+// MAIL FROM identity when reported.
+func selectSPFAuthResult(results []dmarc.SPFAuthResult) (dmarc.SPFAuthResult, bool) {
+	if len(results) == 0 {
+		return dmarc.SPFAuthResult{}, false
+	}
+
+	for _, result := range results {
+		if result.Scope == "" || result.Scope == "mfrom" {
+			return result, true
+		}
+	}
+
+	return results[0], true
+}
+
+// selectDKIMAuthResult picks the most relevant of possibly many DKIM signatures:
+// an aligned passing signature, then any passing signature, then the first one.
+func selectDKIMAuthResult(results []dmarc.DKIMAuthResult, mode dmarc.AlignmentMode, headerFrom string) (dmarc.DKIMAuthResult, bool) {
+	if len(results) == 0 {
+		return dmarc.DKIMAuthResult{}, false
+	}
+
+	for _, result := range results {
+		if result.Result.Key() == dmarc.DKIMResultPass.Key() && dmarc.Aligned(mode, result.Domain, headerFrom) {
+			return result, true
+		}
+	}
+
+	for _, result := range results {
+		if result.Result.Key() == dmarc.DKIMResultPass.Key() {
+			return result, true
+		}
+	}
+
+	return results[0], true
+}
+
+// reporterDomain derives the organizational domain of the reporter from its email address.
+func reporterDomain(metadata dmarc.ReportMetadata) string {
+	_, domain, ok := strings.Cut(metadata.Email, "@")
+	if !ok {
+		return ""
+	}
+
+	return dmarc.OrganizationalDomain(domain)
+}
+
+// NewDmarcDnsRecord converts a DMARC record parsed with dmarc.ParseDNSRecord
+// into a DmarcDnsRecord.
+func NewDmarcDnsRecord(record dmarc.DNSRecord) DmarcDnsRecord {
+	dnsRecord := DmarcDnsRecord{
+		Policy:                     record.Policy,
+		SubdomainPolicy:            record.SubdomainPolicy,
+		NonExistentSubdomainPolicy: record.NonExistentSubdomainPolicy,
+		Percentage:                 float64(record.Percentage),
+		AggregateReportingURI:      make([]string, 0, len(record.AggregateReportURIs)),
+		FailureReportingURI:        make([]string, 0, len(record.FailureReportURIs)),
+		SPFAlignmentMode:           string(record.SPFAlignment),
+		DKIMAlignmentMode:          string(record.DKIMAlignment),
+		FailureReportingOptions:    record.FailureOptions,
+		ReportFormat:               record.ReportFormats,
+		ReportInterval:             record.ReportInterval,
+	}
+
+	for _, uri := range record.AggregateReportURIs {
+		dnsRecord.AggregateReportingURI = append(dnsRecord.AggregateReportingURI, uri.String())
+	}
+	for _, uri := range record.FailureReportURIs {
+		dnsRecord.FailureReportingURI = append(dnsRecord.FailureReportingURI, uri.String())
+	}
+
+	return dnsRecord
+}
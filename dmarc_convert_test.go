@@ -0,0 +1,203 @@
+package mailweave_test
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/aldy505/mailweave"
+	"github.com/aldy505/mailweave/dmarc"
+	"github.com/aldy505/mailweave/resolver"
+)
+
+func TestNewDmarcReport(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("amazon xml", func(t *testing.T) {
+		f, err := os.Open(path.Join(pwd, "testdata/dmarc/amazonses.com!example.com!1747180800!1747267200.xml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		feedback, err := dmarc.ParseFeedback(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		report := mailweave.NewDmarcReport(feedback)
+		if report.OrganizationName != "AMAZON-SES" {
+			t.Errorf("OrganizationName = %s, want AMAZON-SES", report.OrganizationName)
+		}
+		if report.DomainName != "amazonses.com" {
+			t.Errorf("DomainName = %s, want amazonses.com", report.DomainName)
+		}
+		if report.ExtraContactInfo != "" {
+			t.Errorf("ExtraContactInfo = %s, want it empty", report.ExtraContactInfo)
+		}
+		if !report.RangeStart.Equal(time.Date(2025, time.May, 14, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("RangeStart = %s, want 2025-05-14 00:00:00 UTC", report.RangeStart)
+		}
+		if report.TotalNumberOfEmails != 14 {
+			t.Errorf("TotalNumberOfEmails = %d, want 14", report.TotalNumberOfEmails)
+		}
+		if len(report.Rows) != 3 {
+			t.Fatalf("len(Rows) = %d, want 3", len(report.Rows))
+		}
+
+		row := report.Rows[0]
+		if row.EnvelopeFrom != "postman.com" {
+			t.Errorf("EnvelopeFrom = %s, want postman.com", row.EnvelopeFrom)
+		}
+		if row.SPFDomain != "postman.com" || row.SPFResult != "pass" {
+			t.Errorf("SPF = %s %s, want postman.com pass", row.SPFDomain, row.SPFResult)
+		}
+		if row.DMARCSPFAligned {
+			t.Error("DMARCSPFAligned = true, want false")
+		}
+		if !row.DMARCDKIMAligned {
+			t.Error("DMARCDKIMAligned = false, want true")
+		}
+		if !row.DMARCInferredAligned {
+			t.Error("DMARCInferredAligned = false, want true")
+		}
+		if row.DMARCDisposition != "none" {
+			t.Errorf("DMARCDisposition = %s, want none", row.DMARCDisposition)
+		}
+
+		row = report.Rows[1]
+		if !row.DMARCSPFAligned || !row.DMARCDKIMAligned {
+			t.Errorf("alignment = spf %v dkim %v, want both aligned", row.DMARCSPFAligned, row.DMARCDKIMAligned)
+		}
+	})
+
+	t.Run("google xml", func(t *testing.T) {
+		f, err := os.Open(path.Join(pwd, "testdata/dmarc/google.com!example.com!1747008000!1747094399"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		feedback, err := dmarc.ParseFeedback(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		report := mailweave.NewDmarcReport(feedback)
+		if report.ExtraContactInfo != "https://support.google.com/a/answer/2466580" {
+			t.Errorf("ExtraContactInfo = %s, want https://support.google.com/a/answer/2466580", report.ExtraContactInfo)
+		}
+		if report.TotalNumberOfEmails != 115 {
+			t.Errorf("TotalNumberOfEmails = %d, want 115", report.TotalNumberOfEmails)
+		}
+
+		// The first row has two failing DKIM signatures.
+		row := report.Rows[0]
+		if row.DKIMResult != "fail" || row.DMARCDKIMAligned {
+			t.Errorf("DKIM = %s aligned %v, want fail and not aligned", row.DKIMResult, row.DMARCDKIMAligned)
+		}
+		if !row.DMARCSPFAligned {
+			t.Error("DMARCSPFAligned = false, want true")
+		}
+
+		// The second row is signed by both example.com and amazonses.com.
+		row = report.Rows[1]
+		if row.DKIMDomain != "example.com" || row.DKIMSelector != "wga44lpul4p7rva52ovzl5csydgwelke" {
+			t.Errorf("DKIM = %s %s, want example.com wga44lpul4p7rva52ovzl5csydgwelke", row.DKIMDomain, row.DKIMSelector)
+		}
+		if !row.DMARCDKIMAligned {
+			t.Error("DMARCDKIMAligned = false, want true")
+		}
+		if row.SPFDomain != "ap-southeast-1.amazonses.com" || row.DMARCSPFAligned {
+			t.Errorf("SPF = %s aligned %v, want ap-southeast-1.amazonses.com and not aligned", row.SPFDomain, row.DMARCSPFAligned)
+		}
+	})
+}
+
+func TestNewDmarcReportRowStrictAlignment(t *testing.T) {
+	record := dmarc.Record{
+		Row: dmarc.RecordRow{
+			SourceIP: "192.0.2.1",
+			Count:    5,
+			PolicyEvaluated: dmarc.PolicyEvaluated{
+				Disposition: dmarc.DispositionReject,
+				DKIM:        dmarc.DMARCResultFail,
+				SPF:         dmarc.DMARCResultFail,
+			},
+		},
+		Identifiers: dmarc.Identifiers{HeaderFrom: "example.com"},
+		AuthResults: dmarc.AuthResults{
+			DKIM: []dmarc.DKIMAuthResult{{Domain: "mail.example.com", Result: dmarc.DKIMResultPass}},
+			SPF:  []dmarc.SPFAuthResult{{Domain: "bounce.example.com", Result: dmarc.SPFResultPass, Scope: "mfrom"}},
+		},
+	}
+
+	relaxed := mailweave.NewDmarcReportRow(dmarc.PolicyPublished{Domain: "example.com", ADKIM: "r", ASPF: "r"}, record)
+	if !relaxed.DMARCDKIMAligned || !relaxed.DMARCSPFAligned {
+		t.Errorf("relaxed alignment = dkim %v spf %v, want both aligned", relaxed.DMARCDKIMAligned, relaxed.DMARCSPFAligned)
+	}
+
+	strict := mailweave.NewDmarcReportRow(dmarc.PolicyPublished{Domain: "example.com", ADKIM: "s", ASPF: "s"}, record)
+	if strict.DMARCDKIMAligned || strict.DMARCSPFAligned || strict.DMARCInferredAligned {
+		t.Errorf("strict alignment = dkim %v spf %v, want neither aligned", strict.DMARCDKIMAligned, strict.DMARCSPFAligned)
+	}
+	if strict.DMARCDisposition != "reject" {
+		t.Errorf("DMARCDisposition = %s, want reject", strict.DMARCDisposition)
+	}
+}
+
+func TestResolveDmarcReportHostnames(t *testing.T) {
+	fake := &resolver.FakeResolver{}
+	fake.SetPTR("192.0.2.1", "mail.example.com.")
+
+	report := mailweave.DmarcReport{
+		Rows: []mailweave.DmarcReportRow{
+			{SourceIP: "192.0.2.1"},
+			{SourceIP: "192.0.2.2"},
+			{SourceIP: "192.0.2.1"},
+		},
+	}
+	mailweave.ResolveDmarcReportHostnames(context.Background(), fake, &report)
+
+	want := []string{"mail.example.com", "", "mail.example.com"}
+	for i, row := range report.Rows {
+		if row.ResolvedHostname != want[i] {
+			t.Errorf("Rows[%d].ResolvedHostname = %q, want %q", i, row.ResolvedHostname, want[i])
+		}
+	}
+}
+
+func TestNewDmarcDnsRecord(t *testing.T) {
+	record, err := dmarc.ParseDNSRecord("v=DMARC1; p=reject; pct=50; rua=mailto:dmarc@example.com!10m; aspf=s")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dnsRecord := mailweave.NewDmarcDnsRecord(record)
+	if dnsRecord.Policy != "reject" {
+		t.Errorf("Policy = %s, want reject", dnsRecord.Policy)
+	}
+	if dnsRecord.SubdomainPolicy != "reject" {
+		t.Errorf("SubdomainPolicy = %s, want reject", dnsRecord.SubdomainPolicy)
+	}
+	if dnsRecord.Percentage != 50 {
+		t.Errorf("Percentage = %f, want 50", dnsRecord.Percentage)
+	}
+	if len(dnsRecord.AggregateReportingURI) != 1 || dnsRecord.AggregateReportingURI[0] != "mailto:dmarc@example.com!10m" {
+		t.Errorf("AggregateReportingURI = %v, want [mailto:dmarc@example.com!10m]", dnsRecord.AggregateReportingURI)
+	}
+	if dnsRecord.SPFAlignmentMode != "s" {
+		t.Errorf("SPFAlignmentMode = %s, want s", dnsRecord.SPFAlignmentMode)
+	}
+	if dnsRecord.DKIMAlignmentMode != "r" {
+		t.Errorf("DKIMAlignmentMode = %s, want r", dnsRecord.DKIMAlignmentMode)
+	}
+	if dnsRecord.ReportInterval != 24*time.Hour {
+		t.Errorf("ReportInterval = %s, want 24h", dnsRecord.ReportInterval)
+	}
+}
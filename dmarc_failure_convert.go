@@ -0,0 +1,43 @@
+package mailweave
+
+import (
+	"github.com/aldy505/mailweave/arf"
+)
+
+// NewDmarcFailureReport converts a parsed failure report into a DmarcFailureReport.
+//
+// The fields that do not come from the report itself (DomainOwner, ReportId,
+// ReceivedAt, EmailSender, EmailSubject and Content) are left for the caller
+// to fill in. The Message-ID of the email carrying the report is a good
+// candidate for ReportId, as failure reports do not have an identifier.
+func NewDmarcFailureReport(report *arf.FailureReport) DmarcFailureReport {
+	failureReport := DmarcFailureReport{
+		ReportingMTA:      report.ReportingMTA,
+		UserAgent:         report.UserAgent,
+		ArrivalDate:       report.ArrivalDate,
+		Incidents:         report.Incidents,
+		SourceIP:          report.SourceIP,
+		AuthFailure:       report.AuthFailure,
+		IdentityAlignment: report.IdentityAlignment,
+		DeliveryResult:    report.DeliveryResult,
+		DKIMDomain:        report.DKIMDomain,
+		DKIMSelector:      report.DKIMSelector,
+		DKIMIdentity:      report.DKIMIdentity,
+		SPFDNS:            report.SPFDNS,
+		OriginalMailFrom:  report.OriginalMailFrom,
+		OriginalRcptTo:    report.OriginalRcptTo,
+		OriginalHeaders:   report.RawOriginalHeaders,
+	}
+
+	if len(report.ReportedDomain) > 0 {
+		failureReport.ReportedDomain = report.ReportedDomain[0]
+	}
+
+	if report.OriginalHeaders != nil {
+		failureReport.OriginalHeaderFrom = report.OriginalHeaders.Get("From")
+		failureReport.OriginalSubject = report.OriginalHeaders.Get("Subject")
+		failureReport.OriginalMessageId = report.OriginalHeaders.Get("Message-Id")
+	}
+
+	return failureReport
+}
@@ -0,0 +1,48 @@
+package mailweave_test
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/aldy505/mailweave"
+	"github.com/aldy505/mailweave/arf"
+)
+
+func TestNewDmarcFailureReport(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path.Join(pwd, "testdata/arf/dkim-failure.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	parsed, err := arf.ParseFailureReport(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := mailweave.NewDmarcFailureReport(parsed)
+	if report.ReportedDomain != "example.com" {
+		t.Errorf("ReportedDomain = %s, want example.com", report.ReportedDomain)
+	}
+	if report.AuthFailure != "dkim" {
+		t.Errorf("AuthFailure = %s, want dkim", report.AuthFailure)
+	}
+	if report.DKIMSelector != "outgoing-smtp-1" {
+		t.Errorf("DKIMSelector = %s, want outgoing-smtp-1", report.DKIMSelector)
+	}
+	if report.OriginalHeaderFrom != "<sender@example.com>" {
+		t.Errorf("OriginalHeaderFrom = %s, want <sender@example.com>", report.OriginalHeaderFrom)
+	}
+	if report.OriginalMessageId != "<8787KJKJ3K4J3K4J3K4J3.mail@example.com>" {
+		t.Errorf("OriginalMessageId = %s, want <8787KJKJ3K4J3K4J3K4J3.mail@example.com>", report.OriginalMessageId)
+	}
+	if report.OriginalSubject != "Earn money" {
+		t.Errorf("OriginalSubject = %s, want Earn money", report.OriginalSubject)
+	}
+}
@@ -0,0 +1,49 @@
+package mailweave
+
+import (
+	"context"
+	"time"
+)
+
+type DmarcFailureReport struct {
+	// Report metadata
+	DomainOwner    string
+	ReportId       string
+	ReportingMTA   string
+	UserAgent      string
+	ReportedDomain string
+	ArrivalDate    time.Time
+	Incidents      int64
+
+	// About the report
+	ReceivedAt   time.Time
+	EmailSender  string
+	EmailSubject string
+
+	// About the failure
+	SourceIP          string
+	AuthFailure       string
+	IdentityAlignment []string
+	DeliveryResult    string
+	DKIMDomain        string
+	DKIMSelector      string
+	DKIMIdentity      string
+	SPFDNS            string
+
+	// About the original message
+	OriginalMailFrom   string
+	OriginalRcptTo     []string
+	OriginalHeaderFrom string
+	OriginalSubject    string
+	OriginalMessageId  string
+	OriginalHeaders    string
+
+	// About the content
+	Content string
+}
+
+type DmarcFailureMonitoringReports interface {
+	GetDmarcFailureReports(ctx context.Context, domain string) ([]DmarcFailureReport, error)
+	GetDmarcFailureReportById(ctx context.Context, domain string, reportId string) (DmarcFailureReport, error)
+	WriteDmarcFailureReport(ctx context.Context, domain string, report DmarcFailureReport) error
+}
@@ -6,13 +6,18 @@ import (
 )
 
 type DmarcDnsRecord struct {
-	Policy                string
-	SubdomainPolicy       string
-	Percentage            float64
-	AggregateReportingURI []string
-	FailureReportingURI   []string
-	SPFAlignmentMode      string
-	DKIMAlignmentMode     string
+	Policy                     string
+	SubdomainPolicy            string
+	NonExistentSubdomainPolicy string
+	Percentage                 float64
+	// The destinations with their size limit, e.g. "mailto:dmarc@example.com!10m".
+	AggregateReportingURI   []string
+	FailureReportingURI     []string
+	SPFAlignmentMode        string
+	DKIMAlignmentMode       string
+	FailureReportingOptions []string
+	ReportFormat            []string
+	ReportInterval          time.Duration
 }
 
 type DmarcReportRow struct {
@@ -47,12 +52,17 @@ type DmarcReport struct {
 	ReportId         string
 	RangeStart       time.Time
 	RangeEnd         time.Time
+	// The domain of the published policy the report is about.
+	PolicyDomain string
 
 	// About the report
 	ReceivedAt     time.Time
 	EmailSender    string
 	EmailSubject   string
 	ReportFileName string
+	// Differences between the report filename and the content of the report,
+	// which flag the report as suspicious. See ReportFileNameMismatches.
+	ReportFileNameMismatches []string
 
 	// About the content
 	TotalNumberOfEmails int64
@@ -83,6 +93,13 @@ type DmarcMonitoringReports interface {
 	WriteDmarcReport(ctx context.Context, domain string, report DmarcReport) error
 }
 
+// DmarcMonitoringReportRows appends rows to a report previously written with
+// DmarcMonitoringReports.WriteDmarcReport. It allows large reports to be
+// ingested in batches instead of holding every row in memory.
+type DmarcMonitoringReportRows interface {
+	WriteDmarcReportRows(ctx context.Context, domain string, reportId string, rows []DmarcReportRow) error
+}
+
 type DmarcMonitoringSources interface {
 	GetDmarcSources(ctx context.Context, domain string) ([]DmarcSources, error)
 	WriteDmarcSourcesAggregate(ctx context.Context, domain string, reports []DmarcReport) error
@@ -0,0 +1,34 @@
+package mailweave
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"github.com/aldy505/mailweave/spf"
+)
+
+// ExplainDmarcReportRowSpf evaluates the SPF record of a report row again, to
+// explain why its source passed or failed SPF. The domain is the SPFDomain of
+// the row, falling back to EnvelopeFrom and HeaderFrom. As the record is
+// looked up now, the result may differ from the one the reporter saw if the
+// record changed since.
+func ExplainDmarcReportRowSpf(ctx context.Context, resolver spf.Resolver, row DmarcReportRow) (spf.Evaluation, error) {
+	ip, err := netip.ParseAddr(row.SourceIP)
+	if err != nil {
+		return spf.Evaluation{}, fmt.Errorf("parsing source ip: %w", err)
+	}
+
+	domain := row.SPFDomain
+	if domain == "" {
+		domain = row.EnvelopeFrom
+	}
+	if domain == "" {
+		domain = row.HeaderFrom
+	}
+	if domain == "" {
+		return spf.Evaluation{}, fmt.Errorf("row has no domain to evaluate")
+	}
+
+	return spf.Evaluate(ctx, resolver, ip, domain, ""), nil
+}
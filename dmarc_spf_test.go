@@ -0,0 +1,54 @@
+package mailweave_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aldy505/mailweave"
+	"github.com/aldy505/mailweave/resolver"
+	"github.com/aldy505/mailweave/spf"
+)
+
+func TestExplainDmarcReportRowSpf(t *testing.T) {
+	fake := &resolver.FakeResolver{}
+	fake.SetTXT("example.com", "v=spf1 ip4:192.0.2.0/24 -all")
+
+	t.Run("failing source", func(t *testing.T) {
+		evaluation, err := mailweave.ExplainDmarcReportRowSpf(context.Background(), fake, mailweave.DmarcReportRow{
+			SourceIP:  "203.0.113.1",
+			SPFDomain: "example.com",
+			SPFResult: "fail",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if evaluation.Result != spf.ResultFail {
+			t.Errorf("Result = %s, want fail", evaluation.Result)
+		}
+		if evaluation.Match != "-all" {
+			t.Errorf("Match = %s, want -all", evaluation.Match)
+		}
+	})
+
+	t.Run("falls back to the envelope from", func(t *testing.T) {
+		evaluation, err := mailweave.ExplainDmarcReportRowSpf(context.Background(), fake, mailweave.DmarcReportRow{
+			SourceIP:     "192.0.2.1",
+			EnvelopeFrom: "example.com",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if evaluation.Result != spf.ResultPass {
+			t.Errorf("Result = %s, want pass", evaluation.Result)
+		}
+	})
+
+	t.Run("invalid source ip", func(t *testing.T) {
+		_, err := mailweave.ExplainDmarcReportRowSpf(context.Background(), fake, mailweave.DmarcReportRow{SourceIP: "invalid", SPFDomain: "example.com"})
+		if err == nil {
+			t.Error("error = nil, want an error")
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package mailweave
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNoDnsRecordSnapshot is returned by DnsRecordSnapshots when no snapshot
+// of the record has been taken yet.
+var ErrNoDnsRecordSnapshot = errors.New("no dns record snapshot")
+
+// DnsRecordType is the kind of DNS record being watched for a domain.
+type DnsRecordType string
+
+const (
+	// DnsRecordDmarc is the TXT record of _dmarc.<domain>.
+	DnsRecordDmarc DnsRecordType = "dmarc"
+	// DnsRecordTlsRpt is the TXT record of _smtp._tls.<domain>.
+	DnsRecordTlsRpt DnsRecordType = "tlsrpt"
+	// DnsRecordSpf is the SPF TXT record of <domain>.
+	DnsRecordSpf DnsRecordType = "spf"
+	// DnsRecordMx is the MX record of <domain>.
+	DnsRecordMx DnsRecordType = "mx"
+	// DnsRecordMtaSts is the TXT record of _mta-sts.<domain>.
+	DnsRecordMtaSts DnsRecordType = "mta-sts"
+)
+
+// DnsRecordTypes are the record types watched for every domain.
+var DnsRecordTypes = []DnsRecordType{DnsRecordDmarc, DnsRecordTlsRpt, DnsRecordSpf, DnsRecordMx, DnsRecordMtaSts}
+
+type DnsRecordSnapshot struct {
+	Domain     string
+	RecordType DnsRecordType
+	// The name that was queried, e.g. _dmarc.example.com.
+	Name string
+	// The values of the record, sorted. Empty when the record does not exist.
+	Values    []string
+	FetchedAt time.Time
+}
+
+// DnsRecordChange is raised when a record differs from its last snapshot.
+type DnsRecordChange struct {
+	Domain     string
+	RecordType DnsRecordType
+	Previous   DnsRecordSnapshot
+	Current    DnsRecordSnapshot
+}
+
+type DnsRecordSnapshots interface {
+	// GetLatestDnsRecordSnapshot returns the last snapshot of the record, or
+	// ErrNoDnsRecordSnapshot when there is none.
+	GetLatestDnsRecordSnapshot(ctx context.Context, domain string, recordType DnsRecordType) (DnsRecordSnapshot, error)
+	// GetDnsRecordSnapshots returns the snapshots of every record of the domain,
+	// sorted by the time they were fetched.
+	GetDnsRecordSnapshots(ctx context.Context, domain string) ([]DnsRecordSnapshot, error)
+	WriteDnsRecordSnapshot(ctx context.Context, domain string, snapshot DnsRecordSnapshot) error
+}
@@ -2,4 +2,7 @@ module github.com/aldy505/mailweave
 
 go 1.24.3
 
-require github.com/kelseyhightower/envconfig v1.4.0
+require (
+	github.com/kelseyhightower/envconfig v1.4.0
+	golang.org/x/net v0.50.0
+)
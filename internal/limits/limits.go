@@ -0,0 +1,139 @@
+// Package limits caps the resources spent on parsing a single report, shared
+// by the dmarc and tlsrpt parsers.
+package limits
+
+import (
+	"errors"
+	"io"
+)
+
+var (
+	// ErrCompressedSizeExceeded is returned when a compressed report is larger
+	// than Limits.MaxCompressedSize.
+	ErrCompressedSizeExceeded = errors.New("compressed size limit exceeded")
+	// ErrDecompressedSizeExceeded is returned when a report decompresses to
+	// more than Limits.MaxDecompressedSize, or an uncompressed report is larger
+	// than it.
+	ErrDecompressedSizeExceeded = errors.New("decompressed size limit exceeded")
+	// ErrCompressionRatioExceeded is returned when a report decompresses to
+	// more than Limits.MaxCompressionRatio times its compressed size.
+	ErrCompressionRatioExceeded = errors.New("compression ratio limit exceeded")
+	// ErrZipMembersExceeded is returned when a zip archive has more than
+	// Limits.MaxZipMembers members.
+	ErrZipMembersExceeded = errors.New("zip member limit exceeded")
+	// ErrRecordsExceeded is returned when a report has more than
+	// Limits.MaxRecords records.
+	ErrRecordsExceeded = errors.New("record limit exceeded")
+)
+
+// Limits caps the resources spent on parsing a single report, so a
+// decompression bomb sent to the rua address fails with an error instead of
+// exhausting the memory. A zero field uses the default of the parser, and a
+// negative field disables the limit.
+type Limits struct {
+	// The maximum size in bytes of a compressed report.
+	MaxCompressedSize int64
+	// The maximum number of bytes decompressed from a report, summed over the
+	// members of a zip archive and the gzip files nested in them.
+	MaxDecompressedSize int64
+	// The maximum ratio of the decompressed size to the compressed size. It is
+	// only enforced once more than a mebibyte has been decompressed, since
+	// small reports can have a high ratio.
+	MaxCompressionRatio int64
+	// The maximum number of members in a zip archive.
+	MaxZipMembers int
+	// The maximum number of records in a single report. For TLS-RPT reports,
+	// the policies and failure details are counted together.
+	MaxRecords int
+}
+
+// WithDefaults fills the zero fields of l with the ones of defaults.
+func (l Limits) WithDefaults(defaults Limits) Limits {
+	if l.MaxCompressedSize == 0 {
+		l.MaxCompressedSize = defaults.MaxCompressedSize
+	}
+	if l.MaxDecompressedSize == 0 {
+		l.MaxDecompressedSize = defaults.MaxDecompressedSize
+	}
+	if l.MaxCompressionRatio == 0 {
+		l.MaxCompressionRatio = defaults.MaxCompressionRatio
+	}
+	if l.MaxZipMembers == 0 {
+		l.MaxZipMembers = defaults.MaxZipMembers
+	}
+	if l.MaxRecords == 0 {
+		l.MaxRecords = defaults.MaxRecords
+	}
+
+	return l
+}
+
+// ratioThreshold is the decompressed size after which the compression ratio is enforced.
+const ratioThreshold = 1 << 20
+
+// Counter counts the bytes read through its readers, and fails them all with
+// err once more than max bytes have been read. Sharing a Counter between
+// readers, such as the members of a zip archive, enforces the limit on their
+// sum. Once failed, every read fails.
+type Counter struct {
+	n   int64
+	max int64
+	err error
+
+	compressed *Counter
+	ratio      int64
+	exceeded   error
+}
+
+// NewCounter returns a Counter failing with err after max bytes. A negative
+// max disables the limit.
+func NewCounter(max int64, err error) *Counter {
+	return &Counter{max: max, err: err}
+}
+
+// LimitRatio also fails the readers of c with ErrCompressionRatioExceeded
+// once the bytes counted by c exceed ratio times the bytes counted by
+// compressed. A negative ratio disables the limit.
+func (c *Counter) LimitRatio(compressed *Counter, ratio int64) {
+	c.compressed = compressed
+	c.ratio = ratio
+}
+
+// Reader returns a reader counting the bytes read from r with c.
+func (c *Counter) Reader(r io.Reader) io.Reader {
+	return &countingReader{r: r, counter: c}
+}
+
+type countingReader struct {
+	r       io.Reader
+	counter *Counter
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	l := c.counter
+	if l.exceeded != nil {
+		return 0, l.exceeded
+	}
+
+	if l.max >= 0 {
+		// Read at most one byte past the limit to detect it.
+		if remaining := l.max + 1 - l.n; int64(len(p)) > remaining {
+			p = p[:remaining]
+		}
+	}
+
+	n, err := c.r.Read(p)
+	l.n += int64(n)
+
+	if l.max >= 0 && l.n > l.max {
+		l.exceeded = l.err
+		return n - int(l.n-l.max), l.exceeded
+	}
+
+	if l.compressed != nil && l.ratio >= 0 && l.n > ratioThreshold && l.n > l.ratio*l.compressed.n {
+		l.exceeded = ErrCompressionRatioExceeded
+		return n, l.exceeded
+	}
+
+	return n, err
+}
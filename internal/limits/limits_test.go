@@ -0,0 +1,55 @@
+package limits_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aldy505/mailweave/internal/limits"
+)
+
+func TestCounter(t *testing.T) {
+	t.Run("shared between readers", func(t *testing.T) {
+		counter := limits.NewCounter(10, limits.ErrDecompressedSizeExceeded)
+
+		if _, err := io.ReadAll(counter.Reader(strings.NewReader("123456"))); err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := io.ReadAll(counter.Reader(strings.NewReader("123456")))
+		if !errors.Is(err, limits.ErrDecompressedSizeExceeded) {
+			t.Errorf("ReadAll() error = %v, want %v", err, limits.ErrDecompressedSizeExceeded)
+		}
+		if string(content) != "1234" {
+			t.Errorf("content = %q, want 1234", content)
+		}
+
+		if _, err := counter.Reader(strings.NewReader("1")).Read(make([]byte, 1)); !errors.Is(err, limits.ErrDecompressedSizeExceeded) {
+			t.Errorf("Read() after exceeding error = %v, want %v", err, limits.ErrDecompressedSizeExceeded)
+		}
+	})
+
+	t.Run("ratio", func(t *testing.T) {
+		compressed := limits.NewCounter(-1, limits.ErrCompressedSizeExceeded)
+		if _, err := io.ReadAll(compressed.Reader(strings.NewReader("1234"))); err != nil {
+			t.Fatal(err)
+		}
+
+		decompressed := limits.NewCounter(-1, limits.ErrDecompressedSizeExceeded)
+		decompressed.LimitRatio(compressed, 10)
+
+		_, err := io.ReadAll(decompressed.Reader(bytes.NewReader(make([]byte, 2<<20))))
+		if !errors.Is(err, limits.ErrCompressionRatioExceeded) {
+			t.Errorf("ReadAll() error = %v, want %v", err, limits.ErrCompressionRatioExceeded)
+		}
+	})
+
+	t.Run("with defaults", func(t *testing.T) {
+		got := limits.Limits{MaxRecords: -1}.WithDefaults(limits.Limits{MaxRecords: 10, MaxZipMembers: 2})
+		if got.MaxRecords != -1 || got.MaxZipMembers != 2 {
+			t.Errorf("WithDefaults() = %+v, want MaxRecords -1 and MaxZipMembers 2", got)
+		}
+	})
+}
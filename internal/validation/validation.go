@@ -0,0 +1,55 @@
+// Package validation provides the validation result shared by the report
+// and DNS record validators of the dmarc and tlsrpt packages.
+package validation
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FieldError describes a problem found on a single field. Field is the path
+// to the offending element, e.g. "record[3].row.source_ip".
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Result is the outcome of a validation. Errors are problems that make the
+// value unusable, while Warnings are suspicious but tolerable values.
+type Result struct {
+	Errors   []FieldError
+	Warnings []FieldError
+}
+
+// Valid reports whether no errors were found. Warnings are ignored.
+func (v Result) Valid() bool {
+	return len(v.Errors) == 0
+}
+
+// Err returns the errors joined together, or nil if the value is valid.
+func (v Result) Err() error {
+	if len(v.Errors) == 0 {
+		return nil
+	}
+
+	errs := make([]error, 0, len(v.Errors))
+	for _, e := range v.Errors {
+		errs = append(errs, e)
+	}
+
+	return errors.Join(errs...)
+}
+
+// AddError records an error on the field.
+func (v *Result) AddError(field string, format string, args ...any) {
+	v.Errors = append(v.Errors, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// AddWarning records a warning on the field.
+func (v *Result) AddWarning(field string, format string, args ...any) {
+	v.Warnings = append(v.Warnings, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,258 @@
+// Package mailer sends emails with attachments over SMTP, such as the
+// aggregate reports we generate for other domains.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"mime"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidHeader is returned by Message.Compose when a header field
+	// name or value could inject other header fields.
+	ErrInvalidHeader = errors.New("invalid header field")
+	// ErrStartTLSUnsupported is returned by SMTP.Send when STARTTLS is required
+	// but not advertised by the server.
+	ErrStartTLSUnsupported = errors.New("server does not support STARTTLS")
+)
+
+// SMTP holds the settings of the SMTP server used to send emails.
+type SMTP struct {
+	Hostname string
+	Port     string
+	Username string
+	Password string
+	// The address emails are sent from.
+	From string
+	// Upgrades the connection with STARTTLS. Sending fails when the server
+	// does not support it, rather than falling back to plaintext.
+	StartTLS bool
+	// Connects with implicit TLS, usually on port 465.
+	TLS                bool
+	InsecureSkipVerify bool
+}
+
+// Attachment is a file attached to a Message.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// Message is an email to be sent.
+type Message struct {
+	To      []string
+	Subject string
+	// Additional header fields, such as TLS-Report-Domain.
+	Headers     map[string]string
+	Body        string
+	Attachments []Attachment
+	// When set, the message is a multipart/report of this report-type
+	// (RFC 6522) instead of a multipart/mixed message.
+	ReportType string
+}
+
+// Compose renders the message as an RFC 5322 email sent from the given address.
+// It returns ErrInvalidHeader when the sender, a recipient or a header field
+// contains a line break.
+func (m Message) Compose(from string, date time.Time) ([]byte, error) {
+	if len(m.To) == 0 {
+		return nil, errors.New("message has no recipients")
+	}
+
+	if err := checkHeaderValue("From", from); err != nil {
+		return nil, err
+	}
+	for _, to := range m.To {
+		if err := checkHeaderValue("To", to); err != nil {
+			return nil, err
+		}
+	}
+	for key, value := range m.Headers {
+		if key == "" || strings.ContainsAny(key, ": \t\r\n") {
+			return nil, fmt.Errorf("%w: invalid name %q", ErrInvalidHeader, key)
+		}
+		if err := checkHeaderValue(key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	boundary, err := randomHex()
+	if err != nil {
+		return nil, fmt.Errorf("generating boundary: %w", err)
+	}
+
+	messageID, err := randomHex()
+	if err != nil {
+		return nil, fmt.Errorf("generating message id: %w", err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", messageID, domainOf(from))
+	for _, key := range slices.Sorted(maps.Keys(m.Headers)) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", textproto.CanonicalMIMEHeaderKey(key), m.Headers[key])
+	}
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	if m.ReportType != "" {
+		fmt.Fprintf(&buf, "Content-Type: %s\r\n", mime.FormatMediaType("multipart/report", map[string]string{"report-type": m.ReportType, "boundary": boundary}))
+	} else {
+		fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%q\r\n", boundary)
+	}
+	buf.WriteString("\r\n")
+
+	fmt.Fprintf(&buf, "--%s\r\n", boundary)
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(m.Body, "\r\n", "\n"), "\n", "\r\n"))
+	buf.WriteString("\r\n")
+
+	for _, attachment := range m.Attachments {
+		contentType := attachment.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		fmt.Fprintf(&buf, "--%s\r\n", boundary)
+		fmt.Fprintf(&buf, "Content-Type: %s\r\n", mime.FormatMediaType(contentType, map[string]string{"name": attachment.Filename}))
+		buf.WriteString("Content-Transfer-Encoding: base64\r\n")
+		fmt.Fprintf(&buf, "Content-Disposition: %s\r\n", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+		buf.WriteString("\r\n")
+
+		encoded := base64.StdEncoding.EncodeToString(attachment.Content)
+		for len(encoded) > 76 {
+			buf.WriteString(encoded[:76])
+			buf.WriteString("\r\n")
+			encoded = encoded[76:]
+		}
+		buf.WriteString(encoded)
+		buf.WriteString("\r\n")
+	}
+
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+
+	return buf.Bytes(), nil
+}
+
+// Send delivers the message through the SMTP server.
+func (s *SMTP) Send(ctx context.Context, message Message) error {
+	content, err := message.Compose(s.From, time.Now())
+	if err != nil {
+		return err
+	}
+
+	address := net.JoinHostPort(s.Hostname, s.Port)
+	tlsConfig := &tls.Config{
+		ServerName:         s.Hostname,
+		InsecureSkipVerify: s.InsecureSkipVerify,
+	}
+
+	var conn net.Conn
+	if s.TLS {
+		dialer := &tls.Dialer{Config: tlsConfig}
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", address, err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.Hostname)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("creating smtp client: %w", err)
+	}
+	defer client.Close()
+
+	if !s.TLS && s.StartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%w: %s", ErrStartTLSUnsupported, address)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("starting tls: %w", err)
+		}
+	}
+
+	if s.Username != "" {
+		if ok, _ := client.Extension("AUTH"); ok {
+			if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Hostname)); err != nil {
+				return fmt.Errorf("authenticating: %w", err)
+			}
+		}
+	}
+
+	if err := client.Mail(s.From); err != nil {
+		return fmt.Errorf("sending MAIL FROM: %w", err)
+	}
+
+	for _, to := range message.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("sending RCPT TO %s: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("sending DATA: %w", err)
+	}
+
+	if _, err := w.Write(content); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+func randomHex() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b[:]), nil
+}
+
+func checkHeaderValue(name string, value string) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("%w: %s contains a line break", ErrInvalidHeader, name)
+	}
+
+	return nil
+}
+
+func domainOf(address string) string {
+	address = strings.TrimSuffix(address, ">")
+	if _, domain, ok := strings.Cut(address, "@"); ok {
+		return domain
+	}
+
+	return "localhost"
+}
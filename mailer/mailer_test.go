@@ -0,0 +1,181 @@
+package mailer_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aldy505/mailweave/mailer"
+)
+
+func TestMessageCompose(t *testing.T) {
+	message := mailer.Message{
+		To:      []string{"dmarc@example.com"},
+		Subject: "Report Domain: example.com Submitter: example.net Report-ID: <1>",
+		Body:    "This is an aggregate report.",
+		Attachments: []mailer.Attachment{
+			{Filename: "example.net!example.com!1747180800!1747267199.xml.gz", ContentType: "application/gzip", Content: bytes.Repeat([]byte{0x1f, 0x8b, 0x00}, 100)},
+		},
+	}
+
+	content, err := message.Compose("mailweave@example.net", time.Date(2025, 5, 15, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := parsed.Header.Get("To"); got != "dmarc@example.com" {
+		t.Errorf("To = %s, want dmarc@example.com", got)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %s, want multipart/mixed", mediaType)
+	}
+
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	if _, err := reader.NextPart(); err != nil {
+		t.Fatal(err)
+	}
+
+	part, err := reader.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if part.FileName() != message.Attachments[0].Filename {
+		t.Errorf("FileName() = %s, want %s", part.FileName(), message.Attachments[0].Filename)
+	}
+
+	attachment, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, part))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(attachment, message.Attachments[0].Content) {
+		t.Error("attachment content does not round trip")
+	}
+
+	t.Run("report", func(t *testing.T) {
+		report := message
+		report.ReportType = "tlsrpt"
+		report.Headers = map[string]string{"tls-report-domain": "example.com"}
+
+		content, err := report.Compose("mailweave@example.net", time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parsed, err := mail.ReadMessage(bytes.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := parsed.Header.Get("TLS-Report-Domain"); got != "example.com" {
+			t.Errorf("TLS-Report-Domain = %s, want example.com", got)
+		}
+
+		mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mediaType != "multipart/report" || params["report-type"] != "tlsrpt" {
+			t.Errorf("Content-Type = %s; report-type=%s, want multipart/report; report-type=tlsrpt", mediaType, params["report-type"])
+		}
+	})
+
+	if _, err := (mailer.Message{}).Compose("mailweave@example.net", time.Now()); err == nil {
+		t.Error("expected an error for a message without recipients")
+	}
+
+	t.Run("message id", func(t *testing.T) {
+		_, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		messageID := parsed.Header.Get("Message-ID")
+		if !strings.HasSuffix(messageID, "@example.net>") {
+			t.Errorf("Message-ID = %s, want it on example.net", messageID)
+		}
+		if strings.Contains(messageID, params["boundary"]) {
+			t.Errorf("Message-ID = %s, want it distinct from the boundary", messageID)
+		}
+	})
+
+	t.Run("header injection", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			message mailer.Message
+		}{
+			{name: "to", message: mailer.Message{To: []string{"dmarc@example.com\r\nBcc: victim@example.org"}}},
+			{name: "header value", message: mailer.Message{To: []string{"dmarc@example.com"}, Headers: map[string]string{"TLS-Report-Domain": "example.com\nBcc: victim@example.org"}}},
+			{name: "header name", message: mailer.Message{To: []string{"dmarc@example.com"}, Headers: map[string]string{"Bcc: victim@example.org\r\nX": "1"}}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if _, err := tt.message.Compose("mailweave@example.net", time.Now()); !errors.Is(err, mailer.ErrInvalidHeader) {
+					t.Errorf("Compose() error = %v, want %v", err, mailer.ErrInvalidHeader)
+				}
+			})
+		}
+	})
+}
+
+func TestSMTPSendStartTLSUnsupported(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// The server does not advertise any extension.
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		_, _ = io.WriteString(conn, "220 localhost ESMTP\r\n")
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			command := strings.ToUpper(scanner.Text())
+			switch {
+			case strings.HasPrefix(command, "EHLO"):
+				_, _ = io.WriteString(conn, "250 localhost\r\n")
+			case strings.HasPrefix(command, "QUIT"):
+				_, _ = io.WriteString(conn, "221 bye\r\n")
+				return
+			default:
+				_, _ = io.WriteString(conn, "250 ok\r\n")
+			}
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &mailer.SMTP{Hostname: host, Port: port, From: "mailweave@example.net", StartTLS: true}
+	err = client.Send(context.Background(), mailer.Message{To: []string{"dmarc@example.com"}, Body: "report"})
+	if !errors.Is(err, mailer.ErrStartTLSUnsupported) {
+		t.Errorf("Send() error = %v, want %v", err, mailer.ErrStartTLSUnsupported)
+	}
+}
@@ -0,0 +1,18 @@
+package mailweave
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrDomainNotManaged is returned by ManagedDomains when the domain is not
+// managed by any owner.
+var ErrDomainNotManaged = errors.New("domain is not managed")
+
+type ManagedDomains interface {
+	// GetDomainOwner returns the owner of the domain, or ErrDomainNotManaged
+	// when the domain is not managed.
+	GetDomainOwner(ctx context.Context, domain string) (string, error)
+	// GetManagedDomains returns every managed domain, sorted.
+	GetManagedDomains(ctx context.Context) ([]string, error)
+}
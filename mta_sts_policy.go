@@ -0,0 +1,65 @@
+package mailweave
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+// ErrNoMtaStsPolicy is returned by MtaStsPolicies when the domain has no policy.
+var ErrNoMtaStsPolicy = errors.New("no mta-sts policy")
+
+// MtaStsPolicy is the MTA-STS policy Mailweave serves for a managed domain.
+type MtaStsPolicy struct {
+	DomainOwner string
+	Domain      string
+	Mode        tlsrpt.STSMode
+	MX          []string
+	MaxAge      time.Duration
+	// The id to publish in the _mta-sts TXT record, bumped on every change.
+	Id        string
+	UpdatedAt time.Time
+}
+
+// STSPolicy returns the policy as served in the policy file.
+func (p MtaStsPolicy) STSPolicy() tlsrpt.STSPolicy {
+	return tlsrpt.STSPolicy{
+		Version: "STSv1",
+		Mode:    p.Mode,
+		MX:      p.MX,
+		MaxAge:  p.MaxAge,
+	}
+}
+
+// TXTRecord returns the value of the _mta-sts TXT record to publish.
+func (p MtaStsPolicy) TXTRecord() string {
+	return "v=STSv1; id=" + p.Id
+}
+
+// NextMtaStsPolicyId returns the id of a policy changed at the given time,
+// formatted as a UTC timestamp such as 20250101120000. It is always greater
+// than the previous id, even when the policy changes twice within a second.
+func NextMtaStsPolicyId(previous string, now time.Time) string {
+	id := now.UTC().Format("20060102150405")
+	if len(previous) != len(id) || previous < id {
+		return id
+	}
+
+	// previous is a timestamp id at or after now
+	number, err := strconv.ParseUint(previous, 10, 64)
+	if err != nil {
+		return id
+	}
+
+	return strconv.FormatUint(number+1, 10)
+}
+
+type MtaStsPolicies interface {
+	// GetMtaStsPolicy returns the policy of the domain, or ErrNoMtaStsPolicy
+	// when there is none.
+	GetMtaStsPolicy(ctx context.Context, domain string) (MtaStsPolicy, error)
+	WriteMtaStsPolicy(ctx context.Context, domain string, policy MtaStsPolicy) error
+}
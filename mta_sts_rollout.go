@@ -0,0 +1,115 @@
+package mailweave
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+// MtaStsRollout moves the MTA-STS policy of a domain from testing to enforce
+// once the TLS-RPT reports show that senders can deliver over TLS to the MX
+// hosts of the policy. In testing mode, senders deliver anyway and report the
+// failures, so the reports tell whether enforcing the policy would lose mail.
+type MtaStsRollout struct {
+	Policies MtaStsPolicies
+	Reports  TlsRptMonitoringReports
+	// The minimum number of sessions reported under the current policy.
+	MinSessions int64
+	// The minimum ratio of successful sessions, between 0 and 1.
+	MinSuccessRatio float64
+	// Only the reports whose range ends within this duration count.
+	Window time.Duration
+	// Returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// MtaStsRolloutStatus is the outcome of MtaStsRollout.Advance.
+type MtaStsRolloutStatus struct {
+	Domain             string
+	Mode               tlsrpt.STSMode
+	SuccessfulSessions int64
+	FailedSessions     int64
+	// Whether the policy was moved to enforce.
+	Promoted bool
+	// Why the policy was or was not moved to enforce.
+	Reason string
+}
+
+// Advance checks the TLS-RPT reports of a domain whose policy is in testing
+// mode, and moves it to enforce when they show at least MinSessions sessions
+// with a success ratio of at least MinSuccessRatio. Only the sessions whose
+// policy-string has the same content as the current policy count. The policy
+// id is not part of the policy-string, so sessions reported under an older id
+// with the same mode, mx and max_age count as well.
+func (r *MtaStsRollout) Advance(ctx context.Context, domain string) (MtaStsRolloutStatus, error) {
+	now := time.Now
+	if r.Now != nil {
+		now = r.Now
+	}
+
+	policy, err := r.Policies.GetMtaStsPolicy(ctx, domain)
+	if err != nil {
+		return MtaStsRolloutStatus{}, fmt.Errorf("getting the mta-sts policy of %s: %w", domain, err)
+	}
+
+	status := MtaStsRolloutStatus{Domain: policy.Domain, Mode: policy.Mode}
+	if policy.Mode != tlsrpt.STSModeTesting {
+		status.Reason = fmt.Sprintf("policy is in %s mode, not testing", policy.Mode)
+		return status, nil
+	}
+
+	reports, err := r.Reports.GetTlsRptReports(ctx, policy.DomainOwner)
+	if err != nil {
+		return MtaStsRolloutStatus{}, fmt.Errorf("getting the tls-rpt reports of %s: %w", policy.DomainOwner, err)
+	}
+
+	since := now().Add(-r.Window)
+	published := policy.STSPolicy()
+	for _, report := range reports {
+		if r.Window > 0 && report.RangeEnd.Before(since) {
+			continue
+		}
+
+		for _, row := range report.Rows {
+			if row.PolicyType != "sts" || !strings.EqualFold(strings.TrimSuffix(row.DomainName, "."), policy.Domain) {
+				continue
+			}
+
+			applied, err := tlsrpt.ParseSTSPolicy(row.PolicyString)
+			if err != nil || !applied.Equal(published) {
+				continue
+			}
+
+			status.SuccessfulSessions += row.SuccessfulSessionCount
+			status.FailedSessions += row.FailedSessionCount
+		}
+	}
+
+	total := status.SuccessfulSessions + status.FailedSessions
+	if total < r.MinSessions || total == 0 {
+		status.Reason = fmt.Sprintf("%d sessions reported under the current policy, want at least %d", total, r.MinSessions)
+		return status, nil
+	}
+
+	ratio := float64(status.SuccessfulSessions) / float64(total)
+	if ratio < r.MinSuccessRatio {
+		status.Reason = fmt.Sprintf("%.2f%% of the sessions succeeded, want at least %.2f%%", ratio*100, r.MinSuccessRatio*100)
+		return status, nil
+	}
+
+	policy.Mode = tlsrpt.STSModeEnforce
+	policy.UpdatedAt = now()
+	policy.Id = NextMtaStsPolicyId(policy.Id, policy.UpdatedAt)
+	if err := r.Policies.WriteMtaStsPolicy(ctx, policy.Domain, policy); err != nil {
+		return MtaStsRolloutStatus{}, fmt.Errorf("writing the mta-sts policy of %s: %w", domain, err)
+	}
+
+	status.Mode = policy.Mode
+	status.Promoted = true
+	status.Reason = fmt.Sprintf("%.2f%% of %d sessions succeeded", ratio*100, total)
+
+	return status, nil
+}
@@ -0,0 +1,144 @@
+package mailweave_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aldy505/mailweave"
+	"github.com/aldy505/mailweave/datastore"
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+func TestNextMtaStsPolicyId(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		previous string
+		want     string
+	}{
+		{name: "first", previous: "", want: "20250601120000"},
+		{name: "older", previous: "20250101000000", want: "20250601120000"},
+		{name: "same second", previous: "20250601120000", want: "20250601120001"},
+		{name: "ahead", previous: "20250601120005", want: "20250601120006"},
+		{name: "not a timestamp", previous: "abc", want: "20250601120000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mailweave.NextMtaStsPolicyId(tt.previous, now); got != tt.want {
+				t.Errorf("NextMtaStsPolicyId(%q) = %s, want %s", tt.previous, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMtaStsRollout(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	policy := mailweave.MtaStsPolicy{
+		DomainOwner: "example.com",
+		Domain:      "example.com",
+		Mode:        tlsrpt.STSModeTesting,
+		MX:          []string{"mx1.example.com"},
+		MaxAge:      7 * 24 * time.Hour,
+		Id:          "20250501000000",
+	}
+
+	report := func(policyString []string, successful, failed int64, end time.Time) mailweave.TlsRptReport {
+		return mailweave.TlsRptReport{
+			DomainOwner: "example.com",
+			RangeEnd:    end,
+			Rows: []mailweave.TlsRptReportRow{{
+				DomainName:             "example.com",
+				PolicyType:             "sts",
+				PolicyString:           policyString,
+				SuccessfulSessionCount: successful,
+				FailedSessionCount:     failed,
+			}},
+		}
+	}
+
+	newRollout := func(reports ...mailweave.TlsRptReport) (*mailweave.MtaStsRollout, *datastore.FakeDatastore) {
+		store := &datastore.FakeDatastore{
+			MtaStsPolicies: []mailweave.MtaStsPolicy{policy},
+			TlsRptReports:  reports,
+		}
+		return &mailweave.MtaStsRollout{
+			Policies:        store,
+			Reports:         store,
+			MinSessions:     100,
+			MinSuccessRatio: 0.99,
+			Window:          7 * 24 * time.Hour,
+			Now:             func() time.Time { return now },
+		}, store
+	}
+
+	published := policy.STSPolicy().Lines()
+
+	t.Run("promoted", func(t *testing.T) {
+		rollout, store := newRollout(report(published, 995, 5, now.Add(-24*time.Hour)))
+
+		status, err := rollout.Advance(context.Background(), "example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !status.Promoted {
+			t.Fatalf("Promoted = false, want true: %s", status.Reason)
+		}
+		if status.SuccessfulSessions != 995 || status.FailedSessions != 5 {
+			t.Errorf("sessions = %d/%d, want 995/5", status.SuccessfulSessions, status.FailedSessions)
+		}
+
+		updated := store.MtaStsPolicies[0]
+		if updated.Mode != tlsrpt.STSModeEnforce {
+			t.Errorf("Mode = %s, want %s", updated.Mode, tlsrpt.STSModeEnforce)
+		}
+		if updated.Id != "20250601120000" {
+			t.Errorf("Id = %s, want 20250601120000", updated.Id)
+		}
+	})
+
+	t.Run("too many failures", func(t *testing.T) {
+		rollout, store := newRollout(report(published, 900, 100, now.Add(-24*time.Hour)))
+
+		status, err := rollout.Advance(context.Background(), "example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Promoted {
+			t.Error("Promoted = true, want false")
+		}
+		if store.MtaStsPolicies[0].Mode != tlsrpt.STSModeTesting {
+			t.Errorf("Mode = %s, want %s", store.MtaStsPolicies[0].Mode, tlsrpt.STSModeTesting)
+		}
+	})
+
+	t.Run("stale policy and old reports", func(t *testing.T) {
+		stale := []string{"version: STSv1", "mode: testing", "mx: mx0.example.com", "max_age: 604800"}
+		rollout, _ := newRollout(
+			report(stale, 1000, 0, now.Add(-24*time.Hour)),
+			report(published, 1000, 0, now.Add(-30*24*time.Hour)),
+		)
+
+		status, err := rollout.Advance(context.Background(), "example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Promoted {
+			t.Error("Promoted = true, want false")
+		}
+		if status.SuccessfulSessions != 0 {
+			t.Errorf("SuccessfulSessions = %d, want 0", status.SuccessfulSessions)
+		}
+	})
+
+	t.Run("no policy", func(t *testing.T) {
+		rollout, _ := newRollout()
+
+		_, err := rollout.Advance(context.Background(), "example.org")
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
@@ -0,0 +1,352 @@
+// Package mtasts fetches and checks MTA-STS policies, as defined in RFC 8461,
+// to reproduce the sts-policy-fetch-error and sts-policy-invalid failures of
+// TLS-RPT reports.
+package mtasts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aldy505/mailweave/resolver"
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+var (
+	// ErrInvalidTXTRecord is returned when the _mta-sts TXT record cannot be parsed.
+	ErrInvalidTXTRecord = errors.New("invalid mta-sts record")
+	// ErrNoTXTRecord is returned when the domain has no _mta-sts TXT record.
+	ErrNoTXTRecord = errors.New("no mta-sts record")
+	// ErrPolicyFetch is returned when the policy cannot be fetched.
+	ErrPolicyFetch = errors.New("fetching mta-sts policy")
+)
+
+// MaxPolicySize is the maximum size of a policy file. RFC 8461 Section 3.3
+// lets senders limit it, and 64 KiB is the usual limit.
+const MaxPolicySize = 64 << 10
+
+// PolicyURL returns the URL the policy of the domain is served at.
+func PolicyURL(domain string) string {
+	return "https://mta-sts." + resolver.NormalizeDomain(domain) + "/.well-known/mta-sts.txt"
+}
+
+// TXTRecord is a parsed _mta-sts TXT record, as defined in RFC 8461 Section 3.1.
+type TXTRecord struct {
+	// The version of the record, always "STSv1".
+	Version string
+	// Identifies the current policy. Senders only fetch the policy again when
+	// it changes.
+	ID string
+}
+
+// ParseTXTRecord parses the value of an _mta-sts TXT record, e.g.
+// "v=STSv1; id=20250101000000". Unknown fields are ignored.
+func ParseTXTRecord(value string) (TXTRecord, error) {
+	var record TXTRecord
+	for i, part := range strings.Split(value, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, fieldValue, ok := strings.Cut(part, "=")
+		if !ok {
+			return TXTRecord{}, fmt.Errorf("%w: %q is not a field=value pair", ErrInvalidTXTRecord, part)
+		}
+
+		switch strings.TrimSpace(name) {
+		case "v":
+			if i != 0 || fieldValue != "STSv1" {
+				return TXTRecord{}, fmt.Errorf("%w: must start with v=STSv1", ErrInvalidTXTRecord)
+			}
+			record.Version = fieldValue
+		case "id":
+			if record.ID == "" {
+				record.ID = strings.TrimSpace(fieldValue)
+			}
+		}
+	}
+
+	if record.Version == "" {
+		return TXTRecord{}, fmt.Errorf("%w: must start with v=STSv1", ErrInvalidTXTRecord)
+	}
+	if !validID(record.ID) {
+		return TXTRecord{}, fmt.Errorf("%w: id %q must be 1 to 32 alphanumeric characters", ErrInvalidTXTRecord, record.ID)
+	}
+
+	return record, nil
+}
+
+func validID(id string) bool {
+	if id == "" || len(id) > 32 {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// DefaultFetchTimeout is the timeout of the policy fetches when no client is
+// configured, following the suggestion of RFC 8461 Section 3.3 that senders
+// wait about a minute for the policy host.
+const DefaultFetchTimeout = time.Minute
+
+var defaultClient = &http.Client{Timeout: DefaultFetchTimeout}
+
+// Checker fetches the MTA-STS records and policies of domains.
+type Checker struct {
+	// Fetches the policies. Defaults to a client with DefaultFetchTimeout.
+	// Redirects are never followed, as required by RFC 8461 Section 3.3.
+	Client   *http.Client
+	Resolver resolver.Resolver
+}
+
+// LookupRecord looks up the _mta-sts TXT record of the domain. It returns
+// ErrNoTXTRecord when there is none, and ErrInvalidTXTRecord when there is
+// more than one, as senders then ignore them.
+func (c *Checker) LookupRecord(ctx context.Context, domain string) (TXTRecord, error) {
+	name := "_mta-sts." + resolver.NormalizeDomain(domain)
+	values, err := c.Resolver.LookupTXT(ctx, name)
+	if err != nil && !resolver.IsNotFound(err) {
+		return TXTRecord{}, fmt.Errorf("looking up TXT of %s: %w", name, err)
+	}
+
+	var records []string
+	for _, value := range values {
+		if strings.HasPrefix(value, "v=STSv1") {
+			records = append(records, value)
+		}
+	}
+
+	switch len(records) {
+	case 0:
+		return TXTRecord{}, ErrNoTXTRecord
+	case 1:
+		return ParseTXTRecord(records[0])
+	default:
+		return TXTRecord{}, fmt.Errorf("%w: %s has %d records", ErrInvalidTXTRecord, name, len(records))
+	}
+}
+
+// FetchPolicy fetches and parses the policy of the domain, following the
+// rules of RFC 8461 Section 3.3: the response must be a 200 with a text/plain
+// content type, and redirects are not followed. It returns the parsed policy
+// along with the raw policy file. Fetch failures wrap ErrPolicyFetch, while
+// parse failures wrap tlsrpt.ErrInvalidPolicyString, matching the
+// sts-policy-fetch-error and sts-policy-invalid result types.
+func (c *Checker) FetchPolicy(ctx context.Context, domain string) (tlsrpt.STSPolicy, string, error) {
+	client := c.Client
+	if client == nil {
+		client = defaultClient
+	}
+	noRedirect := *client
+	noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, PolicyURL(domain), nil)
+	if err != nil {
+		return tlsrpt.STSPolicy{}, "", fmt.Errorf("%w: %w", ErrPolicyFetch, err)
+	}
+
+	resp, err := noRedirect.Do(req)
+	if err != nil {
+		return tlsrpt.STSPolicy{}, "", fmt.Errorf("%w: %w", ErrPolicyFetch, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return tlsrpt.STSPolicy{}, "", fmt.Errorf("%w: %s returned %s", ErrPolicyFetch, req.URL, resp.Status)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != "text/plain" {
+		return tlsrpt.STSPolicy{}, "", fmt.Errorf("%w: %s returned content type %q, want text/plain", ErrPolicyFetch, req.URL, resp.Header.Get("Content-Type"))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxPolicySize+1))
+	if err != nil {
+		return tlsrpt.STSPolicy{}, "", fmt.Errorf("%w: reading %s: %w", ErrPolicyFetch, req.URL, err)
+	}
+	if len(body) > MaxPolicySize {
+		return tlsrpt.STSPolicy{}, "", fmt.Errorf("%w: %s is larger than %d bytes", ErrPolicyFetch, req.URL, MaxPolicySize)
+	}
+
+	raw := string(body)
+	policy, err := tlsrpt.ParseSTSPolicy([]string{raw})
+	if err != nil {
+		return tlsrpt.STSPolicy{}, raw, err
+	}
+
+	return policy, raw, nil
+}
+
+// Snapshot is the record id and policy of a domain at a point in time, used
+// to tell whether the policy changed without the id being updated.
+type Snapshot struct {
+	ID        string
+	Policy    tlsrpt.STSPolicy
+	FetchedAt time.Time
+}
+
+// Result is the outcome of Check.
+type Result struct {
+	Record TXTRecord
+	Policy tlsrpt.STSPolicy
+	// The raw policy file, empty when it could not be fetched.
+	RawPolicy string
+	// Errors are problems that make senders fail to apply the policy, while
+	// Warnings are likely mistakes.
+	Errors   []error
+	Warnings []string
+}
+
+// Valid reports whether no errors were found. Warnings are ignored.
+func (r Result) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// Snapshot returns the snapshot of the record id and policy, to be passed to
+// the next Check.
+func (r Result) Snapshot(fetchedAt time.Time) Snapshot {
+	return Snapshot{ID: r.Record.ID, Policy: r.Policy, FetchedAt: fetchedAt}
+}
+
+// Check looks up the record and fetches the policy of the domain, and checks
+// them for consistency:
+//   - the policy changed since the previous snapshot while the record id did
+//     not, so senders keep using their cached policy
+//   - an MX host of the domain matches no mx pattern of the policy, so senders
+//     fail to deliver to it in enforce mode
+//   - the max_age is shorter than a day, so senders keep fetching the policy
+//
+// The previous snapshot is optional.
+func (c *Checker) Check(ctx context.Context, domain string, previous *Snapshot) Result {
+	var result Result
+
+	record, err := c.LookupRecord(ctx, domain)
+	if err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+	result.Record = record
+
+	policy, raw, err := c.FetchPolicy(ctx, domain)
+	result.RawPolicy = raw
+	if err != nil {
+		result.Errors = append(result.Errors, err)
+		return result
+	}
+	result.Policy = policy
+
+	if previous != nil && previous.ID != "" && previous.ID == record.ID && !previous.Policy.Equal(policy) {
+		result.Errors = append(result.Errors, fmt.Errorf("the policy changed but the id of the _mta-sts record is still %s, senders keep using their cached policy", record.ID))
+	}
+
+	if policy.MaxAge < 24*time.Hour {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("max_age of %s is shorter than a day, RFC 8461 recommends weeks", policy.MaxAge))
+	}
+
+	if policy.Mode == tlsrpt.STSModeNone {
+		return result
+	}
+
+	hosts, err := c.Resolver.LookupMX(ctx, resolver.NormalizeDomain(domain))
+	if err != nil && !resolver.IsNotFound(err) {
+		result.Errors = append(result.Errors, fmt.Errorf("looking up MX of %s: %w", domain, err))
+		return result
+	}
+
+	for _, host := range hosts {
+		name := resolver.NormalizeDomain(host.Host)
+		if MatchesMX(policy, name) {
+			continue
+		}
+
+		message := fmt.Sprintf("MX %s matches no mx pattern of the policy", name)
+		if policy.Mode == tlsrpt.STSModeEnforce {
+			result.Errors = append(result.Errors, errors.New(message+", senders refuse to deliver to it"))
+		} else {
+			result.Warnings = append(result.Warnings, message+", senders report failures for it")
+		}
+	}
+
+	return result
+}
+
+// MatchesMX reports whether the MX host matches one of the mx patterns of the
+// policy. As per RFC 8461 Section 4.1, a "*." wildcard only matches a single
+// label.
+func MatchesMX(policy tlsrpt.STSPolicy, host string) bool {
+	host = resolver.NormalizeDomain(host)
+	for _, pattern := range policy.MX {
+		pattern = resolver.NormalizeDomain(pattern)
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			label, rest, found := strings.Cut(host, ".")
+			if found && label != "" && rest == suffix {
+				return true
+			}
+			continue
+		}
+
+		if host == pattern {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Diff compares the published policy with the policy a reporter applied, as
+// found in the policy-string of a TLS-RPT report. It returns the differences,
+// which are empty when both policies are equal. A difference means the
+// reporter used a cached policy, or fetched it while it was being changed.
+func Diff(published tlsrpt.STSPolicy, reported tlsrpt.Policy) ([]string, error) {
+	applied, err := reported.STSPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	var diff []string
+	if published.Mode != applied.Mode {
+		diff = append(diff, fmt.Sprintf("mode: published %s, reported %s", published.Mode, applied.Mode))
+	}
+	if published.MaxAge != applied.MaxAge {
+		diff = append(diff, fmt.Sprintf("max_age: published %d, reported %d", int64(published.MaxAge/time.Second), int64(applied.MaxAge/time.Second)))
+	}
+
+	publishedMX := normalizePatterns(published.MX)
+	appliedMX := normalizePatterns(applied.MX)
+	for _, pattern := range publishedMX {
+		if !slices.Contains(appliedMX, pattern) {
+			diff = append(diff, fmt.Sprintf("mx: %s is published but not reported", pattern))
+		}
+	}
+	for _, pattern := range appliedMX {
+		if !slices.Contains(publishedMX, pattern) {
+			diff = append(diff, fmt.Sprintf("mx: %s is reported but no longer published", pattern))
+		}
+	}
+
+	return diff, nil
+}
+
+func normalizePatterns(patterns []string) []string {
+	normalized := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		normalized = append(normalized, resolver.NormalizeDomain(pattern))
+	}
+
+	return normalized
+}
@@ -0,0 +1,241 @@
+package mtasts_test
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aldy505/mailweave/mtasts"
+	"github.com/aldy505/mailweave/resolver"
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+const policyFile = "version: STSv1\r\nmode: enforce\r\nmx: mx1.example.com\r\nmx: *.mail.example.com\r\nmax_age: 604800\r\n"
+
+// newChecker returns a checker whose client sends every request to the test
+// server, whatever the host.
+func newChecker(t *testing.T, handler http.HandlerFunc) (*mtasts.Checker, *resolver.FakeResolver) {
+	t.Helper()
+
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+
+	client := server.Client()
+	transport := client.Transport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: transport.TLSClientConfig.RootCAs, ServerName: "example.com"}
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, network, server.Listener.Addr().String())
+	}
+	client.Transport = transport
+
+	fake := &resolver.FakeResolver{}
+	fake.SetTXT("_mta-sts.example.com", "v=STSv1; id=20250101")
+	fake.SetMX("example.com", &net.MX{Host: "mx1.example.com.", Pref: 10}, &net.MX{Host: "eu.mail.example.com.", Pref: 20})
+
+	return &mtasts.Checker{Client: client, Resolver: fake}, fake
+}
+
+func servePolicy(policy string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/mta-sts.txt" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(policy))
+	}
+}
+
+func TestParseTXTRecord(t *testing.T) {
+	_, err := mtasts.ParseTXTRecord("v=STSv1; id=2025-01-01")
+	if !errors.Is(err, mtasts.ErrInvalidTXTRecord) {
+		t.Errorf("error = %v, want ErrInvalidTXTRecord for a non-alphanumeric id", err)
+	}
+
+	record, err := mtasts.ParseTXTRecord("v=STSv1; id=20250101000000;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.ID != "20250101000000" {
+		t.Errorf("ID = %s, want 20250101000000", record.ID)
+	}
+
+	for _, value := range []string{"id=1; v=STSv1", "v=STSv2; id=1", "v=STSv1", "v=STSv1; id=" + strings.Repeat("a", 33)} {
+		if _, err := mtasts.ParseTXTRecord(value); !errors.Is(err, mtasts.ErrInvalidTXTRecord) {
+			t.Errorf("ParseTXTRecord(%q) error = %v, want ErrInvalidTXTRecord", value, err)
+		}
+	}
+}
+
+func TestFetchPolicy(t *testing.T) {
+	t.Run("valid policy", func(t *testing.T) {
+		checker, _ := newChecker(t, servePolicy(policyFile))
+
+		policy, raw, err := checker.FetchPolicy(context.Background(), "example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if policy.Mode != tlsrpt.STSModeEnforce {
+			t.Errorf("Mode = %s, want enforce", policy.Mode)
+		}
+		if len(policy.MX) != 2 {
+			t.Errorf("len(MX) = %d, want 2", len(policy.MX))
+		}
+		if raw != policyFile {
+			t.Errorf("raw = %q, want %q", raw, policyFile)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		checker, _ := newChecker(t, http.NotFound)
+
+		_, _, err := checker.FetchPolicy(context.Background(), "example.com")
+		if !errors.Is(err, mtasts.ErrPolicyFetch) {
+			t.Errorf("error = %v, want ErrPolicyFetch", err)
+		}
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		checker, _ := newChecker(t, func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "https://example.com/mta-sts.txt", http.StatusFound)
+		})
+
+		_, _, err := checker.FetchPolicy(context.Background(), "example.com")
+		if !errors.Is(err, mtasts.ErrPolicyFetch) {
+			t.Errorf("error = %v, want ErrPolicyFetch", err)
+		}
+	})
+
+	t.Run("wrong content type", func(t *testing.T) {
+		checker, _ := newChecker(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(policyFile))
+		})
+
+		_, _, err := checker.FetchPolicy(context.Background(), "example.com")
+		if !errors.Is(err, mtasts.ErrPolicyFetch) {
+			t.Errorf("error = %v, want ErrPolicyFetch", err)
+		}
+	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		checker, _ := newChecker(t, servePolicy("version: STSv1\r\nmode: enforce\r\nmax_age: 86400\r\n"))
+
+		_, _, err := checker.FetchPolicy(context.Background(), "example.com")
+		if !errors.Is(err, tlsrpt.ErrInvalidPolicyString) {
+			t.Errorf("error = %v, want ErrInvalidPolicyString", err)
+		}
+	})
+}
+
+func TestCheck(t *testing.T) {
+	t.Run("consistent", func(t *testing.T) {
+		checker, _ := newChecker(t, servePolicy(policyFile))
+
+		result := checker.Check(context.Background(), "example.com", nil)
+		if !result.Valid() || len(result.Warnings) != 0 {
+			t.Errorf("Check() errors = %v, warnings = %v, want none", result.Errors, result.Warnings)
+		}
+		if result.Record.ID != "20250101" {
+			t.Errorf("Record.ID = %s, want 20250101", result.Record.ID)
+		}
+	})
+
+	t.Run("policy changed without a new id", func(t *testing.T) {
+		checker, _ := newChecker(t, servePolicy(policyFile))
+
+		previous, err := tlsrpt.ParseSTSPolicy([]string{"version: STSv1", "mode: testing", "mx: mx1.example.com", "max_age: 604800"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := checker.Check(context.Background(), "example.com", &mtasts.Snapshot{ID: "20250101", Policy: previous, FetchedAt: time.Now()})
+		if result.Valid() {
+			t.Error("Check() is valid, want an error about the id")
+		}
+
+		result = checker.Check(context.Background(), "example.com", &mtasts.Snapshot{ID: "20241231", Policy: previous})
+		if !result.Valid() {
+			t.Errorf("Check() errors = %v, want none once the id changed", result.Errors)
+		}
+	})
+
+	t.Run("mx not covered", func(t *testing.T) {
+		checker, fake := newChecker(t, servePolicy(policyFile))
+		fake.SetMX("example.com", &net.MX{Host: "mx2.example.com.", Pref: 10})
+
+		result := checker.Check(context.Background(), "example.com", nil)
+		if len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Error(), "mx2.example.com") {
+			t.Errorf("Check() errors = %v, want one about mx2.example.com", result.Errors)
+		}
+	})
+
+	t.Run("missing record", func(t *testing.T) {
+		checker, fake := newChecker(t, servePolicy(policyFile))
+		fake.SetTXT("_mta-sts.example.com")
+
+		result := checker.Check(context.Background(), "example.com", nil)
+		if len(result.Errors) != 1 || !errors.Is(result.Errors[0], mtasts.ErrNoTXTRecord) {
+			t.Errorf("Check() errors = %v, want ErrNoTXTRecord", result.Errors)
+		}
+	})
+}
+
+func TestMatchesMX(t *testing.T) {
+	policy := tlsrpt.STSPolicy{MX: []string{"mx1.example.com", "*.mail.example.com"}}
+
+	for host, want := range map[string]bool{
+		"mx1.example.com":       true,
+		"MX1.example.com.":      true,
+		"eu.mail.example.com":   true,
+		"a.eu.mail.example.com": false,
+		"mail.example.com":      false,
+		"mx2.example.com":       false,
+	} {
+		if got := mtasts.MatchesMX(policy, host); got != want {
+			t.Errorf("MatchesMX(%s) = %t, want %t", host, got, want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	published, err := tlsrpt.ParseSTSPolicy([]string{policyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := mtasts.Diff(published, tlsrpt.Policy{
+		PolicyType:   "sts",
+		PolicyString: []string{"version: STSv1", "mode: testing", "mx: mx1.example.com", "mx: mx-old.example.com", "max_age: 86400"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"mode: published enforce, reported testing",
+		"max_age: published 604800, reported 86400",
+		"mx: *.mail.example.com is published but not reported",
+		"mx: mx-old.example.com is reported but no longer published",
+	}
+	if strings.Join(diff, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Diff() = %v, want %v", diff, want)
+	}
+
+	diff, err = mtasts.Diff(published, tlsrpt.Policy{PolicyType: "sts", PolicyString: published.Lines()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff) != 0 {
+		t.Errorf("Diff() = %v, want none", diff)
+	}
+}
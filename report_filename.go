@@ -0,0 +1,47 @@
+package mailweave
+
+import (
+	"time"
+
+	"github.com/aldy505/mailweave/reportname"
+)
+
+// ReportFileNameMismatches decodes the filename of an aggregate report and
+// cross-checks it against the reporter domain, the policy domain and the date
+// range of the report, returning a description of every mismatch. A filename
+// that does not follow the naming convention has nothing to cross-check and
+// returns nil.
+func ReportFileNameMismatches(filename string, receiver string, policyDomain string, rangeStart time.Time, rangeEnd time.Time) []string {
+	name, err := reportname.Parse(filename)
+	if err != nil {
+		return nil
+	}
+
+	var mismatches []string
+	for _, mismatch := range name.Verify(receiver, policyDomain, rangeStart, rangeEnd) {
+		mismatches = append(mismatches, mismatch.String())
+	}
+
+	return mismatches
+}
+
+// SetReportFileName sets the ReportFileName of the report, usually the
+// attachment filename, and cross-checks it with ReportFileNameMismatches.
+func (r *DmarcReport) SetReportFileName(filename string) {
+	r.ReportFileName = filename
+	r.ReportFileNameMismatches = ReportFileNameMismatches(filename, r.DomainName, r.PolicyDomain, r.RangeStart, r.RangeEnd)
+}
+
+// SetReportFileName sets the ReportFileName of the report, usually the
+// attachment filename, and cross-checks it with ReportFileNameMismatches. The
+// policy domain is the one of the first row, as a report is about a single
+// policy domain.
+func (r *TlsRptReport) SetReportFileName(filename string) {
+	var policyDomain string
+	if len(r.Rows) > 0 {
+		policyDomain = r.Rows[0].DomainName
+	}
+
+	r.ReportFileName = filename
+	r.ReportFileNameMismatches = ReportFileNameMismatches(filename, r.DomainName, policyDomain, r.RangeStart, r.RangeEnd)
+}
@@ -0,0 +1,72 @@
+package mailweave_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/aldy505/mailweave"
+	"github.com/aldy505/mailweave/dmarc"
+)
+
+func TestReportFileNameMismatches(t *testing.T) {
+	begin := time.Unix(1747180800, 0)
+	end := time.Unix(1747267199, 0)
+
+	if got := mailweave.ReportFileNameMismatches("example.org!example.com!1747180800!1747267199.xml", "example.org", "example.com", begin, end); len(got) != 0 {
+		t.Errorf("ReportFileNameMismatches() = %v, want none", got)
+	}
+	if got := mailweave.ReportFileNameMismatches("example.org!example.net!1747180800!1747267199.xml", "example.org", "example.com", begin, end); len(got) != 1 {
+		t.Errorf("len(ReportFileNameMismatches()) = %d, want 1", len(got))
+	}
+	if got := mailweave.ReportFileNameMismatches("report.xml", "example.org", "example.com", begin, end); got != nil {
+		t.Errorf("ReportFileNameMismatches() = %v, want nil", got)
+	}
+	if got := mailweave.ReportFileNameMismatches("example.net!example.com!1747180800!1747267199.xml", "example.org", "example.com", begin, end); len(got) != 1 {
+		t.Errorf("len(ReportFileNameMismatches()) = %d, want 1", len(got))
+	}
+
+	t.Run("attachment", func(t *testing.T) {
+		report := mailweave.DmarcReport{
+			DomainName:   "example.org",
+			PolicyDomain: "example.com",
+			RangeStart:   begin,
+			RangeEnd:     end,
+		}
+
+		report.SetReportFileName("example.org!example.com!1747180800!1747267200.xml.gz")
+		if len(report.ReportFileNameMismatches) != 1 {
+			t.Errorf("ReportFileNameMismatches = %v, want an end mismatch", report.ReportFileNameMismatches)
+		}
+	})
+
+	t.Run("zip member", func(t *testing.T) {
+		feedback := dmarc.Feedback{
+			ReportMetadata: dmarc.ReportMetadata{
+				OrgName:   "example.org",
+				Email:     "dmarc@example.org",
+				ReportID:  "1",
+				DateRange: dmarc.DateRange{Begin: begin.Unix(), End: end.Unix()},
+			},
+			PolicyPublished: dmarc.PolicyPublished{Domain: "example.com", P: "none"},
+		}
+
+		_, content, err := dmarc.PackageFeedback("example.org", feedback, dmarc.CompressionTypeZIP)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		feedbacks, err := dmarc.ParseFeedbacks(bytes.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		report := mailweave.NewDmarcReport(feedbacks[0])
+		if report.ReportFileName != "example.org!example.com!1747180800!1747267199.xml" {
+			t.Errorf("ReportFileName = %s, want example.org!example.com!1747180800!1747267199.xml", report.ReportFileName)
+		}
+		if len(report.ReportFileNameMismatches) != 0 {
+			t.Errorf("ReportFileNameMismatches = %v, want none", report.ReportFileNameMismatches)
+		}
+	})
+}
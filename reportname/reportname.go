@@ -0,0 +1,194 @@
+// Package reportname parses and formats the filenames of aggregate reports.
+//
+// DMARC aggregate reports (RFC 7489 Section 7.2.1.1) and TLS-RPT reports
+// (RFC 8460 Section 5.1) share the same naming convention:
+//
+//	receiver "!" policy-domain "!" begin-timestamp "!" end-timestamp [ "!" unique-id ] "." extension
+//
+// where the timestamps are seconds since the Unix epoch. For example,
+// "google.com!example.com!1747094400!1747180799!001.json.gz". The optional
+// unique-id is used by some TLS-RPT reporters, such as Microsoft with a
+// sequence number, or Mimecast with a SHA-256 hash.
+package reportname
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidName is returned when a filename does not follow the naming convention.
+var ErrInvalidName = errors.New("invalid report filename")
+
+// Name is a decoded report filename.
+type Name struct {
+	// The domain of the organization that sent the report.
+	Receiver string
+	// The domain the report is about.
+	PolicyDomain string
+	Begin        time.Time
+	End          time.Time
+	// The optional unique identifier, empty when absent.
+	UniqueID string
+	// The extension without the leading dot, e.g. "xml", "xml.gz", "zip",
+	// "json" or "json.gz". It may be empty, as not every reporter adds one.
+	Extension string
+}
+
+// Parse decodes a report filename. The filename must not contain directories.
+func Parse(filename string) (Name, error) {
+	parts := strings.Split(filename, "!")
+	if len(parts) != 4 && len(parts) != 5 {
+		return Name{}, fmt.Errorf("%w: %q has %d parts, want 4 or 5", ErrInvalidName, filename, len(parts))
+	}
+
+	// The extension starts at the first dot of the last part, as neither the
+	// end timestamp nor the unique-id can contain one.
+	last, extension, _ := strings.Cut(parts[len(parts)-1], ".")
+	parts[len(parts)-1] = last
+
+	name := Name{
+		Receiver:     parts[0],
+		PolicyDomain: parts[1],
+		Extension:    extension,
+	}
+
+	if name.Receiver == "" {
+		return Name{}, fmt.Errorf("%w: %q has an empty receiver", ErrInvalidName, filename)
+	}
+	if name.PolicyDomain == "" {
+		return Name{}, fmt.Errorf("%w: %q has an empty policy domain", ErrInvalidName, filename)
+	}
+
+	var err error
+	name.Begin, err = parseTimestamp(parts[2])
+	if err != nil {
+		return Name{}, fmt.Errorf("%w: %q has an invalid begin timestamp: %w", ErrInvalidName, filename, err)
+	}
+
+	name.End, err = parseTimestamp(parts[3])
+	if err != nil {
+		return Name{}, fmt.Errorf("%w: %q has an invalid end timestamp: %w", ErrInvalidName, filename, err)
+	}
+
+	if len(parts) == 5 {
+		name.UniqueID = parts[4]
+		if !isAlphanumeric(name.UniqueID) {
+			return Name{}, fmt.Errorf("%w: %q has an invalid unique-id", ErrInvalidName, filename)
+		}
+	}
+
+	return name, nil
+}
+
+// String formats the filename.
+func (n Name) String() string {
+	var b strings.Builder
+	b.WriteString(n.Receiver)
+	b.WriteString("!")
+	b.WriteString(n.PolicyDomain)
+	b.WriteString("!")
+	b.WriteString(strconv.FormatInt(n.Begin.Unix(), 10))
+	b.WriteString("!")
+	b.WriteString(strconv.FormatInt(n.End.Unix(), 10))
+	if n.UniqueID != "" {
+		b.WriteString("!")
+		b.WriteString(n.UniqueID)
+	}
+	if n.Extension != "" {
+		b.WriteString(".")
+		b.WriteString(n.Extension)
+	}
+
+	return b.String()
+}
+
+// Mismatch is a difference between a report filename and the content of the
+// report. Legitimate reporters name their reports after the content, so a
+// mismatch is a sign of a spoofed or mangled report.
+type Mismatch struct {
+	// The mismatching field: "receiver", "policy-domain", "begin" or "end".
+	Field    string
+	FileName string
+	Report   string
+}
+
+func (m Mismatch) String() string {
+	return fmt.Sprintf("%s is %s in the filename but %s in the report", m.Field, m.FileName, m.Report)
+}
+
+// Verify cross-checks the filename against the reporter domain, the policy
+// domain and the date range of the report. It returns nil when they match.
+//
+// The receiver matches when it is the reporter domain or one of its
+// subdomains, or the other way around, as reporters name their reports after
+// the host generating them as often as after their organizational domain. An
+// empty reporter domain is not checked, as not every report carries one.
+func (n Name) Verify(receiver string, policyDomain string, begin time.Time, end time.Time) []Mismatch {
+	var mismatches []Mismatch
+
+	if receiver != "" && !relatedDomains(normalizeDomain(n.Receiver), normalizeDomain(receiver)) {
+		mismatches = append(mismatches, Mismatch{Field: "receiver", FileName: n.Receiver, Report: receiver})
+	}
+
+	if normalizeDomain(n.PolicyDomain) != normalizeDomain(policyDomain) {
+		mismatches = append(mismatches, Mismatch{Field: "policy-domain", FileName: n.PolicyDomain, Report: policyDomain})
+	}
+
+	if n.Begin.Unix() != begin.Unix() {
+		mismatches = append(mismatches, Mismatch{
+			Field:    "begin",
+			FileName: strconv.FormatInt(n.Begin.Unix(), 10),
+			Report:   strconv.FormatInt(begin.Unix(), 10),
+		})
+	}
+
+	if n.End.Unix() != end.Unix() {
+		mismatches = append(mismatches, Mismatch{
+			Field:    "end",
+			FileName: strconv.FormatInt(n.End.Unix(), 10),
+			Report:   strconv.FormatInt(end.Unix(), 10),
+		})
+	}
+
+	return mismatches
+}
+
+func parseTimestamp(value string) (time.Time, error) {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if seconds < 0 {
+		return time.Time{}, fmt.Errorf("negative timestamp %d", seconds)
+	}
+
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+func isAlphanumeric(value string) bool {
+	if value == "" {
+		return false
+	}
+
+	for _, r := range value {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// relatedDomains reports whether a and b are the same domain, or one is a
+// subdomain of the other.
+func relatedDomains(a string, b string) bool {
+	return a == b || strings.HasSuffix(a, "."+b) || strings.HasSuffix(b, "."+a)
+}
+
+func normalizeDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
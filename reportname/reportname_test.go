@@ -0,0 +1,129 @@
+package reportname_test
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/aldy505/mailweave/reportname"
+)
+
+func TestParseFixtures(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, directory := range []string{"dmarc", "tlsrpt"} {
+		entries, err := os.ReadDir(path.Join(pwd, "../testdata", directory))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, entry := range entries {
+			t.Run(entry.Name(), func(t *testing.T) {
+				name, err := reportname.Parse(entry.Name())
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if name.PolicyDomain != "example.com" {
+					t.Errorf("PolicyDomain = %s, want example.com", name.PolicyDomain)
+				}
+				if got := name.String(); got != entry.Name() {
+					t.Errorf("String() = %s, want %s", got, entry.Name())
+				}
+			})
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Run("unique-id", func(t *testing.T) {
+		name, err := reportname.Parse("mimecast.org!example.com!1742860800!1742947199!c7115a9ad9266efcddcb560fc5963028de5d922f5d1bf878e8ec82a8d6a5cf2c.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if name.Receiver != "mimecast.org" {
+			t.Errorf("Receiver = %s, want mimecast.org", name.Receiver)
+		}
+		if !name.Begin.Equal(time.Date(2025, time.March, 25, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("Begin = %s, want 2025-03-25 00:00:00 UTC", name.Begin)
+		}
+		if !name.End.Equal(time.Date(2025, time.March, 25, 23, 59, 59, 0, time.UTC)) {
+			t.Errorf("End = %s, want 2025-03-25 23:59:59 UTC", name.End)
+		}
+		if name.UniqueID != "c7115a9ad9266efcddcb560fc5963028de5d922f5d1bf878e8ec82a8d6a5cf2c" {
+			t.Errorf("UniqueID = %s, want the mimecast hash", name.UniqueID)
+		}
+		if name.Extension != "json" {
+			t.Errorf("Extension = %s, want json", name.Extension)
+		}
+	})
+
+	t.Run("compressed extension", func(t *testing.T) {
+		name, err := reportname.Parse("google.com!example.com!1747094400!1747180799!001.json.gz")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if name.UniqueID != "001" {
+			t.Errorf("UniqueID = %s, want 001", name.UniqueID)
+		}
+		if name.Extension != "json.gz" {
+			t.Errorf("Extension = %s, want json.gz", name.Extension)
+		}
+	})
+
+	invalid := []string{
+		"report.xml",
+		"google.com!example.com!1747094400.xml",
+		"google.com!example.com!yesterday!1747180799.xml",
+		"google.com!example.com!1747094400!-1.xml",
+		"!example.com!1747094400!1747180799.xml",
+		"google.com!!1747094400!1747180799.xml",
+		"google.com!example.com!1747094400!1747180799!not-alnum.json",
+		"google.com!example.com!1747094400!1747180799!1!2.json",
+	}
+	for _, filename := range invalid {
+		t.Run(filename, func(t *testing.T) {
+			if _, err := reportname.Parse(filename); !errors.Is(err, reportname.ErrInvalidName) {
+				t.Errorf("Parse(%s) error = %v, want %v", filename, err, reportname.ErrInvalidName)
+			}
+		})
+	}
+}
+
+func TestNameVerify(t *testing.T) {
+	name, err := reportname.Parse("google.com!example.com!1747094400!1747180799!001.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	begin := time.Date(2025, time.May, 13, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, time.May, 13, 23, 59, 59, 0, time.UTC)
+
+	for _, receiver := range []string{"google.com", "Google.com.", "mail.google.com", ""} {
+		if mismatches := name.Verify(receiver, "Example.com.", begin, end); len(mismatches) != 0 {
+			t.Errorf("Verify(%q) = %v, want no mismatches", receiver, mismatches)
+		}
+	}
+
+	if mismatches := name.Verify("notgoogle.com", "example.com", begin, end); len(mismatches) != 1 || mismatches[0].Field != "receiver" {
+		t.Errorf("Verify() = %v, want a receiver mismatch", mismatches)
+	}
+
+	mismatches := name.Verify("google.com", "example.net", begin, end.Add(24*time.Hour))
+	if len(mismatches) != 2 {
+		t.Fatalf("len(Verify()) = %d, want 2", len(mismatches))
+	}
+	if mismatches[0].Field != "policy-domain" {
+		t.Errorf("mismatches[0].Field = %s, want policy-domain", mismatches[0].Field)
+	}
+	if got := mismatches[1].String(); got != "end is 1747180799 in the filename but 1747267199 in the report" {
+		t.Errorf("mismatches[1].String() = %s", got)
+	}
+}
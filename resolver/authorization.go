@@ -0,0 +1,173 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aldy505/mailweave"
+	"github.com/aldy505/mailweave/dmarc"
+)
+
+// ExternalDestination is a report destination of a DMARC record whose
+// organizational domain differs from the domain publishing the record. As per
+// RFC 7489 Section 7.1, receivers only send reports to it when the destination
+// domain publishes an authorization record.
+type ExternalDestination struct {
+	// The tag listing the destination, either "rua" or "ruf".
+	Tag string
+	URI dmarc.ReportURI
+	// The domain of the destination address.
+	Domain string
+	// The name of the authorization TXT record, e.g.
+	// example.com._report._dmarc.example.net.
+	AuthorizationName string
+	// Whether the authorization record was found, either at AuthorizationName
+	// or at the wildcard name covering every domain. Reports sent to an
+	// unauthorized destination are silently dropped by receivers.
+	Authorized bool
+}
+
+// AuthorizationName returns the name of the TXT record that authorizes the
+// destination domain to receive the reports of the domain, as defined in
+// RFC 7489 Section 7.1.
+func AuthorizationName(domain string, destinationDomain string) string {
+	return NormalizeDomain(domain) + "._report._dmarc." + NormalizeDomain(destinationDomain)
+}
+
+// WildcardAuthorizationName returns the name of the TXT record that authorizes
+// the destination domain to receive the reports of every domain, e.g.
+// *._report._dmarc.example.net. Receivers fall back to it when there is no
+// authorization record for the domain itself.
+func WildcardAuthorizationName(destinationDomain string) string {
+	return "*._report._dmarc." + NormalizeDomain(destinationDomain)
+}
+
+// ExternalDestinations returns the mailto destinations of the record that
+// require an authorization record, without looking them up.
+func ExternalDestinations(domain string, record dmarc.DNSRecord) []ExternalDestination {
+	var destinations []ExternalDestination
+	for _, tag := range []struct {
+		name string
+		uris []dmarc.ReportURI
+	}{
+		{name: "rua", uris: record.AggregateReportURIs},
+		{name: "ruf", uris: record.FailureReportURIs},
+	} {
+		for _, uri := range tag.uris {
+			_, destinationDomain, ok := strings.Cut(uri.Address(), "@")
+			if !ok {
+				continue
+			}
+
+			if dmarc.OrganizationalDomain(destinationDomain) == dmarc.OrganizationalDomain(domain) {
+				continue
+			}
+
+			destinations = append(destinations, ExternalDestination{
+				Tag:               tag.name,
+				URI:               uri,
+				Domain:            NormalizeDomain(destinationDomain),
+				AuthorizationName: AuthorizationName(domain, destinationDomain),
+			})
+		}
+	}
+
+	return destinations
+}
+
+// VerifyExternalDestinations looks up the authorization record of every
+// external destination of the record published by the domain. A destination
+// is authorized when one of the TXT records of its authorization name starts
+// with "v=DMARC1". A failed lookup does not stop the other destinations from
+// being verified, and is returned along with them. When the authorization name
+// has no such record, the WildcardAuthorizationName is tried as well.
+func VerifyExternalDestinations(ctx context.Context, resolver Resolver, domain string, record dmarc.DNSRecord) ([]ExternalDestination, error) {
+	return verify(ctx, resolver, ExternalDestinations(domain, record))
+}
+
+// VerifyMailboxAuthorization runs the reverse check of
+// VerifyExternalDestinations: it returns the external destinations of the
+// DMARC record of the domain that point at the given mailbox, e.g. the
+// mailbox Mailweave reads the reports from. An unauthorized destination means
+// the domain of the mailbox must publish its authorization record for the
+// reports to arrive. It returns no destination when the domain has no DMARC
+// record, does not send its reports to the mailbox, or sends them from the
+// same organizational domain, and an error when it has more than one DMARC
+// record.
+func VerifyMailboxAuthorization(ctx context.Context, resolver Resolver, domain string, mailbox string) ([]ExternalDestination, error) {
+	values, err := resolver.LookupTXT(ctx, RecordName(domain, mailweave.DnsRecordDmarc))
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("looking up the DMARC record of %s: %w", domain, err)
+	}
+
+	var dmarcRecords []string
+	for _, value := range values {
+		if strings.HasPrefix(strings.ToLower(strings.TrimSpace(value)), versionPrefix(mailweave.DnsRecordDmarc)) {
+			dmarcRecords = append(dmarcRecords, value)
+		}
+	}
+	switch len(dmarcRecords) {
+	case 0:
+		return nil, nil
+	case 1:
+	default:
+		return nil, fmt.Errorf("%s has %d DMARC records, want 1", domain, len(dmarcRecords))
+	}
+
+	record, err := dmarc.ParseDNSRecord(dmarcRecords[0])
+	if err != nil {
+		return nil, fmt.Errorf("parsing the DMARC record of %s: %w", domain, err)
+	}
+
+	var destinations []ExternalDestination
+	for _, destination := range ExternalDestinations(domain, record) {
+		if strings.EqualFold(destination.URI.Address(), mailbox) {
+			destinations = append(destinations, destination)
+		}
+	}
+
+	return verify(ctx, resolver, destinations)
+}
+
+func verify(ctx context.Context, resolver Resolver, destinations []ExternalDestination) ([]ExternalDestination, error) {
+	var errs []error
+	for i := range destinations {
+		authorized, err := lookupAuthorization(ctx, resolver, destinations[i].AuthorizationName)
+		if err == nil && !authorized {
+			authorized, err = lookupAuthorization(ctx, resolver, WildcardAuthorizationName(destinations[i].Domain))
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		destinations[i].Authorized = authorized
+	}
+
+	return destinations, errors.Join(errs...)
+}
+
+func lookupAuthorization(ctx context.Context, resolver Resolver, name string) (bool, error) {
+	values, err := resolver.LookupTXT(ctx, name)
+	if err != nil {
+		if IsNotFound(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("looking up TXT of %s: %w", name, err)
+	}
+
+	for _, value := range values {
+		if strings.HasPrefix(strings.ToLower(strings.TrimSpace(value)), versionPrefix(mailweave.DnsRecordDmarc)) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
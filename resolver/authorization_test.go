@@ -0,0 +1,120 @@
+package resolver_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aldy505/mailweave/dmarc"
+	"github.com/aldy505/mailweave/resolver"
+)
+
+func TestVerifyExternalDestinations(t *testing.T) {
+	record, err := dmarc.ParseDNSRecord("v=DMARC1; p=reject; rua=mailto:dmarc@example.com,mailto:reports@example.net,mailto:agg@reports.example.org; ruf=mailto:forensic@example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &resolver.FakeResolver{}
+	fake.SetTXT("example.com._report._dmarc.example.net", "v=DMARC1")
+	fake.SetTXT("example.com._report._dmarc.example.org", "unrelated")
+
+	destinations, err := resolver.VerifyExternalDestinations(context.Background(), fake, "mail.example.com", record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(destinations) != 3 {
+		t.Fatalf("len(destinations) = %d, want 3", len(destinations))
+	}
+
+	for _, want := range []struct {
+		tag        string
+		name       string
+		authorized bool
+	}{
+		{tag: "rua", name: "mail.example.com._report._dmarc.example.net", authorized: false},
+		{tag: "rua", name: "mail.example.com._report._dmarc.reports.example.org", authorized: false},
+		{tag: "ruf", name: "mail.example.com._report._dmarc.example.org", authorized: false},
+	} {
+		found := false
+		for _, destination := range destinations {
+			if destination.Tag == want.tag && destination.AuthorizationName == want.name {
+				found = true
+				if destination.Authorized != want.authorized {
+					t.Errorf("%s Authorized = %t, want %t", want.name, destination.Authorized, want.authorized)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("missing destination %s", want.name)
+		}
+	}
+
+	destinations, err = resolver.VerifyExternalDestinations(context.Background(), fake, "example.com", record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, destination := range destinations {
+		want := destination.Domain == "example.net"
+		if destination.Authorized != want {
+			t.Errorf("%s Authorized = %t, want %t", destination.AuthorizationName, destination.Authorized, want)
+		}
+	}
+}
+
+func TestVerifyMailboxAuthorization(t *testing.T) {
+	fake := &resolver.FakeResolver{}
+	fake.SetTXT("_dmarc.example.com", "v=DMARC1; p=none; rua=mailto:dmarc@mailweave.example.net")
+	fake.SetTXT("_dmarc.example.org", "v=DMARC1; p=none; rua=mailto:dmarc@mailweave.example.net")
+	fake.SetTXT("example.org._report._dmarc.mailweave.example.net", "v=DMARC1")
+	fake.SetTXT("_dmarc.example.info", "v=DMARC1; p=none; rua=mailto:dmarc@example.info")
+	fake.SetTXT("_dmarc.example.biz", "google-site-verification=abc")
+	fake.SetTXT("_dmarc.example.io", "v=DMARC1; p=none; rua=mailto:dmarc@reports.example.dev")
+	fake.SetTXT("*._report._dmarc.reports.example.dev", "v=DMARC1")
+
+	for _, tt := range []struct {
+		domain       string
+		destinations int
+		authorized   bool
+	}{
+		{domain: "example.com", destinations: 1, authorized: false},
+		{domain: "example.org", destinations: 1, authorized: true},
+		{domain: "example.info", destinations: 0},
+		{domain: "example.edu", destinations: 0},
+		{domain: "example.biz", destinations: 0},
+	} {
+		t.Run(tt.domain, func(t *testing.T) {
+			destinations, err := resolver.VerifyMailboxAuthorization(context.Background(), fake, tt.domain, "DMARC@mailweave.example.net")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(destinations) != tt.destinations {
+				t.Fatalf("len(destinations) = %d, want %d", len(destinations), tt.destinations)
+			}
+			if tt.destinations > 0 && destinations[0].Authorized != tt.authorized {
+				t.Errorf("Authorized = %t, want %t", destinations[0].Authorized, tt.authorized)
+			}
+		})
+	}
+
+	t.Run("wildcard authorization", func(t *testing.T) {
+		destinations, err := resolver.VerifyMailboxAuthorization(context.Background(), fake, "example.io", "dmarc@reports.example.dev")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(destinations) != 1 || !destinations[0].Authorized {
+			t.Errorf("destinations = %+v, want one authorized destination", destinations)
+		}
+	})
+
+	t.Run("multiple records", func(t *testing.T) {
+		fake.SetTXT("_dmarc.example.net", "v=DMARC1; p=none", "v=DMARC1; p=reject")
+
+		if _, err := resolver.VerifyMailboxAuthorization(context.Background(), fake, "example.net", "dmarc@mailweave.example.net"); err == nil {
+			t.Error("VerifyMailboxAuthorization() error = nil, want an error")
+		}
+	})
+}
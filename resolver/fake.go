@@ -0,0 +1,184 @@
+package resolver
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// FakeResolver implements Resolver and spf.Resolver with in-memory records.
+// It should be used for testing purposes. Names are matched case-insensitively, without the
+// trailing dot. It is safe for concurrent use.
+type FakeResolver struct {
+	mu  sync.Mutex
+	TXT map[string][]string
+	MX  map[string][]*net.MX
+	IP  map[string][]net.IPAddr
+	// The PTR records, keyed by IP address.
+	PTR map[string][]string
+	// Errors are returned for the names instead of looking them up.
+	Errors map[string]error
+}
+
+var _ Resolver = (*FakeResolver)(nil)
+
+// SetTXT replaces the TXT records of the name. Calling it with no values
+// removes the records.
+func (f *FakeResolver) SetTXT(name string, values ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.TXT == nil {
+		f.TXT = make(map[string][]string)
+	}
+
+	name = NormalizeDomain(name)
+	if len(values) == 0 {
+		delete(f.TXT, name)
+		return
+	}
+
+	f.TXT[name] = values
+}
+
+// SetMX replaces the MX records of the name. Calling it with no values
+// removes the records.
+func (f *FakeResolver) SetMX(name string, values ...*net.MX) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.MX == nil {
+		f.MX = make(map[string][]*net.MX)
+	}
+
+	name = NormalizeDomain(name)
+	if len(values) == 0 {
+		delete(f.MX, name)
+		return
+	}
+
+	f.MX[name] = values
+}
+
+// SetIP replaces the A and AAAA records of the name. Calling it with no
+// values removes the records. It panics on an invalid address.
+func (f *FakeResolver) SetIP(name string, values ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.IP == nil {
+		f.IP = make(map[string][]net.IPAddr)
+	}
+
+	name = NormalizeDomain(name)
+	if len(values) == 0 {
+		delete(f.IP, name)
+		return
+	}
+
+	addrs := make([]net.IPAddr, 0, len(values))
+	for _, value := range values {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			panic("invalid IP address " + value)
+		}
+		addrs = append(addrs, net.IPAddr{IP: ip})
+	}
+
+	f.IP[name] = addrs
+}
+
+// SetPTR replaces the PTR records of the IP address. Calling it with no
+// values removes the records.
+func (f *FakeResolver) SetPTR(addr string, names ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.PTR == nil {
+		f.PTR = make(map[string][]string)
+	}
+
+	if len(names) == 0 {
+		delete(f.PTR, addr)
+		return
+	}
+
+	f.PTR[addr] = names
+}
+
+// LookupTXT implements Resolver.
+func (f *FakeResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name = NormalizeDomain(name)
+	if err, ok := f.Errors[name]; ok {
+		return nil, err
+	}
+
+	values, ok := f.TXT[name]
+	if !ok {
+		return nil, notFound(name)
+	}
+
+	return append([]string(nil), values...), nil
+}
+
+// LookupMX implements Resolver.
+func (f *FakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name = NormalizeDomain(name)
+	if err, ok := f.Errors[name]; ok {
+		return nil, err
+	}
+
+	values, ok := f.MX[name]
+	if !ok {
+		return nil, notFound(name)
+	}
+
+	return append([]*net.MX(nil), values...), nil
+}
+
+// LookupIPAddr looks up the A and AAAA records of the host, as
+// net.Resolver.LookupIPAddr does.
+func (f *FakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	host = NormalizeDomain(host)
+	if err, ok := f.Errors[host]; ok {
+		return nil, err
+	}
+
+	values, ok := f.IP[host]
+	if !ok {
+		return nil, notFound(host)
+	}
+
+	return append([]net.IPAddr(nil), values...), nil
+}
+
+// LookupAddr looks up the PTR records of the IP address, as
+// net.Resolver.LookupAddr does.
+func (f *FakeResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err, ok := f.Errors[addr]; ok {
+		return nil, err
+	}
+
+	values, ok := f.PTR[addr]
+	if !ok {
+		return nil, notFound(addr)
+	}
+
+	return append([]string(nil), values...), nil
+}
+
+func notFound(name string) error {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
@@ -0,0 +1,127 @@
+// Package resolver fetches the DNS records published by the managed domains,
+// and keeps a history of them to tell when they changed.
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aldy505/mailweave"
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+// Resolver looks up DNS records. It is implemented by *net.Resolver, and by
+// FakeResolver for testing purposes.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+var _ Resolver = (*net.Resolver)(nil)
+
+// RecordName returns the name queried for the record type of the domain, e.g.
+// _dmarc.example.com for mailweave.DnsRecordDmarc.
+func RecordName(domain string, recordType mailweave.DnsRecordType) string {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+
+	switch recordType {
+	case mailweave.DnsRecordDmarc:
+		return "_dmarc." + domain
+	case mailweave.DnsRecordTlsRpt:
+		return "_smtp._tls." + domain
+	case mailweave.DnsRecordMtaSts:
+		return "_mta-sts." + domain
+	default:
+		return domain
+	}
+}
+
+// Fetch looks up the record type of the domain. The values are sorted, so two
+// snapshots of the same record are equal regardless of the order of the
+// answer. A record that does not exist is returned with no values, while other
+// lookup failures are returned as errors.
+//
+// Only the TXT records starting with the version tag of the record type are
+// kept, e.g. "v=spf1" for mailweave.DnsRecordSpf, so unrelated TXT records
+// such as site verification tokens do not count as a change. MX records are
+// formatted as "preference host".
+func Fetch(ctx context.Context, resolver Resolver, domain string, recordType mailweave.DnsRecordType) (mailweave.DnsRecordSnapshot, error) {
+	snapshot := mailweave.DnsRecordSnapshot{
+		Domain:     domain,
+		RecordType: recordType,
+		Name:       RecordName(domain, recordType),
+		Values:     []string{},
+	}
+
+	if recordType == mailweave.DnsRecordMx {
+		records, err := resolver.LookupMX(ctx, snapshot.Name)
+		if err != nil && !IsNotFound(err) {
+			return mailweave.DnsRecordSnapshot{}, fmt.Errorf("looking up MX of %s: %w", snapshot.Name, err)
+		}
+
+		for _, record := range records {
+			host := strings.ToLower(strings.TrimSuffix(record.Host, "."))
+			snapshot.Values = append(snapshot.Values, strconv.Itoa(int(record.Pref))+" "+host)
+		}
+	} else {
+		records, err := resolver.LookupTXT(ctx, snapshot.Name)
+		if err != nil && !IsNotFound(err) {
+			return mailweave.DnsRecordSnapshot{}, fmt.Errorf("looking up TXT of %s: %w", snapshot.Name, err)
+		}
+
+		for _, record := range records {
+			if isRecordType(record, recordType) {
+				snapshot.Values = append(snapshot.Values, record)
+			}
+		}
+	}
+
+	slices.Sort(snapshot.Values)
+	snapshot.FetchedAt = time.Now()
+
+	return snapshot, nil
+}
+
+// isRecordType reports whether the TXT record value is of the record type.
+// TLS-RPT records are matched with tlsrpt.IsDNSRecord, so that a record kept
+// here is also accepted by the tlsrpt parser.
+func isRecordType(value string, recordType mailweave.DnsRecordType) bool {
+	if recordType == mailweave.DnsRecordTlsRpt {
+		return tlsrpt.IsDNSRecord(value)
+	}
+
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(value)), versionPrefix(recordType))
+}
+
+func versionPrefix(recordType mailweave.DnsRecordType) string {
+	switch recordType {
+	case mailweave.DnsRecordDmarc:
+		return "v=dmarc1"
+	case mailweave.DnsRecordSpf:
+		return "v=spf1"
+	case mailweave.DnsRecordMtaSts:
+		return "v=stsv1"
+	default:
+		return ""
+	}
+}
+
+// IsNotFound reports whether err is a lookup failure because the name or the
+// record does not exist, as opposed to a failure to get an answer.
+func IsNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// NormalizeDomain lowercases the domain and removes its surrounding spaces and
+// trailing dot, so that names from DNS answers, reports and user input compare
+// equal.
+func NormalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+}
@@ -0,0 +1,107 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/aldy505/mailweave"
+)
+
+// Watcher regularly fetches the DMARC, TLS-RPT, SPF, MX and MTA-STS records of
+// every managed domain, and stores a snapshot of each record the first time it
+// is seen and whenever it changes. The snapshots line up the policy_published
+// of the reports with the moment the DNS records changed.
+type Watcher struct {
+	Resolver  Resolver
+	Domains   mailweave.ManagedDomains
+	Snapshots mailweave.DnsRecordSnapshots
+	// Called for every record that differs from its last snapshot. Optional.
+	OnChange func(ctx context.Context, change mailweave.DnsRecordChange)
+}
+
+// Poll fetches the records of every managed domain once, and returns the
+// records that changed since their last snapshot. A failed lookup does not
+// stop the other records from being fetched, and is returned along with the
+// changes.
+func (w *Watcher) Poll(ctx context.Context) ([]mailweave.DnsRecordChange, error) {
+	domains, err := w.Domains.GetManagedDomains(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting managed domains: %w", err)
+	}
+
+	var changes []mailweave.DnsRecordChange
+	var errs []error
+	for _, domain := range domains {
+		for _, recordType := range mailweave.DnsRecordTypes {
+			change, changed, err := w.check(ctx, domain, recordType)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			if changed {
+				changes = append(changes, change)
+				if w.OnChange != nil {
+					w.OnChange(ctx, change)
+				}
+			}
+		}
+	}
+
+	return changes, errors.Join(errs...)
+}
+
+func (w *Watcher) check(ctx context.Context, domain string, recordType mailweave.DnsRecordType) (mailweave.DnsRecordChange, bool, error) {
+	current, err := Fetch(ctx, w.Resolver, domain, recordType)
+	if err != nil {
+		return mailweave.DnsRecordChange{}, false, err
+	}
+
+	previous, err := w.Snapshots.GetLatestDnsRecordSnapshot(ctx, domain, recordType)
+	if err != nil && !errors.Is(err, mailweave.ErrNoDnsRecordSnapshot) {
+		return mailweave.DnsRecordChange{}, false, fmt.Errorf("getting the last %s snapshot of %s: %w", recordType, domain, err)
+	}
+
+	firstSnapshot := err != nil
+	if !firstSnapshot && slices.Equal(previous.Values, current.Values) {
+		return mailweave.DnsRecordChange{}, false, nil
+	}
+
+	if err := w.Snapshots.WriteDnsRecordSnapshot(ctx, domain, current); err != nil {
+		return mailweave.DnsRecordChange{}, false, fmt.Errorf("writing the %s snapshot of %s: %w", recordType, domain, err)
+	}
+
+	if firstSnapshot {
+		return mailweave.DnsRecordChange{}, false, nil
+	}
+
+	return mailweave.DnsRecordChange{
+		Domain:     domain,
+		RecordType: recordType,
+		Previous:   previous,
+		Current:    current,
+	}, true, nil
+}
+
+// Run polls the records every interval until the context is done. Failed
+// polls are logged and retried on the next tick.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.Poll(ctx); err != nil {
+			slog.ErrorContext(ctx, "failed to poll dns records", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package resolver_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+
+	"github.com/aldy505/mailweave"
+	"github.com/aldy505/mailweave/datastore"
+	"github.com/aldy505/mailweave/resolver"
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+func TestFetch(t *testing.T) {
+	fake := &resolver.FakeResolver{}
+	fake.SetTXT("example.com", "google-site-verification=abc", "v=spf1 include:_spf.example.net -all")
+	fake.SetTXT("_dmarc.example.com", "v=DMARC1; p=reject")
+	fake.SetMX("example.com", &net.MX{Host: "MX2.example.com.", Pref: 20}, &net.MX{Host: "mx1.example.com.", Pref: 10})
+
+	t.Run("spf", func(t *testing.T) {
+		snapshot, err := resolver.Fetch(context.Background(), fake, "example.com", mailweave.DnsRecordSpf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(snapshot.Values, []string{"v=spf1 include:_spf.example.net -all"}) {
+			t.Errorf("Values = %v, want only the SPF record", snapshot.Values)
+		}
+	})
+
+	t.Run("mx", func(t *testing.T) {
+		snapshot, err := resolver.Fetch(context.Background(), fake, "example.com", mailweave.DnsRecordMx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(snapshot.Values, []string{"10 mx1.example.com", "20 mx2.example.com"}) {
+			t.Errorf("Values = %v, want [10 mx1.example.com 20 mx2.example.com]", snapshot.Values)
+		}
+	})
+
+	t.Run("tlsrpt", func(t *testing.T) {
+		fake := &resolver.FakeResolver{}
+		fake.SetTXT("_smtp._tls.example.com", "v = TLSRPTv1; rua=mailto:tlsrpt@example.com", "v=TLSRPTv10; rua=mailto:tlsrpt@example.com")
+
+		snapshot, err := resolver.Fetch(context.Background(), fake, "example.com", mailweave.DnsRecordTlsRpt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(snapshot.Values, []string{"v = TLSRPTv1; rua=mailto:tlsrpt@example.com"}) {
+			t.Errorf("Values = %v, want only the TLSRPTv1 record", snapshot.Values)
+		}
+		if _, err := tlsrpt.ParseDNSRecords(snapshot.Values); err != nil {
+			t.Errorf("ParseDNSRecords() error = %v", err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		snapshot, err := resolver.Fetch(context.Background(), fake, "example.com", mailweave.DnsRecordTlsRpt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if snapshot.Name != "_smtp._tls.example.com" {
+			t.Errorf("Name = %s, want _smtp._tls.example.com", snapshot.Name)
+		}
+		if len(snapshot.Values) != 0 {
+			t.Errorf("Values = %v, want none", snapshot.Values)
+		}
+	})
+
+	t.Run("lookup failure", func(t *testing.T) {
+		failing := &resolver.FakeResolver{Errors: map[string]error{"_dmarc.example.com": errors.New("timeout")}}
+
+		_, err := resolver.Fetch(context.Background(), failing, "example.com", mailweave.DnsRecordDmarc)
+		if err == nil {
+			t.Error("Fetch() error = nil, want an error")
+		}
+	})
+}
+
+func TestWatcher(t *testing.T) {
+	fake := &resolver.FakeResolver{}
+	fake.SetTXT("_dmarc.example.com", "v=DMARC1; p=none")
+
+	store := &datastore.FakeDatastore{Domains: map[string]string{"example.com": "owner@example.com"}}
+
+	var raised []mailweave.DnsRecordChange
+	watcher := &resolver.Watcher{
+		Resolver:  fake,
+		Domains:   store,
+		Snapshots: store,
+		OnChange: func(ctx context.Context, change mailweave.DnsRecordChange) {
+			raised = append(raised, change)
+		},
+	}
+
+	changes, err := watcher.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("first poll changes = %v, want none", changes)
+	}
+	if len(store.DnsRecordSnapshots) != len(mailweave.DnsRecordTypes) {
+		t.Errorf("len(DnsRecordSnapshots) = %d, want %d", len(store.DnsRecordSnapshots), len(mailweave.DnsRecordTypes))
+	}
+
+	changes, err = watcher.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("unchanged poll changes = %v, want none", changes)
+	}
+	if len(store.DnsRecordSnapshots) != len(mailweave.DnsRecordTypes) {
+		t.Errorf("len(DnsRecordSnapshots) = %d, want %d", len(store.DnsRecordSnapshots), len(mailweave.DnsRecordTypes))
+	}
+
+	fake.SetTXT("_dmarc.example.com", "v=DMARC1; p=reject")
+
+	changes, err = watcher.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || len(raised) != 1 {
+		t.Fatalf("changes = %v, raised = %v, want one change", changes, raised)
+	}
+
+	change := changes[0]
+	if change.RecordType != mailweave.DnsRecordDmarc {
+		t.Errorf("RecordType = %s, want dmarc", change.RecordType)
+	}
+	if !slices.Equal(change.Previous.Values, []string{"v=DMARC1; p=none"}) {
+		t.Errorf("Previous.Values = %v, want [v=DMARC1; p=none]", change.Previous.Values)
+	}
+	if !slices.Equal(change.Current.Values, []string{"v=DMARC1; p=reject"}) {
+		t.Errorf("Current.Values = %v, want [v=DMARC1; p=reject]", change.Current.Values)
+	}
+
+	snapshots, err := store.GetDnsRecordSnapshots(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != len(mailweave.DnsRecordTypes)+1 {
+		t.Errorf("len(snapshots) = %d, want %d", len(snapshots), len(mailweave.DnsRecordTypes)+1)
+	}
+}
@@ -0,0 +1,235 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aldy505/mailweave"
+	"github.com/aldy505/mailweave/resolver"
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+// MTASTSPolicyPath is the path MTA-STS policies are served at, as defined in
+// RFC 8461 Section 3.3.
+const MTASTSPolicyPath = "/.well-known/mta-sts.txt"
+
+// MTASTSHandler serves the MTA-STS policy of the managed domains at
+// MTASTSPolicyPath. The domain is taken from the Host header, which must be
+// mta-sts.<domain>, so the handler can serve every domain whose mta-sts host
+// points at Mailweave. TLS is expected to be terminated in front of it with a
+// certificate covering those hosts.
+type MTASTSHandler struct {
+	Policies mailweave.MtaStsPolicies
+	Domains  mailweave.ManagedDomains
+}
+
+// ServeHTTP implements http.Handler.
+func (h *MTASTSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.URL.Path != MTASTSPolicyPath {
+		http.NotFound(w, r)
+		return
+	}
+
+	host := r.Host
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+
+	domain, ok := strings.CutPrefix(resolver.NormalizeDomain(host), "mta-sts.")
+	if !ok || domain == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if _, err := h.Domains.GetDomainOwner(r.Context(), domain); err != nil {
+		if !errors.Is(err, mailweave.ErrDomainNotManaged) {
+			slog.ErrorContext(r.Context(), "failed to get domain owner", slog.String("domain", domain), slog.String("error", err.Error()))
+		}
+		http.NotFound(w, r)
+		return
+	}
+
+	policy, err := h.Policies.GetMtaStsPolicy(r.Context(), domain)
+	if err != nil {
+		if !errors.Is(err, mailweave.ErrNoMtaStsPolicy) {
+			slog.ErrorContext(r.Context(), "failed to get mta-sts policy", slog.String("domain", domain), slog.String("error", err.Error()))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		_, _ = w.Write([]byte(strings.Join(policy.STSPolicy().Lines(), "\r\n") + "\r\n"))
+	}
+}
+
+// mtaStsPolicy is the JSON representation of a policy in the API.
+type mtaStsPolicy struct {
+	Domain string   `json:"domain"`
+	Mode   string   `json:"mode"`
+	MX     []string `json:"mx"`
+	// The max_age in seconds.
+	MaxAge    int64     `json:"max_age"`
+	Id        string    `json:"id,omitempty"`
+	TXTRecord string    `json:"txt_record,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+}
+
+// MTASTSPolicyHandler is the API managing the MTA-STS policies served by
+// MTASTSHandler. It must be registered with a pattern containing a {domain}
+// wildcard, e.g. "/api/mta-sts/{domain}".
+//
+// Every request must pass Authorize, as the policy decides whether senders
+// require TLS to deliver to the domain. Requests are rejected with 401
+// Unauthorized when Authorize is nil or returns false.
+//
+// GET returns the policy of the domain. PUT replaces its mode, mx and max_age
+// with the ones of the JSON body, and bumps its id when the policy changed, so
+// the _mta-sts TXT record can be updated with the returned txt_record.
+type MTASTSPolicyHandler struct {
+	Policies mailweave.MtaStsPolicies
+	Domains  mailweave.ManagedDomains
+	// Tells whether the request may read and change the policy of the
+	// domain, e.g. with BearerToken. Required.
+	Authorize func(r *http.Request, domain string) bool
+	// Returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// BearerToken returns an Authorize function accepting the requests whose
+// Authorization header is "Bearer <token>". An empty token accepts nothing.
+func BearerToken(token string) func(r *http.Request, domain string) bool {
+	return func(r *http.Request, domain string) bool {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		return ok && token != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *MTASTSPolicyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	domain := resolver.NormalizeDomain(r.PathValue("domain"))
+
+	if h.Authorize == nil || !h.Authorize(r, domain) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	owner, err := h.Domains.GetDomainOwner(r.Context(), domain)
+	if err != nil {
+		if errors.Is(err, mailweave.ErrDomainNotManaged) {
+			http.Error(w, fmt.Sprintf("domain %s is not managed", domain), http.StatusNotFound)
+			return
+		}
+
+		slog.ErrorContext(r.Context(), "failed to get domain owner", slog.String("domain", domain), slog.String("error", err.Error()))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	current, err := h.Policies.GetMtaStsPolicy(r.Context(), domain)
+	exists := err == nil
+	if err != nil && !errors.Is(err, mailweave.ErrNoMtaStsPolicy) {
+		slog.ErrorContext(r.Context(), "failed to get mta-sts policy", slog.String("domain", domain), slog.String("error", err.Error()))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if !exists {
+			http.Error(w, fmt.Sprintf("domain %s has no mta-sts policy", domain), http.StatusNotFound)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, newMtaStsPolicy(current))
+	case http.MethodPut:
+		var body mtaStsPolicy
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&body); err != nil {
+			http.Error(w, fmt.Sprintf("decoding body: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		lines := tlsrpt.STSPolicy{
+			Version: "STSv1",
+			Mode:    tlsrpt.STSMode(body.Mode),
+			MX:      body.MX,
+			MaxAge:  time.Duration(body.MaxAge) * time.Second,
+		}.Lines()
+		policy, err := tlsrpt.ParseSTSPolicy(lines)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if exists && current.STSPolicy().Equal(policy) {
+			writeJSON(w, http.StatusOK, newMtaStsPolicy(current))
+			return
+		}
+
+		now := time.Now
+		if h.Now != nil {
+			now = h.Now
+		}
+
+		updated := mailweave.MtaStsPolicy{
+			DomainOwner: owner,
+			Domain:      domain,
+			Mode:        policy.Mode,
+			MX:          policy.MX,
+			MaxAge:      policy.MaxAge,
+			Id:          mailweave.NextMtaStsPolicyId(current.Id, now()),
+			UpdatedAt:   now(),
+		}
+		if err := h.Policies.WriteMtaStsPolicy(r.Context(), domain, updated); err != nil {
+			slog.ErrorContext(r.Context(), "failed to write mta-sts policy", slog.String("domain", domain), slog.String("error", err.Error()))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		status := http.StatusOK
+		if !exists {
+			status = http.StatusCreated
+		}
+		writeJSON(w, status, newMtaStsPolicy(updated))
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func newMtaStsPolicy(policy mailweave.MtaStsPolicy) mtaStsPolicy {
+	return mtaStsPolicy{
+		Domain:    policy.Domain,
+		Mode:      string(policy.Mode),
+		MX:        policy.MX,
+		MaxAge:    int64(policy.MaxAge / time.Second),
+		Id:        policy.Id,
+		TXTRecord: policy.TXTRecord(),
+		UpdatedAt: policy.UpdatedAt,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
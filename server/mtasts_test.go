@@ -0,0 +1,204 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aldy505/mailweave"
+	"github.com/aldy505/mailweave/datastore"
+	"github.com/aldy505/mailweave/server"
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+func TestMTASTSHandler(t *testing.T) {
+	store := &datastore.FakeDatastore{
+		Domains: map[string]string{"example.com": "example.com", "example.org": "example.org"},
+		MtaStsPolicies: []mailweave.MtaStsPolicy{{
+			DomainOwner: "example.com",
+			Domain:      "example.com",
+			Mode:        tlsrpt.STSModeTesting,
+			MX:          []string{"mx1.example.com", "*.example.net"},
+			MaxAge:      24 * time.Hour,
+			Id:          "20250101000000",
+		}},
+	}
+	handler := &server.MTASTSHandler{Policies: store, Domains: store}
+
+	get := func(host string, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("policy", func(t *testing.T) {
+		rec := get("MTA-STS.example.com:443", server.MTASTSPolicyPath)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+
+		if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain") {
+			t.Errorf("Content-Type = %s, want text/plain", contentType)
+		}
+
+		want := "version: STSv1\r\nmode: testing\r\nmx: mx1.example.com\r\nmx: *.example.net\r\nmax_age: 86400\r\n"
+		if rec.Body.String() != want {
+			t.Errorf("body = %q, want %q", rec.Body.String(), want)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			host   string
+			target string
+		}{
+			{name: "no policy", host: "mta-sts.example.org", target: server.MTASTSPolicyPath},
+			{name: "unmanaged domain", host: "mta-sts.example.net", target: server.MTASTSPolicyPath},
+			{name: "not an mta-sts host", host: "example.com", target: server.MTASTSPolicyPath},
+			{name: "other path", host: "mta-sts.example.com", target: "/"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if rec := get(tt.host, tt.target); rec.Code != http.StatusNotFound {
+					t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+				}
+			})
+		}
+	})
+}
+
+func TestMTASTSPolicyHandler(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := &datastore.FakeDatastore{
+		Domains: map[string]string{"example.com": "example.com"},
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/mta-sts/{domain}", &server.MTASTSPolicyHandler{
+		Policies:  store,
+		Domains:   store,
+		Authorize: server.BearerToken("secret"),
+		Now:       func() time.Time { return now },
+	})
+
+	do := func(method string, domain string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/mta-sts/"+domain, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	decode := func(t *testing.T, rec *httptest.ResponseRecorder) map[string]any {
+		t.Helper()
+
+		var policy map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &policy); err != nil {
+			t.Fatal(err)
+		}
+		return policy
+	}
+
+	if rec := do(http.MethodGet, "example.com", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("status before PUT = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec := do(http.MethodPut, "example.com", `{"mode":"testing","mx":["mx1.example.com"],"max_age":604800}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if policy := decode(t, rec); policy["txt_record"] != "v=STSv1; id=20250601120000" {
+		t.Errorf("txt_record = %v, want v=STSv1; id=20250601120000", policy["txt_record"])
+	}
+	if store.MtaStsPolicies[0].DomainOwner != "example.com" {
+		t.Errorf("DomainOwner = %s, want example.com", store.MtaStsPolicies[0].DomainOwner)
+	}
+
+	t.Run("unchanged", func(t *testing.T) {
+		rec := do(http.MethodPut, "example.com", `{"mode":"testing","mx":["MX1.example.com"],"max_age":604800}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		if store.MtaStsPolicies[0].Id != "20250601120000" {
+			t.Errorf("Id = %s, want 20250601120000", store.MtaStsPolicies[0].Id)
+		}
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		rec := do(http.MethodPut, "example.com", `{"mode":"enforce","mx":["mx1.example.com"],"max_age":604800}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+
+		policy := decode(t, do(http.MethodGet, "example.com", ""))
+		if policy["mode"] != "enforce" {
+			t.Errorf("mode = %v, want enforce", policy["mode"])
+		}
+		if policy["id"] != "20250601120001" {
+			t.Errorf("id = %v, want 20250601120001", policy["id"])
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		tests := []struct {
+			name string
+			body string
+		}{
+			{name: "malformed", body: `{`},
+			{name: "invalid mode", body: `{"mode":"strict","mx":["mx1.example.com"],"max_age":604800}`},
+			{name: "no mx", body: `{"mode":"enforce","max_age":604800}`},
+			{name: "max_age too large", body: `{"mode":"enforce","mx":["mx1.example.com"],"max_age":99999999}`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if rec := do(http.MethodPut, "example.com", tt.body); rec.Code != http.StatusBadRequest {
+					t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+				}
+			})
+		}
+	})
+
+	t.Run("unmanaged domain", func(t *testing.T) {
+		rec := do(http.MethodPut, "example.net", `{"mode":"none","max_age":86400}`)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		for _, authorization := range []string{"", "Bearer wrong", "secret"} {
+			req := httptest.NewRequest(http.MethodPut, "/api/mta-sts/example.com", strings.NewReader(`{"mode":"none","mx":["mx1.example.com"],"max_age":86400}`))
+			if authorization != "" {
+				req.Header.Set("Authorization", authorization)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("status with %q = %d, want %d", authorization, rec.Code, http.StatusUnauthorized)
+			}
+		}
+		if store.MtaStsPolicies[0].Mode != "enforce" {
+			t.Errorf("Mode = %s, want enforce", store.MtaStsPolicies[0].Mode)
+		}
+	})
+
+	t.Run("no authorize", func(t *testing.T) {
+		handler := &server.MTASTSPolicyHandler{Policies: store, Domains: store}
+		req := httptest.NewRequest(http.MethodGet, "/api/mta-sts/example.com", nil)
+		req.SetPathValue("domain", "example.com")
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+// Package server provides the HTTP handlers of mailweave.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/aldy505/mailweave"
+	"github.com/aldy505/mailweave/resolver"
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+// TLSRPTHandler receives TLS-RPT reports submitted over HTTPS, as described in
+// RFC 8460 Section 3, so an "rua=https://..." destination can be published
+// in the TLS-RPT record of the managed domains.
+//
+// A report is accepted with 201 Created once stored. Following RFC 8460
+// Section 5.3, any other status code tells the sender the submission failed:
+//   - 405 Method Not Allowed for a method other than POST
+//   - 415 Unsupported Media Type for a Content-Type other than
+//     application/tlsrpt+json or application/tlsrpt+gzip
+//   - 413 Request Entity Too Large for a report exceeding the limits
+//   - 400 Bad Request for a report that cannot be parsed or is invalid
+//   - 403 Forbidden for a report about a domain that is not managed
+//   - 500 Internal Server Error when the report cannot be stored
+type TLSRPTHandler struct {
+	// Stores the accepted reports, under the owner of their policy domain.
+	Reports mailweave.TlsRptMonitoringReports
+	// Tells whether the policy domain of a report is managed, and its owner.
+	Domains mailweave.ManagedDomains
+	// The limits applied when parsing a report. Defaults to tlsrpt.DefaultLimits.
+	Limits tlsrpt.Limits
+}
+
+// ServeHTTP implements http.Handler.
+func (h *TLSRPTHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != tlsrpt.MediaTypeJSON && mediaType != tlsrpt.MediaTypeGZIP) {
+		http.Error(w, fmt.Sprintf("content type must be %s or %s", tlsrpt.MediaTypeJSON, tlsrpt.MediaTypeGZIP), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	compression, _ := tlsrpt.CompressionFromMediaType(mediaType)
+	report, err := tlsrpt.ParseReport(r.Body, compression, tlsrpt.WithLimits(h.Limits))
+	if err != nil {
+		if isLimitError(err) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		http.Error(w, fmt.Sprintf("parsing report: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := tlsrpt.Validate(report).Err(); err != nil {
+		http.Error(w, fmt.Sprintf("invalid report: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	policyDomain, err := reportPolicyDomain(report)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid report: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	owner, err := h.Domains.GetDomainOwner(r.Context(), policyDomain)
+	if err != nil {
+		if errors.Is(err, mailweave.ErrDomainNotManaged) {
+			http.Error(w, fmt.Sprintf("domain %s is not managed", policyDomain), http.StatusForbidden)
+			return
+		}
+
+		slog.ErrorContext(r.Context(), "failed to get domain owner", slog.String("domain", policyDomain), slog.String("error", err.Error()))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	content, err := json.Marshal(report)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to marshal report", slog.String("error", err.Error()))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	tlsRptReport := mailweave.NewTlsRptReport(report)
+	tlsRptReport.DomainOwner = owner
+	tlsRptReport.ReceivedAt = time.Now()
+	tlsRptReport.Content = string(content)
+
+	if err := h.Reports.WriteTlsRptReport(r.Context(), owner, tlsRptReport); err != nil {
+		slog.ErrorContext(r.Context(), "failed to write tls-rpt report", slog.String("domain", policyDomain), slog.String("error", err.Error()))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// reportPolicyDomain returns the policy domain of the report. A report only
+// covers a single policy domain, as per RFC 8460 Section 4.
+func reportPolicyDomain(report *tlsrpt.Report) (string, error) {
+	var policyDomain string
+	for _, policy := range report.Policies {
+		domain := resolver.NormalizeDomain(policy.Policy.PolicyDomain)
+		if policyDomain == "" {
+			policyDomain = domain
+			continue
+		}
+
+		if domain != policyDomain {
+			return "", fmt.Errorf("report covers more than one policy domain: %s and %s", policyDomain, domain)
+		}
+	}
+
+	return policyDomain, nil
+}
+
+func isLimitError(err error) bool {
+	return errors.Is(err, tlsrpt.ErrCompressedSizeExceeded) ||
+		errors.Is(err, tlsrpt.ErrDecompressedSizeExceeded) ||
+		errors.Is(err, tlsrpt.ErrCompressionRatioExceeded) ||
+		errors.Is(err, tlsrpt.ErrRecordsExceeded)
+}
@@ -0,0 +1,152 @@
+package server_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/aldy505/mailweave/datastore"
+	"github.com/aldy505/mailweave/server"
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+func TestTLSRPTHandler(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jsonReport, err := os.ReadFile(path.Join(pwd, "../testdata/tlsrpt/google.com!example.com!1747094400!1747180799!001.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gzipReport, err := os.ReadFile(path.Join(pwd, "../testdata/tlsrpt/google.com!example.com!1747094400!1747180799!001.json.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newHandler := func() (*server.TLSRPTHandler, *datastore.FakeDatastore) {
+		store := &datastore.FakeDatastore{
+			Domains: map[string]string{"example.com": "owner@example.com"},
+		}
+		return &server.TLSRPTHandler{Reports: store, Domains: store}, store
+	}
+
+	post := func(handler http.Handler, contentType string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/tlsrpt", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("json", func(t *testing.T) {
+		handler, store := newHandler()
+
+		rec := post(handler, tlsrpt.MediaTypeJSON, jsonReport)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+		}
+
+		if len(store.TlsRptReports) != 1 {
+			t.Fatalf("len(TlsRptReports) = %d, want 1", len(store.TlsRptReports))
+		}
+
+		report := store.TlsRptReports[0]
+		if report.DomainOwner != "owner@example.com" {
+			t.Errorf("DomainOwner = %s, want owner@example.com", report.DomainOwner)
+		}
+		if report.OrganizationName != "Google Inc." {
+			t.Errorf("OrganizationName = %s, want Google Inc.", report.OrganizationName)
+		}
+		if report.ReceivedAt.IsZero() {
+			t.Error("ReceivedAt is zero")
+		}
+		if report.Content == "" {
+			t.Error("Content is empty")
+		}
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		handler, store := newHandler()
+
+		rec := post(handler, tlsrpt.MediaTypeGZIP, gzipReport)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+		}
+
+		if len(store.TlsRptReports) != 1 {
+			t.Errorf("len(TlsRptReports) = %d, want 1", len(store.TlsRptReports))
+		}
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		handler, _ := newHandler()
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tlsrpt", nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+		}
+		if rec.Header().Get("Allow") != http.MethodPost {
+			t.Errorf("Allow = %s, want %s", rec.Header().Get("Allow"), http.MethodPost)
+		}
+	})
+
+	t.Run("unsupported media type", func(t *testing.T) {
+		handler, _ := newHandler()
+
+		rec := post(handler, "application/json", jsonReport)
+		if rec.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusUnsupportedMediaType)
+		}
+	})
+
+	t.Run("malformed report", func(t *testing.T) {
+		handler, store := newHandler()
+
+		rec := post(handler, tlsrpt.MediaTypeJSON, []byte(`{"organization-name":`))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+		if len(store.TlsRptReports) != 0 {
+			t.Errorf("len(TlsRptReports) = %d, want 0", len(store.TlsRptReports))
+		}
+	})
+
+	t.Run("invalid report", func(t *testing.T) {
+		handler, _ := newHandler()
+
+		rec := post(handler, tlsrpt.MediaTypeJSON, []byte(`{"organization-name":"Example","report-id":"1","policies":[]}`))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("unmanaged domain", func(t *testing.T) {
+		handler, store := newHandler()
+		store.Domains = map[string]string{"example.org": "owner@example.org"}
+
+		rec := post(handler, tlsrpt.MediaTypeJSON, jsonReport)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+		if len(store.TlsRptReports) != 0 {
+			t.Errorf("len(TlsRptReports) = %d, want 0", len(store.TlsRptReports))
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		handler, _ := newHandler()
+		handler.Limits = tlsrpt.Limits{MaxDecompressedSize: 16}
+
+		rec := post(handler, tlsrpt.MediaTypeJSON, jsonReport)
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+		}
+	})
+}
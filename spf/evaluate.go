@@ -0,0 +1,456 @@
+package spf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+var (
+	// ErrLookupLimitExceeded is returned when evaluating a record takes more
+	// than LookupLimit DNS lookups.
+	ErrLookupLimitExceeded = errors.New("dns lookup limit exceeded")
+	// ErrVoidLookupLimitExceeded is returned when evaluating a record takes
+	// more than VoidLookupLimit lookups that return no answer.
+	ErrVoidLookupLimitExceeded = errors.New("void dns lookup limit exceeded")
+	// ErrNoRecord is returned when a domain has no SPF record.
+	ErrNoRecord = errors.New("no spf record")
+	// ErrMultipleRecords is returned when a domain has more than one SPF record.
+	ErrMultipleRecords = errors.New("multiple spf records")
+)
+
+const (
+	// LookupLimit is the maximum number of mechanisms and modifiers that
+	// cause DNS lookups, as per RFC 7208 Section 4.6.4.
+	LookupLimit = 10
+	// VoidLookupLimit is the maximum number of DNS lookups that return no
+	// answer, as per RFC 7208 Section 4.6.4.
+	VoidLookupLimit = 2
+	// nameLimit is the maximum number of names looked up for a single mx or
+	// ptr mechanism.
+	nameLimit = 10
+)
+
+// Result is the result of an SPF evaluation, as defined in RFC 7208 Section 2.6.
+type Result string
+
+const (
+	ResultNone      Result = "none"
+	ResultNeutral   Result = "neutral"
+	ResultPass      Result = "pass"
+	ResultFail      Result = "fail"
+	ResultSoftFail  Result = "softfail"
+	ResultTempError Result = "temperror"
+	ResultPermError Result = "permerror"
+)
+
+// Resolver looks up DNS records. It is implemented by *net.Resolver.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+var _ Resolver = (*net.Resolver)(nil)
+
+// Node is an SPF record in the tree of the records reached through include
+// and redirect.
+type Node struct {
+	Domain string
+	// The term that led to this record, e.g. "include:_spf.example.net".
+	// Empty for the root.
+	Via string
+	// The SPF record of the domain, empty when there is none.
+	Record string
+	// The result of the record, only set by Evaluate.
+	Result Result
+	// The mechanism that matched, only set by Evaluate. Empty when no
+	// mechanism matched.
+	Match    string
+	Children []*Node
+	// Why the record could not be evaluated.
+	Err error
+}
+
+// String renders the tree, one record per line, indented by depth.
+func (n *Node) String() string {
+	var b strings.Builder
+	n.render(&b, 0)
+	return b.String()
+}
+
+func (n *Node) render(b *strings.Builder, depth int) {
+	b.WriteString(strings.Repeat("  ", depth))
+	if n.Via != "" {
+		b.WriteString(n.Via + " -> ")
+	}
+	b.WriteString(n.Domain)
+	if n.Record != "" {
+		b.WriteString(": " + n.Record)
+	}
+	if n.Result != "" {
+		b.WriteString(" => " + string(n.Result))
+		if n.Match != "" {
+			b.WriteString(" (" + n.Match + ")")
+		}
+	}
+	if n.Err != nil {
+		b.WriteString(" [" + n.Err.Error() + "]")
+	}
+	b.WriteString("\n")
+
+	for _, child := range n.Children {
+		child.render(b, depth+1)
+	}
+}
+
+// Evaluation is the outcome of Evaluate.
+type Evaluation struct {
+	Result Result
+	// The top-level mechanism that matched, empty when none did.
+	Match string
+	// The number of lookups counted toward LookupLimit and VoidLookupLimit.
+	Lookups     int
+	VoidLookups int
+	// The records that were evaluated.
+	Tree *Node
+	// Why the result is a temperror or permerror.
+	Err error
+}
+
+// Evaluate answers whether the IP address is allowed to send mail for the
+// domain, following the check_host() function of RFC 7208 Section 4. The
+// sender is the MAIL FROM address, and defaults to postmaster@domain. The
+// exp modifier is not evaluated.
+func Evaluate(ctx context.Context, resolver Resolver, ip netip.Addr, domain string, sender string) Evaluation {
+	domain = normalizeDomain(domain)
+	if sender == "" {
+		sender = "postmaster@" + domain
+	}
+
+	e := &evaluator{resolver: resolver, ip: ip.Unmap(), sender: sender}
+	tree := e.checkHost(ctx, domain, "")
+
+	return Evaluation{
+		Result:      tree.Result,
+		Match:       tree.Match,
+		Lookups:     e.lookups,
+		VoidLookups: e.voidLookups,
+		Tree:        tree,
+		Err:         tree.Err,
+	}
+}
+
+type evaluator struct {
+	resolver    Resolver
+	ip          netip.Addr
+	sender      string
+	lookups     int
+	voidLookups int
+}
+
+func (e *evaluator) checkHost(ctx context.Context, domain string, via string) *Node {
+	node := &Node{Domain: domain, Via: via}
+
+	value, err := lookupRecord(ctx, e.resolver, domain)
+	if err != nil {
+		node.Err = err
+		switch {
+		case errors.Is(err, ErrNoRecord):
+			node.Result = ResultNone
+		case errors.Is(err, ErrMultipleRecords):
+			node.Result = ResultPermError
+		default:
+			node.Result = ResultTempError
+		}
+		return node
+	}
+	node.Record = value
+
+	record, err := ParseRecord(value)
+	if err != nil {
+		node.Result, node.Err = ResultPermError, err
+		return node
+	}
+
+	macros := macroContext{sender: e.sender, domain: domain, ip: e.ip}
+
+	for _, mechanism := range record.Mechanisms {
+		if mechanism.countsLookup() {
+			if err := e.countLookup(); err != nil {
+				node.Result, node.Err = ResultPermError, err
+				return node
+			}
+		}
+
+		matched, result, err := e.match(ctx, node, mechanism, macros)
+		if err != nil {
+			node.Result, node.Err = result, err
+			return node
+		}
+
+		if matched {
+			node.Result, node.Match = mechanism.Qualifier.Result(), mechanism.String()
+			return node
+		}
+	}
+
+	if record.Redirect == "" {
+		node.Result = ResultNeutral
+		return node
+	}
+
+	if err := e.countLookup(); err != nil {
+		node.Result, node.Err = ResultPermError, err
+		return node
+	}
+
+	target, err := expand(record.Redirect, macros, true)
+	if err != nil {
+		node.Result, node.Err = ResultPermError, err
+		return node
+	}
+
+	child := e.checkHost(ctx, normalizeDomain(target), "redirect="+record.Redirect)
+	node.Children = append(node.Children, child)
+	node.Result, node.Match = child.Result, "redirect="+record.Redirect
+	if child.Result == ResultNone {
+		node.Result, node.Err = ResultPermError, fmt.Errorf("redirect to %s: %w", child.Domain, ErrNoRecord)
+	} else if child.Err != nil {
+		node.Err = fmt.Errorf("redirect to %s: %w", child.Domain, child.Err)
+	}
+
+	return node
+}
+
+// match reports whether the mechanism matches. On error, the result is the
+// result of the whole evaluation.
+func (e *evaluator) match(ctx context.Context, node *Node, mechanism Mechanism, macros macroContext) (bool, Result, error) {
+	target := macros.domain
+	if mechanism.Domain != "" {
+		expanded, err := expand(mechanism.Domain, macros, true)
+		if err != nil {
+			return false, ResultPermError, err
+		}
+		target = normalizeDomain(expanded)
+	}
+
+	switch mechanism.Name {
+	case "all":
+		return true, "", nil
+	case "ip4", "ip6":
+		return mechanism.Prefix.Contains(e.ip), "", nil
+	case "include":
+		child := e.checkHost(ctx, target, mechanism.String())
+		node.Children = append(node.Children, child)
+
+		switch child.Result {
+		case ResultPass:
+			return true, "", nil
+		case ResultTempError:
+			return false, ResultTempError, fmt.Errorf("include of %s: %w", target, child.Err)
+		case ResultPermError:
+			return false, ResultPermError, fmt.Errorf("include of %s: %w", target, child.Err)
+		case ResultNone:
+			return false, ResultPermError, fmt.Errorf("include of %s: %w", target, ErrNoRecord)
+		default:
+			return false, "", nil
+		}
+	case "a":
+		addrs, err := e.lookupIP(ctx, target, true)
+		if err != nil {
+			return false, resultOf(err), err
+		}
+		return e.containsIP(addrs, mechanism), "", nil
+	case "mx":
+		records, err := e.resolver.LookupMX(ctx, target)
+		if err != nil && !isNotFound(err) {
+			return false, ResultTempError, fmt.Errorf("looking up MX of %s: %w", target, err)
+		}
+		if len(records) == 0 {
+			if err := e.countVoidLookup(); err != nil {
+				return false, ResultPermError, err
+			}
+			return false, "", nil
+		}
+		if len(records) > nameLimit {
+			return false, ResultPermError, fmt.Errorf("%s has more than %d MX records", target, nameLimit)
+		}
+
+		for _, record := range records {
+			addrs, err := e.lookupIP(ctx, normalizeDomain(record.Host), false)
+			if err != nil {
+				return false, resultOf(err), err
+			}
+			if e.containsIP(addrs, mechanism) {
+				return true, "", nil
+			}
+		}
+		return false, "", nil
+	case "ptr":
+		return e.matchPTR(ctx, target)
+	case "exists":
+		addrs, err := e.lookupIP(ctx, target, true)
+		if err != nil {
+			return false, resultOf(err), err
+		}
+		for _, addr := range addrs {
+			if addr.Is4() {
+				return true, "", nil
+			}
+		}
+		return false, "", nil
+	default:
+		return false, ResultPermError, fmt.Errorf("%w: unknown mechanism %s", ErrInvalidRecord, mechanism.Name)
+	}
+}
+
+func (e *evaluator) matchPTR(ctx context.Context, target string) (bool, Result, error) {
+	names, err := e.resolver.LookupAddr(ctx, e.ip.String())
+	if err != nil && !isNotFound(err) {
+		// RFC 7208 Section 5.5: a failed PTR lookup is treated as no match.
+		return false, "", nil
+	}
+	if len(names) == 0 {
+		if err := e.countVoidLookup(); err != nil {
+			return false, ResultPermError, err
+		}
+		return false, "", nil
+	}
+	if len(names) > nameLimit {
+		names = names[:nameLimit]
+	}
+
+	for _, name := range names {
+		name = normalizeDomain(name)
+		if name != target && !strings.HasSuffix(name, "."+target) {
+			continue
+		}
+
+		addrs, err := e.resolver.LookupIPAddr(ctx, name)
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ip, ok := netip.AddrFromSlice(addr.IP); ok && ip.Unmap() == e.ip {
+				return true, "", nil
+			}
+		}
+	}
+
+	return false, "", nil
+}
+
+// lookupIP looks up the addresses of the host. When void is set, an empty
+// answer counts toward VoidLookupLimit.
+func (e *evaluator) lookupIP(ctx context.Context, host string, void bool) ([]netip.Addr, error) {
+	answers, err := e.resolver.LookupIPAddr(ctx, host)
+	if err != nil && !isNotFound(err) {
+		return nil, fmt.Errorf("looking up the addresses of %s: %w", host, err)
+	}
+
+	if len(answers) == 0 && void {
+		if err := e.countVoidLookup(); err != nil {
+			return nil, err
+		}
+	}
+
+	addrs := make([]netip.Addr, 0, len(answers))
+	for _, answer := range answers {
+		if addr, ok := netip.AddrFromSlice(answer.IP); ok {
+			addrs = append(addrs, addr.Unmap())
+		}
+	}
+
+	return addrs, nil
+}
+
+func (e *evaluator) containsIP(addrs []netip.Addr, mechanism Mechanism) bool {
+	for _, addr := range addrs {
+		if addr.Is4() != e.ip.Is4() {
+			continue
+		}
+
+		bits := addr.BitLen()
+		if addr.Is4() && mechanism.IP4CIDR >= 0 {
+			bits = mechanism.IP4CIDR
+		} else if addr.Is6() && mechanism.IP6CIDR >= 0 {
+			bits = mechanism.IP6CIDR
+		}
+
+		if prefix, err := addr.Prefix(bits); err == nil && prefix.Contains(e.ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *evaluator) countLookup() error {
+	e.lookups++
+	if e.lookups > LookupLimit {
+		return ErrLookupLimitExceeded
+	}
+
+	return nil
+}
+
+func (e *evaluator) countVoidLookup() error {
+	e.voidLookups++
+	if e.voidLookups > VoidLookupLimit {
+		return ErrVoidLookupLimitExceeded
+	}
+
+	return nil
+}
+
+// resultOf returns the result of a lookup error, a permerror when a limit is
+// exceeded and a temperror otherwise.
+func resultOf(err error) Result {
+	if errors.Is(err, ErrLookupLimitExceeded) || errors.Is(err, ErrVoidLookupLimitExceeded) {
+		return ResultPermError
+	}
+
+	return ResultTempError
+}
+
+// lookupRecord returns the SPF record of the domain.
+func lookupRecord(ctx context.Context, resolver Resolver, domain string) (string, error) {
+	values, err := resolver.LookupTXT(ctx, domain)
+	if err != nil {
+		if isNotFound(err) {
+			return "", ErrNoRecord
+		}
+
+		return "", fmt.Errorf("looking up TXT of %s: %w", domain, err)
+	}
+
+	var records []string
+	for _, value := range values {
+		if IsRecord(value) {
+			records = append(records, value)
+		}
+	}
+
+	switch len(records) {
+	case 0:
+		return "", ErrNoRecord
+	case 1:
+		return records[0], nil
+	default:
+		return "", fmt.Errorf("%w: %s has %d records", ErrMultipleRecords, domain, len(records))
+	}
+}
+
+func normalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
@@ -0,0 +1,153 @@
+package spf_test
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aldy505/mailweave/resolver"
+	"github.com/aldy505/mailweave/spf"
+)
+
+func newResolver() *resolver.FakeResolver {
+	fake := &resolver.FakeResolver{}
+	fake.SetTXT("example.com", "v=spf1 ip4:192.0.2.0/24 a:mail.example.com mx include:_spf.example.net -all")
+	fake.SetIP("mail.example.com", "198.51.100.10")
+	fake.SetMX("example.com")
+	fake.SetTXT("_spf.example.net", "v=spf1 ip6:2001:db8::/32 exists:%{ir}.allow.example.net ~all")
+	fake.SetIP("1.113.0.203.allow.example.net", "127.0.0.2")
+	fake.SetTXT("example.org", "v=spf1 redirect=example.com")
+	fake.SetTXT("ptr.example.com", "v=spf1 ptr -all")
+	fake.SetPTR("203.0.113.50", "host.ptr.example.com.")
+	fake.SetIP("host.ptr.example.com", "203.0.113.50")
+	return fake
+}
+
+func TestEvaluate(t *testing.T) {
+	fake := newResolver()
+
+	for _, tt := range []struct {
+		name   string
+		ip     string
+		domain string
+		want   spf.Result
+		match  string
+	}{
+		{name: "ip4", ip: "192.0.2.10", domain: "example.com", want: spf.ResultPass, match: "ip4:192.0.2.0/24"},
+		{name: "a", ip: "198.51.100.10", domain: "example.com", want: spf.ResultPass, match: "a:mail.example.com"},
+		{name: "include ip6", ip: "2001:db8::25", domain: "example.com", want: spf.ResultPass, match: "include:_spf.example.net"},
+		{name: "include exists macro", ip: "203.0.113.1", domain: "example.com", want: spf.ResultPass, match: "include:_spf.example.net"},
+		{name: "fail", ip: "203.0.113.2", domain: "example.com", want: spf.ResultFail, match: "-all"},
+		{name: "redirect", ip: "192.0.2.10", domain: "example.org", want: spf.ResultPass, match: "redirect=example.com"},
+		{name: "ptr", ip: "203.0.113.50", domain: "ptr.example.com", want: spf.ResultPass, match: "ptr"},
+		{name: "ptr mismatch", ip: "203.0.113.51", domain: "ptr.example.com", want: spf.ResultFail, match: "-all"},
+		{name: "none", ip: "192.0.2.10", domain: "example.edu", want: spf.ResultNone},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluation := spf.Evaluate(context.Background(), fake, netip.MustParseAddr(tt.ip), tt.domain, "")
+			if evaluation.Result != tt.want {
+				t.Errorf("Result = %s, want %s\n%s", evaluation.Result, tt.want, evaluation.Tree)
+			}
+			if evaluation.Match != tt.match {
+				t.Errorf("Match = %s, want %s", evaluation.Match, tt.match)
+			}
+		})
+	}
+
+	t.Run("tree", func(t *testing.T) {
+		evaluation := spf.Evaluate(context.Background(), fake, netip.MustParseAddr("203.0.113.2"), "example.com", "")
+		if evaluation.Lookups != 4 {
+			t.Errorf("Lookups = %d, want 4", evaluation.Lookups)
+		}
+		if evaluation.VoidLookups != 2 {
+			t.Errorf("VoidLookups = %d, want 2", evaluation.VoidLookups)
+		}
+		if len(evaluation.Tree.Children) != 1 || evaluation.Tree.Children[0].Result != spf.ResultSoftFail {
+			t.Errorf("Tree = %s, want an include that soft fails", evaluation.Tree)
+		}
+		if !strings.Contains(evaluation.Tree.String(), "  include:_spf.example.net -> _spf.example.net") {
+			t.Errorf("Tree.String() = %s, want an indented include", evaluation.Tree)
+		}
+	})
+
+	t.Run("lookup limit", func(t *testing.T) {
+		fake := &resolver.FakeResolver{}
+		var includes []string
+		for i := range 11 {
+			name := "_spf" + strconv.Itoa(i) + ".example.com"
+			includes = append(includes, "include:"+name)
+			fake.SetTXT(name, "v=spf1 ?all")
+		}
+		fake.SetTXT("example.com", "v=spf1 "+strings.Join(includes, " ")+" -all")
+
+		evaluation := spf.Evaluate(context.Background(), fake, netip.MustParseAddr("192.0.2.1"), "example.com", "")
+		if evaluation.Result != spf.ResultPermError {
+			t.Errorf("Result = %s, want permerror", evaluation.Result)
+		}
+		if !errors.Is(evaluation.Err, spf.ErrLookupLimitExceeded) {
+			t.Errorf("Err = %v, want ErrLookupLimitExceeded", evaluation.Err)
+		}
+	})
+
+	t.Run("void lookup limit", func(t *testing.T) {
+		fake := &resolver.FakeResolver{}
+		fake.SetTXT("example.com", "v=spf1 a:a.example.com a:b.example.com a:c.example.com -all")
+
+		evaluation := spf.Evaluate(context.Background(), fake, netip.MustParseAddr("192.0.2.1"), "example.com", "")
+		if !errors.Is(evaluation.Err, spf.ErrVoidLookupLimitExceeded) {
+			t.Errorf("Err = %v, want ErrVoidLookupLimitExceeded", evaluation.Err)
+		}
+	})
+
+	t.Run("multiple records", func(t *testing.T) {
+		fake := &resolver.FakeResolver{}
+		fake.SetTXT("example.com", "v=spf1 -all", "v=spf1 +all")
+
+		evaluation := spf.Evaluate(context.Background(), fake, netip.MustParseAddr("192.0.2.1"), "example.com", "")
+		if evaluation.Result != spf.ResultPermError {
+			t.Errorf("Result = %s, want permerror", evaluation.Result)
+		}
+	})
+
+	t.Run("temperror", func(t *testing.T) {
+		fake := &resolver.FakeResolver{Errors: map[string]error{"example.com": errors.New("timeout")}}
+
+		evaluation := spf.Evaluate(context.Background(), fake, netip.MustParseAddr("192.0.2.1"), "example.com", "")
+		if evaluation.Result != spf.ResultTempError {
+			t.Errorf("Result = %s, want temperror", evaluation.Result)
+		}
+	})
+}
+
+func TestExpand(t *testing.T) {
+	fake := newResolver()
+	fake.SetTXT("loop.example.com", "v=spf1 include:loop.example.com -all")
+
+	t.Run("tree", func(t *testing.T) {
+		expansion := spf.Expand(context.Background(), fake, "example.org")
+		if expansion.Lookups != 5 {
+			t.Errorf("Lookups = %d, want 5\n%s", expansion.Lookups, expansion.Tree)
+		}
+		if expansion.Exceeded() != nil {
+			t.Errorf("Exceeded() = %v, want nil", expansion.Exceeded())
+		}
+
+		tree := expansion.Tree
+		if len(tree.Children) != 1 || tree.Children[0].Domain != "example.com" {
+			t.Fatalf("Tree = %s, want a redirect to example.com", tree)
+		}
+		if len(tree.Children[0].Children) != 1 || tree.Children[0].Children[0].Domain != "_spf.example.net" {
+			t.Errorf("Tree = %s, want an include of _spf.example.net", tree)
+		}
+	})
+
+	t.Run("loop", func(t *testing.T) {
+		expansion := spf.Expand(context.Background(), fake, "loop.example.com")
+		if len(expansion.Tree.Children) != 1 || expansion.Tree.Children[0].Err == nil {
+			t.Errorf("Tree = %s, want an error on the loop", expansion.Tree)
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package spf
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// macroContext holds the values macros expand to, as defined in RFC 7208
+// Section 7.3.
+type macroContext struct {
+	sender string
+	domain string
+	ip     netip.Addr
+	helo   string
+}
+
+// expand expands the macros of a domain-spec. When evaluate is false, only the
+// syntax is checked.
+func expand(spec string, ctx macroContext, evaluate bool) (string, error) {
+	if !strings.Contains(spec, "%") {
+		return spec, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(spec); i++ {
+		if spec[i] != '%' {
+			b.WriteByte(spec[i])
+			continue
+		}
+
+		if i+1 >= len(spec) {
+			return "", fmt.Errorf("%w: trailing %% in %q", ErrInvalidRecord, spec)
+		}
+
+		i++
+		switch spec[i] {
+		case '%':
+			b.WriteByte('%')
+		case '_':
+			b.WriteByte(' ')
+		case '-':
+			b.WriteString("%20")
+		case '{':
+			end := strings.IndexByte(spec[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("%w: unterminated macro in %q", ErrInvalidRecord, spec)
+			}
+
+			value, err := expandMacro(spec[i+1:i+end], ctx, evaluate)
+			if err != nil {
+				return "", fmt.Errorf("%w in %q", err, spec)
+			}
+			b.WriteString(value)
+			i += end
+		default:
+			return "", fmt.Errorf("%w: invalid macro %%%c in %q", ErrInvalidRecord, spec[i], spec)
+		}
+	}
+
+	return b.String(), nil
+}
+
+// expandMacro expands the inside of a "%{...}" macro, e.g. "ir" or "d2".
+func expandMacro(macro string, ctx macroContext, evaluate bool) (string, error) {
+	if macro == "" {
+		return "", fmt.Errorf("%w: empty macro", ErrInvalidRecord)
+	}
+
+	letter := macro[0]
+	rest := macro[1:]
+
+	digits := 0
+	for digits < len(rest) && rest[digits] >= '0' && rest[digits] <= '9' {
+		digits++
+	}
+
+	keep := 0
+	if digits > 0 {
+		parsed, err := strconv.Atoi(rest[:digits])
+		if err != nil || parsed == 0 {
+			return "", fmt.Errorf("%w: invalid transformer in macro %q", ErrInvalidRecord, macro)
+		}
+		keep = parsed
+	}
+	rest = rest[digits:]
+
+	reverse := false
+	if strings.HasPrefix(rest, "r") || strings.HasPrefix(rest, "R") {
+		reverse = true
+		rest = rest[1:]
+	}
+
+	delimiters := rest
+	if strings.Trim(delimiters, ".-+,/_=") != "" {
+		return "", fmt.Errorf("%w: invalid delimiter in macro %q", ErrInvalidRecord, macro)
+	}
+	if delimiters == "" {
+		delimiters = "."
+	}
+
+	var value string
+	switch letter | 0x20 {
+	case 's':
+		value = ctx.sender
+	case 'l':
+		value, _, _ = strings.Cut(ctx.sender, "@")
+		if value == "" {
+			value = "postmaster"
+		}
+	case 'o':
+		_, value, _ = strings.Cut(ctx.sender, "@")
+	case 'd':
+		value = ctx.domain
+	case 'i':
+		value = dottedIP(ctx.ip)
+	case 'p':
+		value = "unknown"
+	case 'v':
+		value = "in-addr"
+		if ctx.ip.Is6() {
+			value = "ip6"
+		}
+	case 'h':
+		value = ctx.helo
+	default:
+		return "", fmt.Errorf("%w: invalid macro letter %q", ErrInvalidRecord, string(letter))
+	}
+
+	if !evaluate {
+		return "", nil
+	}
+
+	parts := strings.FieldsFunc(value, func(r rune) bool { return strings.ContainsRune(delimiters, r) })
+	if reverse {
+		slices.Reverse(parts)
+	}
+	if keep > 0 && keep < len(parts) {
+		parts = parts[len(parts)-keep:]
+	}
+	value = strings.Join(parts, ".")
+
+	if letter >= 'A' && letter <= 'Z' {
+		value = url.PathEscape(value)
+	}
+
+	return value, nil
+}
+
+// dottedIP formats the address as in the "i" macro: dotted quads for IPv4, and
+// dot-separated nibbles for IPv6.
+func dottedIP(ip netip.Addr) string {
+	if !ip.IsValid() {
+		return ""
+	}
+	if ip.Is4() {
+		return ip.String()
+	}
+
+	const hex = "0123456789abcdef"
+	bytes := ip.As16()
+	nibbles := make([]string, 0, 32)
+	for _, b := range bytes {
+		nibbles = append(nibbles, string(hex[b>>4]), string(hex[b&0x0f]))
+	}
+
+	return strings.Join(nibbles, ".")
+}
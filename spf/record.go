@@ -0,0 +1,297 @@
+// Package spf parses and evaluates Sender Policy Framework records, as
+// defined in RFC 7208, to explain why a source passes or fails SPF.
+package spf
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidRecord is returned when an SPF record cannot be parsed.
+var ErrInvalidRecord = errors.New("invalid spf record")
+
+// Qualifier is the qualifier of a mechanism, telling the result when it matches.
+type Qualifier byte
+
+const (
+	QualifierPass     Qualifier = '+'
+	QualifierFail     Qualifier = '-'
+	QualifierSoftFail Qualifier = '~'
+	QualifierNeutral  Qualifier = '?'
+)
+
+// Result returns the result of a matching mechanism with the qualifier.
+func (q Qualifier) Result() Result {
+	switch q {
+	case QualifierFail:
+		return ResultFail
+	case QualifierSoftFail:
+		return ResultSoftFail
+	case QualifierNeutral:
+		return ResultNeutral
+	default:
+		return ResultPass
+	}
+}
+
+// Mechanism is a single mechanism of an SPF record, e.g. "-ip4:192.0.2.0/24".
+type Mechanism struct {
+	Qualifier Qualifier
+	// The name of the mechanism, one of "all", "include", "a", "mx", "ptr",
+	// "ip4", "ip6" and "exists".
+	Name string
+	// The domain-spec of include, a, mx, ptr and exists, which may contain
+	// macros. Empty for a, mx and ptr means the current domain.
+	Domain string
+	// The network of ip4 and ip6.
+	Prefix netip.Prefix
+	// The CIDR lengths of a and mx, -1 when not set.
+	IP4CIDR int
+	IP6CIDR int
+}
+
+// String formats the mechanism as in the record, leaving out the "+" qualifier.
+func (m Mechanism) String() string {
+	var b strings.Builder
+	if m.Qualifier != QualifierPass {
+		b.WriteByte(byte(m.Qualifier))
+	}
+	b.WriteString(m.Name)
+
+	switch m.Name {
+	case "ip4", "ip6":
+		b.WriteString(":")
+		if m.Prefix.IsSingleIP() {
+			b.WriteString(m.Prefix.Addr().String())
+		} else {
+			b.WriteString(m.Prefix.String())
+		}
+	default:
+		if m.Domain != "" {
+			b.WriteString(":" + m.Domain)
+		}
+		if m.IP4CIDR >= 0 {
+			b.WriteString("/" + strconv.Itoa(m.IP4CIDR))
+		}
+		if m.IP6CIDR >= 0 {
+			b.WriteString("//" + strconv.Itoa(m.IP6CIDR))
+		}
+	}
+
+	return b.String()
+}
+
+// countsLookup reports whether the mechanism counts toward the DNS lookup
+// limit of RFC 7208 Section 4.6.4.
+func (m Mechanism) countsLookup() bool {
+	switch m.Name {
+	case "include", "a", "mx", "ptr", "exists":
+		return true
+	default:
+		return false
+	}
+}
+
+// Record is a parsed SPF record.
+type Record struct {
+	Mechanisms []Mechanism
+	// The domain-spec of the redirect modifier, empty when not set.
+	Redirect string
+	// The domain-spec of the exp modifier, empty when not set.
+	Explanation string
+}
+
+// IsRecord reports whether the TXT record is an SPF record, i.e. starts with
+// "v=spf1" followed by a space or nothing.
+func IsRecord(value string) bool {
+	value = strings.ToLower(value)
+	return value == "v=spf1" || strings.HasPrefix(value, "v=spf1 ")
+}
+
+// ParseRecord parses the value of an SPF TXT record, e.g.
+// "v=spf1 include:_spf.example.net -all". Unknown modifiers are ignored, as
+// required by RFC 7208 Section 6.
+func ParseRecord(value string) (Record, error) {
+	if !IsRecord(value) {
+		return Record{}, fmt.Errorf("%w: %q does not start with v=spf1", ErrInvalidRecord, value)
+	}
+
+	var record Record
+	var hasRedirect, hasExplanation bool
+
+	for _, term := range strings.Fields(value)[1:] {
+		if name, spec, ok := strings.Cut(term, "="); ok && isModifierName(name) {
+			switch strings.ToLower(name) {
+			case "redirect":
+				if hasRedirect {
+					return Record{}, fmt.Errorf("%w: redirect appears more than once", ErrInvalidRecord)
+				}
+				if err := validateDomainSpec(spec); err != nil {
+					return Record{}, err
+				}
+				record.Redirect, hasRedirect = spec, true
+			case "exp":
+				if hasExplanation {
+					return Record{}, fmt.Errorf("%w: exp appears more than once", ErrInvalidRecord)
+				}
+				if err := validateDomainSpec(spec); err != nil {
+					return Record{}, err
+				}
+				record.Explanation, hasExplanation = spec, true
+			}
+			continue
+		}
+
+		mechanism, err := parseMechanism(term)
+		if err != nil {
+			return Record{}, err
+		}
+
+		record.Mechanisms = append(record.Mechanisms, mechanism)
+	}
+
+	return record, nil
+}
+
+func isModifierName(name string) bool {
+	if name == "" || !isAlpha(name[0]) {
+		return false
+	}
+
+	for i := 1; i < len(name); i++ {
+		c := name[i]
+		if !isAlpha(c) && !(c >= '0' && c <= '9') && c != '-' && c != '_' && c != '.' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func parseMechanism(term string) (Mechanism, error) {
+	mechanism := Mechanism{Qualifier: QualifierPass, IP4CIDR: -1, IP6CIDR: -1}
+
+	switch term[0] {
+	case '+', '-', '~', '?':
+		mechanism.Qualifier = Qualifier(term[0])
+		term = term[1:]
+	}
+
+	name, value, hasValue := strings.Cut(term, ":")
+	if !hasValue {
+		if index := strings.Index(name, "/"); index >= 0 {
+			name, value = name[:index], name[index:]
+		}
+	}
+	mechanism.Name = strings.ToLower(name)
+
+	switch mechanism.Name {
+	case "all":
+		if hasValue || value != "" {
+			return Mechanism{}, fmt.Errorf("%w: all takes no argument in %q", ErrInvalidRecord, term)
+		}
+	case "include", "exists":
+		if !hasValue || value == "" {
+			return Mechanism{}, fmt.Errorf("%w: %s requires a domain in %q", ErrInvalidRecord, mechanism.Name, term)
+		}
+		if err := validateDomainSpec(value); err != nil {
+			return Mechanism{}, err
+		}
+		mechanism.Domain = value
+	case "a", "mx":
+		domain, err := parseDualCIDR(&mechanism, value)
+		if err != nil {
+			return Mechanism{}, fmt.Errorf("%w in %q", err, term)
+		}
+		if hasValue && domain == "" {
+			return Mechanism{}, fmt.Errorf("%w: empty domain in %q", ErrInvalidRecord, term)
+		}
+		if err := validateDomainSpec(domain); err != nil {
+			return Mechanism{}, err
+		}
+		mechanism.Domain = domain
+	case "ptr":
+		if hasValue && value == "" {
+			return Mechanism{}, fmt.Errorf("%w: empty domain in %q", ErrInvalidRecord, term)
+		}
+		if err := validateDomainSpec(value); err != nil {
+			return Mechanism{}, err
+		}
+		mechanism.Domain = value
+	case "ip4", "ip6":
+		if !hasValue {
+			return Mechanism{}, fmt.Errorf("%w: %s requires an address in %q", ErrInvalidRecord, mechanism.Name, term)
+		}
+
+		prefix, err := parsePrefix(value, mechanism.Name == "ip4")
+		if err != nil {
+			return Mechanism{}, fmt.Errorf("%w: invalid network in %q", ErrInvalidRecord, term)
+		}
+		mechanism.Prefix = prefix
+	default:
+		return Mechanism{}, fmt.Errorf("%w: unknown mechanism %q", ErrInvalidRecord, term)
+	}
+
+	return mechanism, nil
+}
+
+// parseDualCIDR parses the "domain/4//6" value of a and mx, returning the domain.
+func parseDualCIDR(mechanism *Mechanism, value string) (string, error) {
+	domain, ip6, hasIP6 := strings.Cut(value, "//")
+	if hasIP6 {
+		length, err := strconv.Atoi(ip6)
+		if err != nil || length < 0 || length > 128 {
+			return "", fmt.Errorf("%w: invalid ip6-cidr-length %q", ErrInvalidRecord, ip6)
+		}
+		mechanism.IP6CIDR = length
+	}
+
+	if index := strings.LastIndex(domain, "/"); index >= 0 {
+		length, err := strconv.Atoi(domain[index+1:])
+		if err != nil || length < 0 || length > 32 {
+			return "", fmt.Errorf("%w: invalid ip4-cidr-length %q", ErrInvalidRecord, domain[index+1:])
+		}
+		mechanism.IP4CIDR = length
+		domain = domain[:index]
+	}
+
+	return domain, nil
+}
+
+func parsePrefix(value string, ip4 bool) (netip.Prefix, error) {
+	var prefix netip.Prefix
+	if strings.Contains(value, "/") {
+		parsed, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		prefix = parsed.Masked()
+	} else {
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		prefix = netip.PrefixFrom(addr, addr.BitLen())
+	}
+
+	if prefix.Addr().Is4() != ip4 {
+		return netip.Prefix{}, fmt.Errorf("address family mismatch")
+	}
+
+	return prefix, nil
+}
+
+func validateDomainSpec(spec string) error {
+	if _, err := expand(spec, macroContext{}, false); err != nil {
+		return err
+	}
+
+	return nil
+}
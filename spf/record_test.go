@@ -0,0 +1,62 @@
+package spf_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aldy505/mailweave/spf"
+)
+
+func TestParseRecord(t *testing.T) {
+	t.Run("mechanisms and modifiers", func(t *testing.T) {
+		record, err := spf.ParseRecord("v=spf1 ip4:192.0.2.0/24 ip6:2001:db8::1 a mx:mail.example.com/24//64 ~include:_spf.example.net ?exists:%{ir}.%{l1r+-}._spf.%{d} ptr -all redirect=_spf.example.org exp=explain.%{d} unknown=ignored")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{
+			"ip4:192.0.2.0/24",
+			"ip6:2001:db8::1",
+			"a",
+			"mx:mail.example.com/24//64",
+			"~include:_spf.example.net",
+			"?exists:%{ir}.%{l1r+-}._spf.%{d}",
+			"ptr",
+			"-all",
+		}
+		if len(record.Mechanisms) != len(want) {
+			t.Fatalf("len(Mechanisms) = %d, want %d", len(record.Mechanisms), len(want))
+		}
+		for i, mechanism := range record.Mechanisms {
+			if mechanism.String() != want[i] {
+				t.Errorf("Mechanisms[%d] = %s, want %s", i, mechanism.String(), want[i])
+			}
+		}
+
+		if record.Redirect != "_spf.example.org" {
+			t.Errorf("Redirect = %s, want _spf.example.org", record.Redirect)
+		}
+		if record.Explanation != "explain.%{d}" {
+			t.Errorf("Explanation = %s, want explain.%%{d}", record.Explanation)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, value := range []string{
+			"v=spf2 -all",
+			"v=spf1 ip4:2001:db8::1",
+			"v=spf1 ip4:192.0.2.300",
+			"v=spf1 include",
+			"v=spf1 a:example.com/33",
+			"v=spf1 foo:example.com",
+			"v=spf1 include:%{x}.example.com",
+			"v=spf1 redirect=a.example.com redirect=b.example.com",
+			"v=spf1 all:example.com",
+		} {
+			_, err := spf.ParseRecord(value)
+			if !errors.Is(err, spf.ErrInvalidRecord) {
+				t.Errorf("ParseRecord(%q) error = %v, want ErrInvalidRecord", value, err)
+			}
+		}
+	})
+}
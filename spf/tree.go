@@ -0,0 +1,95 @@
+package spf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Expansion is the outcome of Expand.
+type Expansion struct {
+	// The records reached through include and redirect.
+	Tree *Node
+	// The number of mechanisms and modifiers causing DNS lookups in the whole
+	// tree, which receivers reject with a permerror beyond LookupLimit.
+	Lookups int
+	// The number of include and redirect targets without an SPF record.
+	VoidLookups int
+}
+
+// Exceeded returns ErrLookupLimitExceeded or ErrVoidLookupLimitExceeded when
+// the tree needs more lookups than receivers allow, and nil otherwise.
+func (e Expansion) Exceeded() error {
+	var errs []error
+	if e.Lookups > LookupLimit {
+		errs = append(errs, fmt.Errorf("%w: %d lookups, limit is %d", ErrLookupLimitExceeded, e.Lookups, LookupLimit))
+	}
+	if e.VoidLookups > VoidLookupLimit {
+		errs = append(errs, fmt.Errorf("%w: %d void lookups, limit is %d", ErrVoidLookupLimitExceeded, e.VoidLookups, VoidLookupLimit))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Expand builds the include tree of the domain, following every include and
+// redirect regardless of any IP address, and counts the DNS lookups the whole
+// tree costs. Unlike Evaluate, it does not stop at the limits, so the total can
+// be shown, and it does not look up the a, mx, ptr and exists mechanisms.
+// Targets containing macros are not followed, as they depend on the message.
+func Expand(ctx context.Context, resolver Resolver, domain string) Expansion {
+	var expansion Expansion
+	expansion.Tree = expandNode(ctx, resolver, normalizeDomain(domain), "", &expansion, map[string]bool{})
+	return expansion
+}
+
+func expandNode(ctx context.Context, resolver Resolver, domain string, via string, expansion *Expansion, visiting map[string]bool) *Node {
+	node := &Node{Domain: domain, Via: via}
+
+	if visiting[domain] {
+		node.Err = fmt.Errorf("%w: %s includes itself", ErrInvalidRecord, domain)
+		return node
+	}
+	visiting[domain] = true
+	defer delete(visiting, domain)
+
+	value, err := lookupRecord(ctx, resolver, domain)
+	if err != nil {
+		if errors.Is(err, ErrNoRecord) && via != "" {
+			expansion.VoidLookups++
+		}
+		node.Err = err
+		return node
+	}
+	node.Record = value
+
+	record, err := ParseRecord(value)
+	if err != nil {
+		node.Err = err
+		return node
+	}
+
+	for _, mechanism := range record.Mechanisms {
+		if !mechanism.countsLookup() {
+			continue
+		}
+		expansion.Lookups++
+
+		if mechanism.Name == "include" && !hasMacro(mechanism.Domain) {
+			node.Children = append(node.Children, expandNode(ctx, resolver, normalizeDomain(mechanism.Domain), mechanism.String(), expansion, visiting))
+		}
+	}
+
+	if record.Redirect != "" {
+		expansion.Lookups++
+		if !hasMacro(record.Redirect) {
+			node.Children = append(node.Children, expandNode(ctx, resolver, normalizeDomain(record.Redirect), "redirect="+record.Redirect, expansion, visiting))
+		}
+	}
+
+	return node
+}
+
+func hasMacro(spec string) bool {
+	return strings.Contains(spec, "%")
+}
@@ -0,0 +1,236 @@
+package tlsrpt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aldy505/mailweave/reportname"
+)
+
+// Session is the outcome of a single outbound SMTP session, as fed to a Builder.
+type Session struct {
+	// When the session took place.
+	Time time.Time
+	// The policy applied to the session. Use the "no-policy-found" policy type
+	// when the destination has neither MTA-STS nor DANE.
+	Policy Policy
+	// The result of the failed session, nil when the session succeeded.
+	ResultType            *ResultType
+	SendingMTAIP          string
+	ReceivingMxHostname   string
+	ReceivingMxHelo       string
+	ReceivingIP           string
+	AdditionalInformation string
+	FailureReasonCode     string
+}
+
+// Builder accumulates the outcomes of outbound SMTP sessions and emits one
+// TLS-RPT report per UTC day and policy domain, as RFC 8460 Section 4.1
+// expects reports to cover full UTC days. It is safe for concurrent use.
+type Builder struct {
+	// The name of the organization generating the reports.
+	OrganizationName string
+	// The email address reporters can be contacted at.
+	ContactInfo string
+
+	mu sync.Mutex
+	// the key is the UTC day and the policy domain
+	buckets map[bucketKey]*bucket
+}
+
+type bucketKey struct {
+	day    time.Time
+	domain string
+}
+
+type bucket struct {
+	policies map[string]*TLSPolicy
+	order    []string
+	// the index of the failure details in their policy
+	details map[string]map[string]int
+}
+
+// Add accumulates a single session outcome.
+func (b *Builder) Add(session Session) {
+	key := bucketKey{
+		day:    session.Time.UTC().Truncate(24 * time.Hour),
+		domain: strings.ToLower(session.Policy.PolicyDomain),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.buckets == nil {
+		b.buckets = make(map[bucketKey]*bucket)
+	}
+
+	bk, ok := b.buckets[key]
+	if !ok {
+		bk = &bucket{
+			policies: make(map[string]*TLSPolicy),
+			details:  make(map[string]map[string]int),
+		}
+		b.buckets[key] = bk
+	}
+
+	policyKey := policyKey(session.Policy)
+	policy, ok := bk.policies[policyKey]
+	if !ok {
+		policy = &TLSPolicy{Policy: session.Policy}
+		bk.policies[policyKey] = policy
+		bk.details[policyKey] = make(map[string]int)
+		bk.order = append(bk.order, policyKey)
+	}
+
+	if session.ResultType == nil {
+		policy.Summary.TotalSuccessfulSessionCount++
+		return
+	}
+
+	policy.Summary.TotalFailureSessionCount++
+
+	detail := FailureDetail{
+		ResultType:            session.ResultType,
+		SendingMTAIP:          session.SendingMTAIP,
+		ReceivingMxHostname:   session.ReceivingMxHostname,
+		ReceivingMxHelo:       session.ReceivingMxHelo,
+		ReceivingIP:           session.ReceivingIP,
+		AdditionalInformation: session.AdditionalInformation,
+		FailureReasonCode:     session.FailureReasonCode,
+	}
+
+	detailKey := failureDetailKey(detail)
+	if index, ok := bk.details[policyKey][detailKey]; ok {
+		policy.FailureDetails[index].FailedSessionCount++
+		return
+	}
+
+	detail.FailedSessionCount = 1
+	bk.details[policyKey][detailKey] = len(policy.FailureDetails)
+	policy.FailureDetails = append(policy.FailureDetails, detail)
+}
+
+// Flush returns the reports of the UTC days that ended at or before the given
+// time, sorted by day and policy domain, and removes them from the builder.
+// Pass a time far in the future to flush every day.
+func (b *Builder) Flush(before time.Time) []*Report {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var keys []bucketKey
+	for key := range b.buckets {
+		if !key.day.Add(24 * time.Hour).After(before) {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.SortFunc(keys, func(a, b bucketKey) int {
+		if c := a.day.Compare(b.day); c != 0 {
+			return c
+		}
+		return strings.Compare(a.domain, b.domain)
+	})
+
+	reports := make([]*Report, 0, len(keys))
+	for _, key := range keys {
+		bk := b.buckets[key]
+		delete(b.buckets, key)
+
+		report := &Report{
+			OrganizationName: b.OrganizationName,
+			DateRange: DateRange{
+				StartDateTime: key.day,
+				EndDateTime:   key.day.Add(24*time.Hour - time.Second),
+			},
+			ContactInfo: b.ContactInfo,
+			ReportID:    key.day.Format(time.RFC3339) + "_" + key.domain,
+			Policies:    make([]TLSPolicy, 0, len(bk.order)),
+		}
+
+		for _, policyKey := range bk.order {
+			report.Policies = append(report.Policies, *bk.policies[policyKey])
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports
+}
+
+func policyKey(policy Policy) string {
+	var b strings.Builder
+	b.WriteString(strconv.Quote(policy.PolicyType))
+	b.WriteString(strconv.Quote(strings.ToLower(policy.PolicyDomain)))
+	for _, line := range policy.PolicyString {
+		b.WriteString(strconv.Quote(line))
+	}
+	b.WriteString("mx")
+	for _, mx := range policy.MxHost {
+		b.WriteString(strconv.Quote(mx))
+	}
+
+	return b.String()
+}
+
+func failureDetailKey(detail FailureDetail) string {
+	var b strings.Builder
+	for _, field := range []string{
+		detail.ResultType.Key(),
+		detail.SendingMTAIP,
+		strings.ToLower(detail.ReceivingMxHostname),
+		detail.ReceivingMxHelo,
+		detail.ReceivingIP,
+		detail.AdditionalInformation,
+		detail.FailureReasonCode,
+	} {
+		b.WriteString(strconv.Quote(field))
+	}
+
+	return b.String()
+}
+
+// PackageReport encodes the report as gzip compressed JSON, returning the
+// report filename and content. The filename follows RFC 8460 Section 5.1,
+// with the given sender domain as the receiver part and the domain of the
+// first policy as the policy domain. The content is meant to be delivered with
+// the MediaTypeGZIP media type.
+func PackageReport(sender string, report *Report) (string, []byte, error) {
+	if report == nil {
+		return "", nil, fmt.Errorf("report is nil")
+	}
+	if len(report.Policies) == 0 {
+		return "", nil, fmt.Errorf("report has no policies")
+	}
+
+	content, err := json.Marshal(report)
+	if err != nil {
+		return "", nil, fmt.Errorf("marshaling report: %w", err)
+	}
+
+	name := reportname.Name{
+		Receiver:     sender,
+		PolicyDomain: report.Policies[0].Policy.PolicyDomain,
+		Begin:        report.DateRange.StartDateTime,
+		End:          report.DateRange.EndDateTime,
+		Extension:    "json.gz",
+	}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Name = strings.TrimSuffix(name.String(), ".gz")
+	if _, err := w.Write(content); err != nil {
+		return "", nil, fmt.Errorf("compressing gzip: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", nil, fmt.Errorf("compressing gzip: %w", err)
+	}
+
+	return name.String(), buf.Bytes(), nil
+}
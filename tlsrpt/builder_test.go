@@ -0,0 +1,121 @@
+package tlsrpt_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aldy505/mailweave/reportname"
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+func TestBuilder(t *testing.T) {
+	policy := tlsrpt.Policy{
+		PolicyType:   "sts",
+		PolicyString: []string{"version: STSv1", "mode: enforce", "mx: mx.example.com", "max_age: 86400"},
+		PolicyDomain: "example.com",
+		MxHost:       []string{"mx.example.com"},
+	}
+	day := time.Date(2025, time.May, 13, 0, 0, 0, 0, time.UTC)
+
+	builder := &tlsrpt.Builder{OrganizationName: "Example Net", ContactInfo: "tlsrpt@example.net"}
+	for i := 0; i < 3; i++ {
+		builder.Add(tlsrpt.Session{Time: day.Add(time.Duration(i) * time.Hour), Policy: policy})
+	}
+	for i := 0; i < 2; i++ {
+		builder.Add(tlsrpt.Session{
+			Time:                day.Add(20 * time.Hour),
+			Policy:              policy,
+			ResultType:          tlsrpt.ResultCertificateExpired,
+			SendingMTAIP:        "198.51.100.1",
+			ReceivingMxHostname: "mx.example.com",
+			ReceivingIP:         "192.0.2.1",
+		})
+	}
+	builder.Add(tlsrpt.Session{
+		Time:       day.Add(26 * time.Hour),
+		Policy:     tlsrpt.Policy{PolicyType: "no-policy-found", PolicyDomain: "example.org"},
+		ResultType: tlsrpt.ResultStartTLSNotSupported,
+	})
+
+	t.Run("unfinished days are kept", func(t *testing.T) {
+		reports := builder.Flush(day.Add(30 * time.Hour))
+		if len(reports) != 1 {
+			t.Fatalf("len(reports) = %d, want 1", len(reports))
+		}
+
+		report := reports[0]
+		if report.ReportID != "2025-05-13T00:00:00Z_example.com" {
+			t.Errorf("ReportID = %s, want 2025-05-13T00:00:00Z_example.com", report.ReportID)
+		}
+		if !report.DateRange.EndDateTime.Equal(day.Add(24*time.Hour - time.Second)) {
+			t.Errorf("EndDateTime = %s, want 2025-05-13 23:59:59 UTC", report.DateRange.EndDateTime)
+		}
+		if len(report.Policies) != 1 {
+			t.Fatalf("len(Policies) = %d, want 1", len(report.Policies))
+		}
+
+		summary := report.Policies[0].Summary
+		if summary.TotalSuccessfulSessionCount != 3 || summary.TotalFailureSessionCount != 2 {
+			t.Errorf("Summary = %+v, want 3 successful and 2 failed sessions", summary)
+		}
+		details := report.Policies[0].FailureDetails
+		if len(details) != 1 || details[0].FailedSessionCount != 2 {
+			t.Errorf("FailureDetails = %+v, want one detail with 2 failed sessions", details)
+		}
+		if result := tlsrpt.Validate(report); !result.Valid() {
+			t.Errorf("Validate() = %v, want no errors", result.Err())
+		}
+
+		filename, content, err := tlsrpt.PackageReport("example.net", report)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filename != "example.net!example.com!1747094400!1747180799.json.gz" {
+			t.Errorf("filename = %s, want example.net!example.com!1747094400!1747180799.json.gz", filename)
+		}
+		if _, err := reportname.Parse(filename); err != nil {
+			t.Errorf("reportname.Parse() error = %v", err)
+		}
+
+		parsed, err := tlsrpt.ParseReports(bytes.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed[0].Policies[0].FailureDetails[0].ResultType.Key() != "certificate-expired" {
+			t.Errorf("ResultType = %s, want certificate-expired", parsed[0].Policies[0].FailureDetails[0].ResultType)
+		}
+	})
+
+	t.Run("remaining days", func(t *testing.T) {
+		reports := builder.Flush(day.Add(72 * time.Hour))
+		if len(reports) != 1 {
+			t.Fatalf("len(reports) = %d, want 1", len(reports))
+		}
+		if reports[0].Policies[0].Policy.PolicyDomain != "example.org" {
+			t.Errorf("PolicyDomain = %s, want example.org", reports[0].Policies[0].Policy.PolicyDomain)
+		}
+
+		// The optional fields of a no-policy-found failure without details
+		// are left out rather than sent as null or empty strings.
+		content, err := json.Marshal(reports[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range []string{"null", `"policy-string"`, `"mx-host"`, `"receiving-ip"`, `"failure-reason-code"`} {
+			if bytes.Contains(content, []byte(field)) {
+				t.Errorf("report contains %s: %s", field, content)
+			}
+		}
+		if got := builder.Flush(day.Add(72 * time.Hour)); len(got) != 0 {
+			t.Errorf("len(Flush()) = %d, want 0", len(got))
+		}
+	})
+}
+
+func TestPackageReportNil(t *testing.T) {
+	if _, _, err := tlsrpt.PackageReport("example.net", nil); err == nil {
+		t.Error("PackageReport(nil) error = nil, want an error")
+	}
+}
@@ -0,0 +1,229 @@
+package tlsrpt
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/aldy505/mailweave/internal/limits"
+)
+
+// CompressionType represents the container a TLS-RPT report is delivered in.
+type CompressionType uint8
+
+const (
+	// CompressionTypeNone is a plain JSON report.
+	CompressionTypeNone CompressionType = iota
+	// CompressionTypeGZIP is a gzip compressed JSON report.
+	CompressionTypeGZIP
+	// CompressionTypeZIP is a zip archive containing one or more JSON reports.
+	// It is not part of RFC 8460, but some reporters use it anyway.
+	CompressionTypeZIP
+)
+
+func (c CompressionType) String() string {
+	switch c {
+	case CompressionTypeNone:
+		return "none"
+	case CompressionTypeGZIP:
+		return "gzip"
+	case CompressionTypeZIP:
+		return "zip"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	// MediaTypeJSON is the media type of a plain JSON report, as registered
+	// by RFC 8460 Section 6.4.
+	MediaTypeJSON = "application/tlsrpt+json"
+	// MediaTypeGZIP is the media type of a gzip compressed JSON report, as
+	// registered by RFC 8460 Section 6.5.
+	MediaTypeGZIP = "application/tlsrpt+gzip"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte{'P', 'K', 0x03, 0x04}
+)
+
+// DetectCompression sniffs the magic bytes at the start of header.
+// Anything that is neither gzip nor zip is treated as plain JSON.
+func DetectCompression(header []byte) CompressionType {
+	switch {
+	case bytes.HasPrefix(header, zipMagic):
+		return CompressionTypeZIP
+	case bytes.HasPrefix(header, gzipMagic):
+		return CompressionTypeGZIP
+	default:
+		return CompressionTypeNone
+	}
+}
+
+// CompressionFromMediaType returns the compression of a report with the given
+// media type, e.g. the Content-Type of an email attachment or an HTTPS
+// request. Parameters are ignored. It returns false for a media type that does
+// not tell the compression, such as application/octet-stream.
+func CompressionFromMediaType(mediaType string) (CompressionType, bool) {
+	parsed, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		parsed = strings.ToLower(strings.TrimSpace(mediaType))
+	}
+
+	switch parsed {
+	case MediaTypeJSON, "application/json":
+		return CompressionTypeNone, true
+	case MediaTypeGZIP, "application/gzip", "application/x-gzip":
+		return CompressionTypeGZIP, true
+	case "application/zip", "application/x-zip-compressed":
+		return CompressionTypeZIP, true
+	default:
+		return CompressionTypeNone, false
+	}
+}
+
+// WithMediaType sets the media type the report was delivered with, which
+// ParseReports honours instead of sniffing the content.
+func WithMediaType(mediaType string) ParseOption {
+	return func(o *parseOptions) {
+		o.mediaType = mediaType
+	}
+}
+
+// ParseReports parses a TLS-RPT report without requiring the caller to know
+// how it is compressed. The compression is taken from the media type set with
+// WithMediaType when it is known, and otherwise detected from the magic bytes.
+// A zip archive is always detected, as there is no media type for it.
+//
+// One Report is returned per JSON document found. Zip members that are not
+// JSON documents are skipped.
+func ParseReports(r io.Reader, opts ...ParseOption) ([]*Report, error) {
+	options := newParseOptions(opts)
+
+	br := bufio.NewReader(r)
+	header, err := br.Peek(len(zipMagic))
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	compression := DetectCompression(header)
+	if compression != CompressionTypeZIP {
+		if fromMediaType, ok := CompressionFromMediaType(options.mediaType); ok {
+			compression = fromMediaType
+		}
+	}
+
+	if compression == CompressionTypeZIP {
+		return parseZipReports(br, options.limits)
+	}
+
+	report, err := ParseReport(br, compression, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Report{report}, nil
+}
+
+func parseZipReports(r io.Reader, parseLimits Limits) ([]*Report, error) {
+	compressedCounter := limits.NewCounter(parseLimits.MaxCompressedSize, ErrCompressedSizeExceeded)
+	compressed := compressedCounter.Reader(r)
+
+	// archive/zip needs random access to read the central directory.
+	content, err := io.ReadAll(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("reading zip: %w", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("opening zip: %w", err)
+	}
+
+	if parseLimits.MaxZipMembers >= 0 && len(archive.File) > parseLimits.MaxZipMembers {
+		return nil, fmt.Errorf("opening zip: %w: %d members, limit is %d", ErrZipMembersExceeded, len(archive.File), parseLimits.MaxZipMembers)
+	}
+
+	// Every byte decompressed from the archive, including the gzip files
+	// nested in it, is counted together, so the decompressed size limit
+	// applies to the whole archive.
+	decompressed := limits.NewCounter(parseLimits.MaxDecompressedSize, ErrDecompressedSizeExceeded)
+	decompressed.LimitRatio(compressedCounter, parseLimits.MaxCompressionRatio)
+
+	var reports []*Report
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		report, err := parseZipMember(file, decompressed, parseLimits.MaxRecords)
+		if err != nil {
+			return nil, fmt.Errorf("zip member %s: %w", file.Name, err)
+		}
+
+		if report != nil {
+			reports = append(reports, report)
+		}
+	}
+
+	if len(reports) == 0 {
+		return nil, fmt.Errorf("zip archive does not contain any JSON report")
+	}
+
+	return reports, nil
+}
+
+// parseZipMember returns a nil report for a member that is not a JSON document.
+func parseZipMember(file *zip.File, decompressed *limits.Counter, maxRecords int) (*Report, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("opening: %w", err)
+	}
+	defer rc.Close()
+
+	br := bufio.NewReader(decompressed.Reader(rc))
+
+	// Some reporters put a gzip compressed report inside the zip archive.
+	header, err := br.Peek(len(gzipMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("reading: %w", err)
+	}
+	if DetectCompression(header) == CompressionTypeGZIP {
+		reader, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("decompressing gzip: %w", err)
+		}
+		defer reader.Close()
+
+		br = bufio.NewReader(decompressed.Reader(reader))
+	}
+
+	ok, err := looksLikeJSON(br)
+	if err != nil {
+		return nil, fmt.Errorf("reading: %w", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	return decodeReport(br, maxRecords)
+}
+
+// looksLikeJSON reports whether the first non-whitespace character is the
+// start of a JSON object.
+func looksLikeJSON(r *bufio.Reader) (bool, error) {
+	header, err := r.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+
+	header = bytes.TrimLeft(header, " \t\r\n")
+	return len(header) > 0 && header[0] == '{', nil
+}
@@ -0,0 +1,168 @@
+package tlsrpt_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+func TestDetectCompression(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []byte
+		want   tlsrpt.CompressionType
+	}{
+		{name: "gzip", header: []byte{0x1f, 0x8b, 0x08, 0x00}, want: tlsrpt.CompressionTypeGZIP},
+		{name: "zip", header: []byte{'P', 'K', 0x03, 0x04}, want: tlsrpt.CompressionTypeZIP},
+		{name: "json", header: []byte(`{"organization-name"`), want: tlsrpt.CompressionTypeNone},
+		{name: "empty", header: nil, want: tlsrpt.CompressionTypeNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tlsrpt.DetectCompression(tt.header); got != tt.want {
+				t.Errorf("DetectCompression() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompressionFromMediaType(t *testing.T) {
+	tests := []struct {
+		mediaType string
+		want      tlsrpt.CompressionType
+		ok        bool
+	}{
+		{mediaType: "application/tlsrpt+json", want: tlsrpt.CompressionTypeNone, ok: true},
+		{mediaType: "application/tlsrpt+gzip", want: tlsrpt.CompressionTypeGZIP, ok: true},
+		{mediaType: `Application/TLSRPT+GZIP; name="report.json.gz"`, want: tlsrpt.CompressionTypeGZIP, ok: true},
+		{mediaType: "application/zip", want: tlsrpt.CompressionTypeZIP, ok: true},
+		{mediaType: "application/octet-stream", ok: false},
+		{mediaType: "", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mediaType, func(t *testing.T) {
+			got, ok := tlsrpt.CompressionFromMediaType(tt.mediaType)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("CompressionFromMediaType(%s) = %s, %v, want %s, %v", tt.mediaType, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseReports(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := os.ReadFile(path.Join(pwd, "../testdata/tlsrpt/google.com!example.com!1747094400!1747180799!001.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compressed, err := os.ReadFile(path.Join(pwd, "../testdata/tlsrpt/google.com!example.com!1747094400!1747180799!001.json.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	microsoft, err := os.ReadFile(path.Join(pwd, "../testdata/tlsrpt/microsoft.com!example.com!1739750400!1739836799!133843802167204397.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var archive bytes.Buffer
+	w := zip.NewWriter(&archive)
+	members := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "google.com!example.com!1747094400!1747180799!001.json.gz", content: compressed},
+		{name: "README.txt", content: []byte("not a report")},
+		{name: "microsoft.com!example.com!1739750400!1739836799!133843802167204397.json", content: microsoft},
+	}
+	for _, member := range members {
+		f, err := w.Create(member.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(member.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		content   []byte
+		mediaType string
+		want      []string
+		wantErr   bool
+	}{
+		{name: "sniffed json", content: plain, want: []string{"Google Inc."}},
+		{name: "sniffed gzip", content: compressed, want: []string{"Google Inc."}},
+		{name: "json media type", content: plain, mediaType: tlsrpt.MediaTypeJSON, want: []string{"Google Inc."}},
+		{name: "gzip media type", content: compressed, mediaType: tlsrpt.MediaTypeGZIP, want: []string{"Google Inc."}},
+		{name: "unknown media type", content: compressed, mediaType: "application/octet-stream", want: []string{"Google Inc."}},
+		{name: "gzip media type on json", content: plain, mediaType: tlsrpt.MediaTypeGZIP, wantErr: true},
+		{name: "zip labelled as gzip", content: archive.Bytes(), mediaType: tlsrpt.MediaTypeGZIP, want: []string{"Google Inc.", "Microsoft Corporation"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports, err := tlsrpt.ParseReports(bytes.NewReader(tt.content), tlsrpt.WithMediaType(tt.mediaType))
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(reports) != len(tt.want) {
+				t.Fatalf("len(reports) = %d, want %d", len(reports), len(tt.want))
+			}
+			for i, report := range reports {
+				if report.OrganizationName != tt.want[i] {
+					t.Errorf("reports[%d].OrganizationName = %s, want %s", i, report.OrganizationName, tt.want[i])
+				}
+			}
+		})
+	}
+
+	t.Run("parse report with a single zip member", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		f, err := w.Create("report.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(plain); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		report, err := tlsrpt.ParseReport(&buf, tlsrpt.CompressionTypeZIP)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.OrganizationName != "Google Inc." {
+			t.Errorf("OrganizationName = %s, want Google Inc.", report.OrganizationName)
+		}
+
+		if _, err := tlsrpt.ParseReport(bytes.NewReader(archive.Bytes()), tlsrpt.CompressionTypeZIP); err == nil {
+			t.Error("expected an error for a zip archive with two reports")
+		}
+	})
+}
@@ -0,0 +1,232 @@
+package tlsrpt
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+)
+
+var (
+	// ErrInvalidDNSRecord is returned when a TLS-RPT record cannot be parsed.
+	ErrInvalidDNSRecord = errors.New("invalid tls-rpt record")
+	// ErrNoDNSRecord is returned when none of the TXT records is a TLS-RPT record.
+	ErrNoDNSRecord = errors.New("no tls-rpt record")
+	// ErrMultipleDNSRecords is returned when more than one of the TXT records is
+	// a TLS-RPT record, which RFC 8460 Section 3 requires to be treated as if
+	// there were none.
+	ErrMultipleDNSRecords = errors.New("multiple tls-rpt records")
+)
+
+// DNSRecord is a parsed TLS-RPT record, as published in the TXT record of
+// _smtp._tls.<domain> and defined in RFC 8460 Section 3.
+type DNSRecord struct {
+	// The version of the record, always "TLSRPTv1".
+	Version string
+	// The mailto URIs of the rua tag, e.g. "mailto:tlsrpt@example.com".
+	MailtoURIs []string
+	// The https URIs of the rua tag, e.g. "https://reports.example.com/tlsrpt".
+	HTTPSURIs []string
+}
+
+// Addresses returns the email addresses of the mailto URIs, decoded with
+// MailtoAddress.
+func (r DNSRecord) Addresses() []string {
+	addresses := make([]string, 0, len(r.MailtoURIs))
+	for _, uri := range r.MailtoURIs {
+		if address := MailtoAddress(uri); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+
+	return addresses
+}
+
+// MailtoAddress returns the percent-decoded email address of a mailto URI,
+// e.g. "tlsrpt@example.com" for "mailto:tlsrpt%40example.com", or an empty
+// string for other schemes and malformed URIs.
+func MailtoAddress(uri string) string {
+	parsed, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil || !strings.EqualFold(parsed.Scheme, "mailto") {
+		return ""
+	}
+
+	address, err := url.PathUnescape(parsed.Opaque)
+	if err != nil {
+		return ""
+	}
+
+	return address
+}
+
+// ParseDNSRecord parses the value of a TLS-RPT TXT record, e.g.
+// "v=TLSRPTv1; rua=mailto:tlsrpt@example.com". Unknown tags are ignored, as
+// RFC 8460 allows extensions. Use LintDNSRecords to get every problem of a
+// record.
+func ParseDNSRecord(value string) (DNSRecord, error) {
+	var v ValidationResult
+	record := parseDNSRecord(value, &v)
+	if err := v.Err(); err != nil {
+		return DNSRecord{}, fmt.Errorf("%w: %w", ErrInvalidDNSRecord, err)
+	}
+
+	return record, nil
+}
+
+// ParseDNSRecords parses the TLS-RPT record among the TXT records of
+// _smtp._tls.<domain>, ignoring the TXT records that do not start with
+// "v=TLSRPTv1". It returns ErrNoDNSRecord when there is none, and
+// ErrMultipleDNSRecords when there is more than one.
+func ParseDNSRecords(values []string) (DNSRecord, error) {
+	records := tlsRptRecords(values)
+	switch len(records) {
+	case 0:
+		return DNSRecord{}, ErrNoDNSRecord
+	case 1:
+		return ParseDNSRecord(records[0])
+	default:
+		return DNSRecord{}, fmt.Errorf("%w: found %d records", ErrMultipleDNSRecords, len(records))
+	}
+}
+
+// LintDNSRecords checks the TXT records of _smtp._tls.<domain>, in the same
+// way as dmarc.LintDNSRecord. Errors are problems that make senders ignore the
+// TLS-RPT record, such as a missing or multiple records, syntax errors and
+// invalid rua URIs, while Warnings are duplicate tags and likely mistakes.
+func LintDNSRecords(values []string) ValidationResult {
+	var v ValidationResult
+
+	records := tlsRptRecords(values)
+	switch len(records) {
+	case 0:
+		v.AddError("record", "no TXT record starts with v=TLSRPTv1")
+		return v
+	case 1:
+	default:
+		v.AddError("record", "found %d TLS-RPT records, senders treat them as if there were none", len(records))
+		return v
+	}
+
+	record := parseDNSRecord(records[0], &v)
+	if !v.Valid() {
+		return v
+	}
+
+	seen := make(map[string]bool)
+	for _, uri := range append(record.MailtoURIs, record.HTTPSURIs...) {
+		key := strings.ToLower(uri)
+		if seen[key] {
+			v.AddWarning("rua", "%q is listed more than once", uri)
+		}
+		seen[key] = true
+	}
+
+	return v
+}
+
+// IsDNSRecord reports whether the TXT record value is a TLS-RPT record, that
+// is whether its first tag is a v tag of TLSRPTv1. The tag and its value are
+// compared case-insensitively.
+func IsDNSRecord(value string) bool {
+	version, _, _ := strings.Cut(value, ";")
+	name, tagValue, ok := strings.Cut(version, "=")
+	return ok && strings.EqualFold(strings.TrimSpace(name), "v") && strings.EqualFold(strings.TrimSpace(tagValue), "TLSRPTv1")
+}
+
+// tlsRptRecords returns the values that look like a TLS-RPT record.
+func tlsRptRecords(values []string) []string {
+	var records []string
+	for _, value := range values {
+		if IsDNSRecord(value) {
+			records = append(records, value)
+		}
+	}
+
+	return records
+}
+
+func parseDNSRecord(value string, v *ValidationResult) DNSRecord {
+	var record DNSRecord
+
+	seen := make(map[string]bool)
+	position := 0
+	for _, part := range strings.Split(value, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		position++
+
+		name, tagValue, ok := strings.Cut(part, "=")
+		if !ok {
+			v.AddError("record", "%q is not a tag=value pair", part)
+			continue
+		}
+
+		name = strings.ToLower(strings.TrimSpace(name))
+		tagValue = strings.TrimSpace(tagValue)
+
+		if seen[name] {
+			v.AddWarning(name, "duplicate tag, only the first one is used")
+			continue
+		}
+		seen[name] = true
+
+		switch name {
+		case "v":
+			if position != 1 {
+				v.AddError("v", "must be the first tag")
+			}
+			if !strings.EqualFold(tagValue, "TLSRPTv1") {
+				v.AddError("v", "%q is not TLSRPTv1", tagValue)
+			}
+			record.Version = tagValue
+		case "rua":
+			for _, entry := range strings.Split(tagValue, ",") {
+				entry = strings.TrimSpace(entry)
+				if entry == "" {
+					v.AddError(name, "empty URI")
+					continue
+				}
+
+				parsed, err := url.Parse(entry)
+				if err != nil {
+					v.AddError(name, "%q is not a URI", entry)
+					continue
+				}
+
+				switch strings.ToLower(parsed.Scheme) {
+				case "mailto":
+					address := MailtoAddress(entry)
+					if address == "" {
+						v.AddError(name, "%q is not a valid mailto URI", entry)
+						continue
+					}
+					if parsed, err := mail.ParseAddress(address); err != nil || parsed.Name != "" || parsed.Address != address {
+						v.AddError(name, "%q is not a valid mailto URI", entry)
+						continue
+					}
+					record.MailtoURIs = append(record.MailtoURIs, "mailto:"+parsed.Opaque)
+				case "https":
+					if parsed.Host == "" {
+						v.AddError(name, "%q has no host", entry)
+						continue
+					}
+					record.HTTPSURIs = append(record.HTTPSURIs, entry)
+				default:
+					v.AddError(name, "%q is neither a mailto nor an https URI", entry)
+				}
+			}
+		}
+	}
+
+	if !seen["v"] {
+		v.AddError("v", "missing")
+	}
+	if !seen["rua"] {
+		v.AddError("rua", "missing")
+	}
+
+	return record
+}
@@ -0,0 +1,143 @@
+package tlsrpt_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+func TestParseDNSRecord(t *testing.T) {
+	t.Run("mailto and https", func(t *testing.T) {
+		record, err := tlsrpt.ParseDNSRecord("v=TLSRPTv1; rua=mailto:tlsrpt@example.com,https://reports.example.com/tlsrpt")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if record.Version != "TLSRPTv1" {
+			t.Errorf("Version = %s, want TLSRPTv1", record.Version)
+		}
+		if !slices.Equal(record.MailtoURIs, []string{"mailto:tlsrpt@example.com"}) {
+			t.Errorf("MailtoURIs = %v, want [mailto:tlsrpt@example.com]", record.MailtoURIs)
+		}
+		if !slices.Equal(record.HTTPSURIs, []string{"https://reports.example.com/tlsrpt"}) {
+			t.Errorf("HTTPSURIs = %v, want [https://reports.example.com/tlsrpt]", record.HTTPSURIs)
+		}
+	})
+
+	t.Run("percent-encoded mailto", func(t *testing.T) {
+		record, err := tlsrpt.ParseDNSRecord("v=TLSRPTv1; rua=mailto:tls-rpt%40example.com,https://reports.example.com/tlsrpt")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(record.MailtoURIs, []string{"mailto:tls-rpt%40example.com"}) {
+			t.Errorf("MailtoURIs = %v, want [mailto:tls-rpt%%40example.com]", record.MailtoURIs)
+		}
+		if !slices.Equal(record.Addresses(), []string{"tls-rpt@example.com"}) {
+			t.Errorf("Addresses() = %v, want [tls-rpt@example.com]", record.Addresses())
+		}
+		if got := tlsrpt.MailtoAddress("https://reports.example.com/tlsrpt"); got != "" {
+			t.Errorf("MailtoAddress() = %s, want empty", got)
+		}
+	})
+
+	t.Run("version case", func(t *testing.T) {
+		record, err := tlsrpt.ParseDNSRecords([]string{"V = tlsrptv1; rua=mailto:tlsrpt@example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(record.MailtoURIs, []string{"mailto:tlsrpt@example.com"}) {
+			t.Errorf("MailtoURIs = %v, want [mailto:tlsrpt@example.com]", record.MailtoURIs)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, value := range []string{
+			"v=TLSRPTv1",
+			"v=TLSRPTv2; rua=mailto:tlsrpt@example.com",
+			"rua=mailto:tlsrpt@example.com; v=TLSRPTv1",
+			"v=TLSRPTv1; rua=http://reports.example.com/tlsrpt",
+			"v=TLSRPTv1; rua=mailto:not an address",
+			"v=TLSRPTv1; rua=https:///tlsrpt",
+			"v=TLSRPTv1; rua",
+		} {
+			_, err := tlsrpt.ParseDNSRecord(value)
+			if !errors.Is(err, tlsrpt.ErrInvalidDNSRecord) {
+				t.Errorf("ParseDNSRecord(%q) error = %v, want ErrInvalidDNSRecord", value, err)
+			}
+		}
+	})
+}
+
+func TestParseDNSRecords(t *testing.T) {
+	t.Run("single record", func(t *testing.T) {
+		record, err := tlsrpt.ParseDNSRecords([]string{"google-site-verification=abc", "v=TLSRPTv1; rua=mailto:tlsrpt@example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(record.MailtoURIs) != 1 {
+			t.Errorf("len(MailtoURIs) = %d, want 1", len(record.MailtoURIs))
+		}
+	})
+
+	t.Run("no record", func(t *testing.T) {
+		_, err := tlsrpt.ParseDNSRecords([]string{"v=spf1 -all"})
+		if !errors.Is(err, tlsrpt.ErrNoDNSRecord) {
+			t.Errorf("error = %v, want ErrNoDNSRecord", err)
+		}
+	})
+
+	t.Run("multiple records", func(t *testing.T) {
+		_, err := tlsrpt.ParseDNSRecords([]string{
+			"v=TLSRPTv1; rua=mailto:tlsrpt@example.com",
+			"v=TLSRPTv1; rua=https://reports.example.com/tlsrpt",
+		})
+		if !errors.Is(err, tlsrpt.ErrMultipleDNSRecords) {
+			t.Errorf("error = %v, want ErrMultipleDNSRecords", err)
+		}
+	})
+}
+
+func TestLintDNSRecords(t *testing.T) {
+	hasIssue := func(issues []tlsrpt.FieldError, field string) bool {
+		return slices.ContainsFunc(issues, func(issue tlsrpt.FieldError) bool { return issue.Field == field })
+	}
+
+	t.Run("clean record", func(t *testing.T) {
+		result := tlsrpt.LintDNSRecords([]string{"v=TLSRPTv1; rua=mailto:tlsrpt@example.com"})
+		if !result.Valid() || len(result.Warnings) != 0 {
+			t.Errorf("LintDNSRecords() = %+v, want no issues", result)
+		}
+	})
+
+	t.Run("multiple records", func(t *testing.T) {
+		result := tlsrpt.LintDNSRecords([]string{
+			"v=TLSRPTv1; rua=mailto:tlsrpt@example.com",
+			"v=TLSRPTv1;rua=mailto:tlsrpt@example.net",
+		})
+		if !hasIssue(result.Errors, "record") {
+			t.Errorf("Errors = %v, want a record error", result.Errors)
+		}
+	})
+
+	t.Run("bad uri", func(t *testing.T) {
+		result := tlsrpt.LintDNSRecords([]string{"v=TLSRPTv1; rua=ftp://reports.example.com"})
+		if !hasIssue(result.Errors, "rua") {
+			t.Errorf("Errors = %v, want a rua error", result.Errors)
+		}
+	})
+
+	t.Run("duplicate tag and uri", func(t *testing.T) {
+		result := tlsrpt.LintDNSRecords([]string{"v=TLSRPTv1; rua=mailto:tlsrpt@example.com,mailto:TLSRPT@example.com; rua=https://reports.example.com"})
+		if !result.Valid() {
+			t.Fatal(result.Err())
+		}
+		if len(result.Warnings) != 2 {
+			t.Errorf("Warnings = %v, want 2 warnings", result.Warnings)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package tlsrpt
+
+import (
+	"github.com/aldy505/mailweave/internal/limits"
+)
+
+var (
+	// ErrCompressedSizeExceeded is returned when a compressed report is larger
+	// than Limits.MaxCompressedSize.
+	ErrCompressedSizeExceeded = limits.ErrCompressedSizeExceeded
+	// ErrDecompressedSizeExceeded is returned when a report decompresses to
+	// more than Limits.MaxDecompressedSize, or an uncompressed report is larger
+	// than it.
+	ErrDecompressedSizeExceeded = limits.ErrDecompressedSizeExceeded
+	// ErrCompressionRatioExceeded is returned when a report decompresses to
+	// more than Limits.MaxCompressionRatio times its compressed size.
+	ErrCompressionRatioExceeded = limits.ErrCompressionRatioExceeded
+	// ErrZipMembersExceeded is returned when a zip archive has more than
+	// Limits.MaxZipMembers members.
+	ErrZipMembersExceeded = limits.ErrZipMembersExceeded
+	// ErrRecordsExceeded is returned when a report has more than
+	// Limits.MaxRecords policies and failure details.
+	ErrRecordsExceeded = limits.ErrRecordsExceeded
+)
+
+// Limits caps the resources spent on parsing a single report, so a
+// decompression bomb sent to the rua address fails with an error instead of
+// exhausting the memory. A zero field uses the value of DefaultLimits, and a
+// negative field disables the limit.
+type Limits = limits.Limits
+
+// DefaultLimits are the limits applied when parsing without WithLimits.
+var DefaultLimits = Limits{
+	MaxCompressedSize:   10 << 20,
+	MaxDecompressedSize: 50 << 20,
+	MaxCompressionRatio: 200,
+	MaxZipMembers:       16,
+	MaxRecords:          100_000,
+}
+
+type parseOptions struct {
+	limits    Limits
+	mediaType string
+}
+
+// ParseOption configures how a report is parsed.
+type ParseOption func(*parseOptions)
+
+// WithLimits overrides the DefaultLimits.
+func WithLimits(limits Limits) ParseOption {
+	return func(o *parseOptions) {
+		o.limits = limits
+	}
+}
+
+func newParseOptions(opts []ParseOption) parseOptions {
+	var options parseOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	options.limits = options.limits.WithDefaults(DefaultLimits)
+
+	return options
+}
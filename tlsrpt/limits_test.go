@@ -0,0 +1,119 @@
+package tlsrpt_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+func TestParseReportLimits(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := os.ReadFile(path.Join(pwd, "../testdata/tlsrpt/google.com!example.com!1747094400!1747180799!001.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withFailures, err := os.ReadFile(path.Join(pwd, "../testdata/tlsrpt/microsoft.com!example.com!1739750400!1739836799!133843802167204397.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compressed, err := os.ReadFile(path.Join(pwd, "../testdata/tlsrpt/google.com!example.com!1747094400!1747180799!001.json.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Whitespace is valid JSON and compresses extremely well.
+	var bomb bytes.Buffer
+	w := gzip.NewWriter(&bomb)
+	if _, err := w.Write(append(bytes.Repeat([]byte(" "), 20<<20), report...)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The record limit is hit before the parser reaches the malformed end.
+	malformed := withFailures[:bytes.LastIndex(withFailures, []byte("}"))]
+	malformed = append(malformed[:len(malformed):len(malformed)], ", {{{"...)
+
+	tests := []struct {
+		name        string
+		content     []byte
+		compression tlsrpt.CompressionType
+		limits      tlsrpt.Limits
+		want        error
+	}{
+		{
+			name:        "compression ratio",
+			content:     bomb.Bytes(),
+			compression: tlsrpt.CompressionTypeGZIP,
+			want:        tlsrpt.ErrCompressionRatioExceeded,
+		},
+		{
+			name:        "decompressed size",
+			content:     bomb.Bytes(),
+			compression: tlsrpt.CompressionTypeGZIP,
+			limits:      tlsrpt.Limits{MaxDecompressedSize: 1 << 20, MaxCompressionRatio: -1},
+			want:        tlsrpt.ErrDecompressedSizeExceeded,
+		},
+		{
+			name:        "decompressed size of plain json",
+			content:     report,
+			compression: tlsrpt.CompressionTypeNone,
+			limits:      tlsrpt.Limits{MaxDecompressedSize: 64},
+			want:        tlsrpt.ErrDecompressedSizeExceeded,
+		},
+		{
+			name:        "compressed size",
+			content:     compressed,
+			compression: tlsrpt.CompressionTypeGZIP,
+			limits:      tlsrpt.Limits{MaxCompressedSize: 64},
+			want:        tlsrpt.ErrCompressedSizeExceeded,
+		},
+		{
+			name:        "records",
+			content:     withFailures,
+			compression: tlsrpt.CompressionTypeNone,
+			limits:      tlsrpt.Limits{MaxRecords: 1},
+			want:        tlsrpt.ErrRecordsExceeded,
+		},
+		{
+			name:        "records before the end of the report",
+			content:     malformed,
+			compression: tlsrpt.CompressionTypeNone,
+			limits:      tlsrpt.Limits{MaxRecords: 1},
+			want:        tlsrpt.ErrRecordsExceeded,
+		},
+		{
+			name:        "within limits",
+			content:     compressed,
+			compression: tlsrpt.CompressionTypeGZIP,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tlsrpt.ParseReport(bytes.NewReader(tt.content), tt.compression, tlsrpt.WithLimits(tt.limits))
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("ParseReport() error = %v, want nil", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ParseReport() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
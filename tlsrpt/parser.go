@@ -5,33 +5,195 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-)
-
-type CompressionType uint8
+	"strings"
 
-const (
-	CompressionTypeNone CompressionType = iota
-	CompressionTypeGZIP
+	"github.com/aldy505/mailweave/internal/limits"
 )
 
-func ParseReport(r io.Reader, compression CompressionType) (*Report, error) {
-	// decompress file if compression is gzip
-	if compression == CompressionTypeGZIP {
-		reader, err := gzip.NewReader(r)
+// ParseReport parses a TLS-RPT report. The report is subject to the Limits set
+// with WithLimits, or to the DefaultLimits. Exceeding a limit fails with the
+// matching error, such as ErrDecompressedSizeExceeded, which can be checked
+// with errors.Is.
+//
+// A zip archive must contain exactly one report. Use ParseReports when the
+// compression is not known, or when an archive may contain several reports.
+func ParseReport(r io.Reader, compression CompressionType, opts ...ParseOption) (*Report, error) {
+	parseLimits := newParseOptions(opts).limits
+
+	switch compression {
+	case CompressionTypeZIP:
+		reports, err := parseZipReports(r, parseLimits)
 		if err != nil {
-			return nil, fmt.Errorf("decompressing gzip: %w", err)
+			return nil, err
+		}
+
+		if len(reports) != 1 {
+			return nil, fmt.Errorf("zip archive contains %d reports, want 1", len(reports))
 		}
-		defer reader.Close()
 
-		r = reader
+		return reports[0], nil
+	case CompressionTypeGZIP:
+		return parseGzipReport(r, parseLimits)
+	default:
+		r = limits.NewCounter(parseLimits.MaxDecompressedSize, ErrDecompressedSizeExceeded).Reader(r)
+		return decodeReport(r, parseLimits.MaxRecords)
+	}
+}
+
+func parseGzipReport(r io.Reader, parseLimits Limits) (*Report, error) {
+	compressed := limits.NewCounter(parseLimits.MaxCompressedSize, ErrCompressedSizeExceeded)
+	reader, err := gzip.NewReader(compressed.Reader(r))
+	if err != nil {
+		return nil, fmt.Errorf("decompressing gzip: %w", err)
+	}
+	defer reader.Close()
+
+	decompressed := limits.NewCounter(parseLimits.MaxDecompressedSize, ErrDecompressedSizeExceeded)
+	decompressed.LimitRatio(compressed, parseLimits.MaxCompressionRatio)
+
+	return decodeReport(decompressed.Reader(reader), parseLimits.MaxRecords)
+}
+
+// decodeReport decodes the JSON report read from r, which is already limited
+// in size by the caller. The policies and their failure details are decoded
+// one at a time, so a report with more than maxRecords of them fails before
+// the ones past the limit are held in memory.
+func decodeReport(r io.Reader, maxRecords int) (*Report, error) {
+	decoder := json.NewDecoder(r)
+	records := 0
+	count := func() error {
+		records++
+		if maxRecords >= 0 && records > maxRecords {
+			return fmt.Errorf("%w: limit is %d", ErrRecordsExceeded, maxRecords)
+		}
+
+		return nil
 	}
 
-	// parse report
 	var report Report
-	err := json.NewDecoder(r).Decode(&report)
+	err := decodeObject(decoder, &report, func(key string) (bool, error) {
+		if !strings.EqualFold(key, "policies") {
+			return false, nil
+		}
+
+		report.Policies = nil
+		return true, decodeArray(decoder, func() error {
+			if err := count(); err != nil {
+				return err
+			}
+
+			var policy TLSPolicy
+			err := decodeObject(decoder, &policy, func(key string) (bool, error) {
+				if !strings.EqualFold(key, "failure-details") {
+					return false, nil
+				}
+
+				policy.FailureDetails = nil
+				return true, decodeArray(decoder, func() error {
+					if err := count(); err != nil {
+						return err
+					}
+
+					var detail FailureDetail
+					if err := decoder.Decode(&detail); err != nil {
+						return err
+					}
+
+					policy.FailureDetails = append(policy.FailureDetails, detail)
+					return nil
+				})
+			})
+			if err != nil {
+				return err
+			}
+
+			report.Policies = append(report.Policies, policy)
+			return nil
+		})
+	})
 	if err != nil {
 		return nil, fmt.Errorf("parsing json report: %w", err)
 	}
 
 	return &report, nil
 }
+
+// decodeObject decodes the JSON object at the position of decoder into v. The
+// members for which stream returns true are decoded by stream itself, the
+// others are decoded into v as encoding/json does. A null leaves v untouched.
+func decodeObject(decoder *json.Decoder, v any, stream func(key string) (bool, error)) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expected a JSON object, got %v", token)
+	}
+
+	rest := make(map[string]json.RawMessage)
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		key, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("expected an object key, got %v", token)
+		}
+
+		streamed, err := stream(key)
+		if err != nil {
+			return err
+		}
+		if streamed {
+			continue
+		}
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+		rest[key] = value
+	}
+
+	// The closing brace.
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(rest)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(content, v)
+}
+
+// decodeArray calls each for every element of the JSON array at the position
+// of decoder, which must decode the element. A null is an empty array.
+func decodeArray(decoder *json.Decoder, each func() error) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected a JSON array, got %v", token)
+	}
+
+	for decoder.More() {
+		if err := each(); err != nil {
+			return err
+		}
+	}
+
+	// The closing bracket.
+	_, err = decoder.Token()
+	return err
+}
@@ -0,0 +1,291 @@
+package tlsrpt
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidPolicyString is returned when the policy-string of a policy cannot be parsed.
+var ErrInvalidPolicyString = errors.New("invalid policy string")
+
+// STSMode is the mode of an MTA-STS policy, as defined in RFC 8461 Section 3.2.
+type STSMode string
+
+const (
+	// STSModeEnforce makes sending MTAs refuse to deliver to MX hosts that
+	// fail the policy.
+	STSModeEnforce STSMode = "enforce"
+	// STSModeTesting makes sending MTAs deliver anyway, but report failures.
+	STSModeTesting STSMode = "testing"
+	// STSModeNone disables the policy.
+	STSModeNone STSMode = "none"
+)
+
+// STSMaxAgeLimit is the maximum max_age of an MTA-STS policy, about one year.
+const STSMaxAgeLimit = 31557600 * time.Second
+
+// STSPolicy is a parsed MTA-STS policy, as defined in RFC 8461 Section 3.2.
+type STSPolicy struct {
+	// The version of the policy, currently always "STSv1".
+	Version string
+	Mode    STSMode
+	// The MX host patterns, which may start with a "*." wildcard.
+	MX     []string
+	MaxAge time.Duration
+}
+
+// ParseSTSPolicy parses the lines of an MTA-STS policy, either from the
+// policy-string of a TLS-RPT report or from a policy file split into lines.
+// An entry containing line breaks is split as well. Unknown keys are ignored,
+// as RFC 8461 allows extensions.
+func ParseSTSPolicy(lines []string) (STSPolicy, error) {
+	var policy STSPolicy
+	var hasMaxAge bool
+
+	for _, entry := range lines {
+		for _, line := range strings.Split(strings.ReplaceAll(entry, "\r\n", "\n"), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+
+			key, value, ok := strings.Cut(line, ":")
+			if !ok {
+				return STSPolicy{}, fmt.Errorf("%w: line %q is not a key: value pair", ErrInvalidPolicyString, line)
+			}
+
+			key = strings.TrimSpace(key)
+			value = strings.TrimSpace(value)
+
+			switch key {
+			case "version":
+				if policy.Version == "" {
+					policy.Version = value
+				}
+			case "mode":
+				if policy.Mode == "" {
+					policy.Mode = STSMode(value)
+				}
+			case "mx":
+				policy.MX = append(policy.MX, value)
+			case "max_age":
+				if hasMaxAge {
+					continue
+				}
+
+				// The seconds are compared before the conversion, which would
+				// overflow for values far above the limit.
+				seconds, err := strconv.ParseInt(value, 10, 64)
+				if err != nil || seconds < 0 || seconds > int64(STSMaxAgeLimit/time.Second) {
+					return STSPolicy{}, fmt.Errorf("%w: invalid max_age %q", ErrInvalidPolicyString, value)
+				}
+
+				policy.MaxAge = time.Duration(seconds) * time.Second
+				hasMaxAge = true
+			}
+		}
+	}
+
+	if policy.Version != "STSv1" {
+		return STSPolicy{}, fmt.Errorf("%w: unsupported version %q", ErrInvalidPolicyString, policy.Version)
+	}
+
+	switch policy.Mode {
+	case STSModeEnforce, STSModeTesting:
+		if len(policy.MX) == 0 {
+			return STSPolicy{}, fmt.Errorf("%w: mode %s requires at least one mx", ErrInvalidPolicyString, policy.Mode)
+		}
+	case STSModeNone:
+	default:
+		return STSPolicy{}, fmt.Errorf("%w: invalid mode %q", ErrInvalidPolicyString, policy.Mode)
+	}
+
+	if !hasMaxAge {
+		return STSPolicy{}, fmt.Errorf("%w: missing max_age", ErrInvalidPolicyString)
+	}
+
+	return policy, nil
+}
+
+// Lines formats the policy as the lines of a policy file, in the order of
+// RFC 8461 Section 3.2.
+func (p STSPolicy) Lines() []string {
+	lines := []string{
+		"version: " + p.Version,
+		"mode: " + string(p.Mode),
+	}
+	for _, mx := range p.MX {
+		lines = append(lines, "mx: "+mx)
+	}
+	lines = append(lines, "max_age: "+strconv.FormatInt(int64(p.MaxAge/time.Second), 10))
+
+	return lines
+}
+
+// Equal reports whether both policies are the same, regardless of the order
+// and case of the MX patterns. A reporter that applied a policy that is not
+// equal to the currently published one is using a stale cached policy.
+func (p STSPolicy) Equal(other STSPolicy) bool {
+	if p.Version != other.Version || p.Mode != other.Mode || p.MaxAge != other.MaxAge || len(p.MX) != len(other.MX) {
+		return false
+	}
+
+	normalize := func(mx []string) []string {
+		normalized := make([]string, 0, len(mx))
+		for _, pattern := range mx {
+			normalized = append(normalized, strings.TrimSuffix(strings.ToLower(pattern), "."))
+		}
+		slices.Sort(normalized)
+		return normalized
+	}
+
+	return slices.Equal(normalize(p.MX), normalize(other.MX))
+}
+
+// TLSAUsage is the certificate usage field of a TLSA record (RFC 6698 Section 2.1.1).
+type TLSAUsage uint8
+
+const (
+	TLSAUsagePKIXTA TLSAUsage = 0
+	TLSAUsagePKIXEE TLSAUsage = 1
+	TLSAUsageDANETA TLSAUsage = 2
+	TLSAUsageDANEEE TLSAUsage = 3
+)
+
+func (u TLSAUsage) String() string {
+	switch u {
+	case TLSAUsagePKIXTA:
+		return "PKIX-TA"
+	case TLSAUsagePKIXEE:
+		return "PKIX-EE"
+	case TLSAUsageDANETA:
+		return "DANE-TA"
+	case TLSAUsageDANEEE:
+		return "DANE-EE"
+	default:
+		return strconv.Itoa(int(u))
+	}
+}
+
+// TLSASelector is the selector field of a TLSA record (RFC 6698 Section 2.1.2).
+type TLSASelector uint8
+
+const (
+	TLSASelectorCert TLSASelector = 0
+	TLSASelectorSPKI TLSASelector = 1
+)
+
+func (s TLSASelector) String() string {
+	switch s {
+	case TLSASelectorCert:
+		return "Cert"
+	case TLSASelectorSPKI:
+		return "SPKI"
+	default:
+		return strconv.Itoa(int(s))
+	}
+}
+
+// TLSAMatchingType is the matching type field of a TLSA record (RFC 6698 Section 2.1.3).
+type TLSAMatchingType uint8
+
+const (
+	TLSAMatchingTypeFull   TLSAMatchingType = 0
+	TLSAMatchingTypeSHA256 TLSAMatchingType = 1
+	TLSAMatchingTypeSHA512 TLSAMatchingType = 2
+)
+
+func (m TLSAMatchingType) String() string {
+	switch m {
+	case TLSAMatchingTypeFull:
+		return "Full"
+	case TLSAMatchingTypeSHA256:
+		return "SHA2-256"
+	case TLSAMatchingTypeSHA512:
+		return "SHA2-512"
+	default:
+		return strconv.Itoa(int(m))
+	}
+}
+
+// TLSARecord is a parsed TLSA record, as defined in RFC 6698 Section 2.
+type TLSARecord struct {
+	Usage        TLSAUsage
+	Selector     TLSASelector
+	MatchingType TLSAMatchingType
+	// The certificate association data, as lowercase hexadecimal.
+	CertificateAssociationData string
+}
+
+// ParseTLSARecord parses a TLSA record in presentation format, e.g.
+// "3 1 1 0123...". A leading owner name, TTL, class and TLSA type, as in
+// "_25._tcp.mx.example.com. 3600 IN TLSA 3 1 1 0123...", are skipped. The
+// certificate association data may be split by whitespace.
+func ParseTLSARecord(value string) (TLSARecord, error) {
+	fields := strings.Fields(value)
+	if index := slices.IndexFunc(fields, func(field string) bool { return strings.EqualFold(field, "TLSA") }); index >= 0 {
+		fields = fields[index+1:]
+	}
+
+	if len(fields) < 4 {
+		return TLSARecord{}, fmt.Errorf("%w: TLSA record %q needs 4 fields", ErrInvalidPolicyString, value)
+	}
+
+	var numbers [3]uint8
+	for i := range numbers {
+		number, err := strconv.ParseUint(fields[i], 10, 8)
+		if err != nil {
+			return TLSARecord{}, fmt.Errorf("%w: TLSA record %q has an invalid field %q", ErrInvalidPolicyString, value, fields[i])
+		}
+		numbers[i] = uint8(number)
+	}
+
+	data := strings.ToLower(strings.Join(fields[3:], ""))
+	if _, err := hex.DecodeString(data); err != nil {
+		return TLSARecord{}, fmt.Errorf("%w: TLSA record %q has invalid certificate association data", ErrInvalidPolicyString, value)
+	}
+
+	return TLSARecord{
+		Usage:                      TLSAUsage(numbers[0]),
+		Selector:                   TLSASelector(numbers[1]),
+		MatchingType:               TLSAMatchingType(numbers[2]),
+		CertificateAssociationData: data,
+	}, nil
+}
+
+func (r TLSARecord) String() string {
+	return fmt.Sprintf("%d %d %d %s", r.Usage, r.Selector, r.MatchingType, r.CertificateAssociationData)
+}
+
+// STSPolicy parses the policy-string of an "sts" policy.
+func (p Policy) STSPolicy() (STSPolicy, error) {
+	if p.PolicyType != "sts" {
+		return STSPolicy{}, fmt.Errorf("%w: policy type is %q, want sts", ErrInvalidPolicyString, p.PolicyType)
+	}
+
+	return ParseSTSPolicy(p.PolicyString)
+}
+
+// TLSARecords parses the policy-string of a "tlsa" policy, one record per entry.
+func (p Policy) TLSARecords() ([]TLSARecord, error) {
+	if p.PolicyType != "tlsa" {
+		return nil, fmt.Errorf("%w: policy type is %q, want tlsa", ErrInvalidPolicyString, p.PolicyType)
+	}
+
+	records := make([]TLSARecord, 0, len(p.PolicyString))
+	for _, entry := range p.PolicyString {
+		record, err := ParseTLSARecord(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,185 @@
+package tlsrpt_test
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+func TestPolicySTSPolicy(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures := []string{
+		"google.com!example.com!1747094400!1747180799!001.json",
+		"microsoft.com!example.com!1739750400!1739836799!133843802167204397.json",
+		"mimecast.org!example.com!1742860800!1742947199!c7115a9ad9266efcddcb560fc5963028de5d922f5d1bf878e8ec82a8d6a5cf2c.json",
+		"ppatk.go.id!example.com!1740960000!1741046399.json",
+	}
+
+	published := tlsrpt.STSPolicy{
+		Version: "STSv1",
+		Mode:    tlsrpt.STSModeTesting,
+		MX:      []string{"incoming-smtp-3.example.com", "incoming-smtp-2.example.com", "INCOMING-SMTP-1.example.com"},
+		MaxAge:  7 * 24 * time.Hour,
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture, func(t *testing.T) {
+			file, err := os.Open(path.Join(pwd, "../testdata/tlsrpt", fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			report, err := tlsrpt.ParseReport(file, tlsrpt.CompressionTypeNone)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			policy, err := report.Policies[0].Policy.STSPolicy()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if policy.Mode != tlsrpt.STSModeTesting {
+				t.Errorf("Mode = %s, want testing", policy.Mode)
+			}
+			if len(policy.MX) != 3 {
+				t.Errorf("len(MX) = %d, want 3", len(policy.MX))
+			}
+			if policy.MaxAge != 7*24*time.Hour {
+				t.Errorf("MaxAge = %s, want 168h", policy.MaxAge)
+			}
+			if !policy.Equal(published) {
+				t.Errorf("Equal() = false, want true for %v", policy)
+			}
+		})
+	}
+}
+
+func TestParseSTSPolicy(t *testing.T) {
+	t.Run("single entry with line breaks", func(t *testing.T) {
+		policy, err := tlsrpt.ParseSTSPolicy([]string{"version: STSv1\r\nmode: enforce\r\nmx: *.example.com\r\nmax_age: 86400\r\n"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if policy.Mode != tlsrpt.STSModeEnforce {
+			t.Errorf("Mode = %s, want enforce", policy.Mode)
+		}
+		if len(policy.MX) != 1 || policy.MX[0] != "*.example.com" {
+			t.Errorf("MX = %v, want [*.example.com]", policy.MX)
+		}
+
+		lines := policy.Lines()
+		if len(lines) != 4 || lines[3] != "max_age: 86400" {
+			t.Errorf("Lines() = %v", lines)
+		}
+	})
+
+	t.Run("stale policy", func(t *testing.T) {
+		cached, err := tlsrpt.ParseSTSPolicy([]string{"version: STSv1", "mode: testing", "mx: mx1.example.com", "max_age: 86400"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		current, err := tlsrpt.ParseSTSPolicy([]string{"version: STSv1", "mode: enforce", "mx: mx1.example.com", "max_age: 86400"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if cached.Equal(current) {
+			t.Error("Equal() = true, want false")
+		}
+	})
+
+	invalid := map[string][]string{
+		"wrong version":    {"version: STSv2", "mode: none", "max_age: 86400"},
+		"invalid mode":     {"version: STSv1", "mode: strict", "mx: mx1.example.com", "max_age: 86400"},
+		"missing mx":       {"version: STSv1", "mode: enforce", "max_age: 86400"},
+		"missing max_age":  {"version: STSv1", "mode: none"},
+		"max_age too big":  {"version: STSv1", "mode: none", "max_age: 31557601"},
+		"max_age overflow": {"version: STSv1", "mode: none", "max_age: 18446744074"},
+		"not key value":    {"version STSv1"},
+	}
+	for name, lines := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := tlsrpt.ParseSTSPolicy(lines); !errors.Is(err, tlsrpt.ErrInvalidPolicyString) {
+				t.Errorf("ParseSTSPolicy() error = %v, want %v", err, tlsrpt.ErrInvalidPolicyString)
+			}
+		})
+	}
+}
+
+func TestParseTLSARecord(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  tlsrpt.TLSARecord
+	}{
+		{
+			name:  "presentation format",
+			value: "3 1 1 1F850A337E6DB9C609C522D136A475638CC5D3C7BDEB1A1564B6CCB0F9BC0E90",
+			want: tlsrpt.TLSARecord{
+				Usage:                      tlsrpt.TLSAUsageDANEEE,
+				Selector:                   tlsrpt.TLSASelectorSPKI,
+				MatchingType:               tlsrpt.TLSAMatchingTypeSHA256,
+				CertificateAssociationData: "1f850a337e6db9c609c522d136a475638cc5d3c7bdeb1a1564b6ccb0f9bc0e90",
+			},
+		},
+		{
+			name:  "resource record with split data",
+			value: "_25._tcp.mx.example.com. 3600 IN TLSA 2 0 1 1F850A337E6DB9C609C522D136A47563 8CC5D3C7BDEB1A1564B6CCB0F9BC0E90",
+			want: tlsrpt.TLSARecord{
+				Usage:                      tlsrpt.TLSAUsageDANETA,
+				Selector:                   tlsrpt.TLSASelectorCert,
+				MatchingType:               tlsrpt.TLSAMatchingTypeSHA256,
+				CertificateAssociationData: "1f850a337e6db9c609c522d136a475638cc5d3c7bdeb1a1564b6ccb0f9bc0e90",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tlsrpt.ParseTLSARecord(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTLSARecord() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("policy", func(t *testing.T) {
+		policy := tlsrpt.Policy{PolicyType: "tlsa", PolicyString: []string{"3 1 1 abcdef", "3 0 2 0123"}}
+		records, err := policy.TLSARecords()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 2 || records[1].MatchingType != tlsrpt.TLSAMatchingTypeSHA512 {
+			t.Errorf("TLSARecords() = %v", records)
+		}
+		if records[0].Usage.String() != "DANE-EE" {
+			t.Errorf("Usage = %s, want DANE-EE", records[0].Usage)
+		}
+
+		if _, err := policy.STSPolicy(); !errors.Is(err, tlsrpt.ErrInvalidPolicyString) {
+			t.Errorf("STSPolicy() error = %v, want %v", err, tlsrpt.ErrInvalidPolicyString)
+		}
+	})
+
+	for _, value := range []string{"3 1 1", "3 1 x abcdef", "3 1 1 nothex", "256 1 1 abcdef"} {
+		t.Run(value, func(t *testing.T) {
+			if _, err := tlsrpt.ParseTLSARecord(value); !errors.Is(err, tlsrpt.ErrInvalidPolicyString) {
+				t.Errorf("ParseTLSARecord(%s) error = %v, want %v", value, err, tlsrpt.ErrInvalidPolicyString)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package tlsrpt
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Remediation is the operator guidance for a failure detail, combining its
+// result type and failure reason code.
+type Remediation struct {
+	ResultType *ResultType
+	// The receiving MX the failure is about, empty when not reported.
+	Host string
+	// What to do about the failure.
+	Action string
+}
+
+// String formats the remediation as "result-type on host: action", e.g.
+// "certificate-expired on mx2.example.com: renew the certificate".
+func (r Remediation) String() string {
+	if r.Host == "" {
+		return fmt.Sprintf("%s: %s", r.ResultType.Key(), r.Action)
+	}
+
+	return fmt.Sprintf("%s on %s: %s", r.ResultType.Key(), r.Host, r.Action)
+}
+
+// reasonRemediation refines the action of a result type when the failure
+// reason code matches the pattern. The reason code is free text, so the
+// patterns are anchored on word boundaries and only looked up within the
+// entry of the result type, never across the catalogue.
+type reasonRemediation struct {
+	pattern *regexp.Regexp
+	action  string
+}
+
+type remediationEntry struct {
+	action  string
+	reasons []reasonRemediation
+}
+
+// remediations is the catalogue of actions, keyed by result type.
+var remediations = map[string]remediationEntry{
+	ResultStartTLSNotSupported.Key(): {
+		action: "enable STARTTLS on the MX, or check that no middlebox strips it from the EHLO response",
+	},
+	ResultCertificateHostMismatch.Key(): {
+		action: "issue a certificate that covers the MX hostname in its subject alternative names",
+	},
+	ResultCertificateExpired.Key(): {
+		action: "renew the certificate",
+	},
+	ResultCertificateNotTrusted.Key(): {
+		action: "serve a certificate issued by a publicly trusted CA, including the full intermediate chain",
+		reasons: []reasonRemediation{
+			{
+				pattern: regexp.MustCompile(`\bself[- ]?signed\b|\bx509_v_err_\w*self_signed`),
+				action:  "replace the self-signed certificate with one issued by a publicly trusted CA",
+			},
+			{
+				pattern: regexp.MustCompile(`\bunable to get local issuer\b|\b(?:incomplete|missing|broken) (?:certificate )?chain\b|\bmissing intermediate\b|\bx509_v_err_unable_to_get_issuer_cert`),
+				action:  "serve the full certificate chain, including the intermediate certificates",
+			},
+			{
+				pattern: regexp.MustCompile(`\b(?:certificate )?revoked\b|\bx509_v_err_cert_revoked\b`),
+				action:  "replace the revoked certificate",
+			},
+		},
+	},
+	ResultValidationFailure.Key(): {
+		action: "check the failure reason code reported by the sender",
+		reasons: []reasonRemediation{
+			{
+				pattern: regexp.MustCompile(`\btime(?:d)?[- ]?out\b`),
+				action:  "check that the MX is reachable and answers the TLS handshake in time",
+			},
+			{
+				pattern: regexp.MustCompile(`\b(?:unsupported |wrong )?protocol version\b|\bno shared cipher\b|\bhandshake[ _]failure\b`),
+				action:  "enable TLS 1.2 or later with modern cipher suites on the MX",
+			},
+		},
+	},
+	ResultTLSAInvalid.Key(): {
+		action: "update the TLSA records to match the certificate or public key served by the MX",
+	},
+	ResultDNSSECInvalid.Key(): {
+		action: "fix the DNSSEC signatures of the zone hosting the TLSA records",
+	},
+	ResultDANERequired.Key(): {
+		action: "publish DNSSEC-signed TLSA records for the MX, or ask the sender to stop requiring DANE",
+	},
+	ResultSTSPolicyFetchError.Key(): {
+		action: "the policy host is unreachable or its cert is invalid; check that https://mta-sts.<domain>/.well-known/mta-sts.txt is served with a valid certificate",
+		reasons: []reasonRemediation{
+			{
+				pattern: regexp.MustCompile(`\b404\b|\bnot found\b`),
+				action:  "publish the policy at https://mta-sts.<domain>/.well-known/mta-sts.txt",
+			},
+			{
+				pattern: regexp.MustCompile(`\bnxdomain\b|\bno such host\b|\bdns (?:lookup|resolution|query) (?:error|failed|failure)\b`),
+				action:  "add the DNS record of the mta-sts policy host",
+			},
+			{
+				pattern: regexp.MustCompile(`\bx509\b|\bcertificate (?:verify failed|has expired|is not valid|signed by unknown authority|is valid for)\b|\bbad certificate\b`),
+				action:  "serve the policy host with a valid certificate covering mta-sts.<domain>",
+			},
+		},
+	},
+	ResultSTSPolicyInvalid.Key(): {
+		action: "fix the syntax of the MTA-STS policy: it needs version, mode, max_age and at least one mx line",
+	},
+	ResultSTSWebPKIInvalid.Key(): {
+		action: "serve the MTA-STS policy host with a publicly trusted certificate covering mta-sts.<domain>",
+	},
+}
+
+// Remediate returns the operator guidance for a failure detail of the given
+// policy domain. Unknown result types fall back to the failure reason code.
+func Remediate(policyDomain string, detail FailureDetail) Remediation {
+	remediation := Remediation{
+		ResultType: detail.ResultType,
+		Host:       detail.ReceivingMxHostname,
+	}
+	if remediation.Host == "" {
+		remediation.Host = detail.ReceivingIP
+	}
+
+	entry, ok := remediations[detail.ResultType.Key()]
+	if !ok {
+		remediation.Action = "check the failure reason code reported by the sender"
+		if detail.FailureReasonCode != "" {
+			remediation.Action = fmt.Sprintf("check the failure reason code reported by the sender: %s", detail.FailureReasonCode)
+		}
+
+		return remediation
+	}
+
+	remediation.Action = entry.action
+	reasonCode := strings.ToLower(detail.FailureReasonCode)
+	if reasonCode != "" {
+		for _, reason := range entry.reasons {
+			if reason.pattern.MatchString(reasonCode) {
+				remediation.Action = reason.action
+				break
+			}
+		}
+	}
+
+	if policyDomain != "" {
+		remediation.Action = strings.ReplaceAll(remediation.Action, "<domain>", policyDomain)
+	}
+
+	return remediation
+}
+
+// Remediations returns the operator guidance for every failure detail of the policy.
+func (p TLSPolicy) Remediations() []Remediation {
+	remediations := make([]Remediation, 0, len(p.FailureDetails))
+	for _, detail := range p.FailureDetails {
+		remediations = append(remediations, Remediate(p.Policy.PolicyDomain, detail))
+	}
+
+	return remediations
+}
@@ -0,0 +1,102 @@
+package tlsrpt_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+func TestRemediate(t *testing.T) {
+	tests := []struct {
+		name   string
+		detail tlsrpt.FailureDetail
+		want   string
+	}{
+		{
+			name:   "certificate expired",
+			detail: tlsrpt.FailureDetail{ResultType: tlsrpt.ResultCertificateExpired, ReceivingMxHostname: "mx2.example.com"},
+			want:   "certificate-expired on mx2.example.com: renew the certificate",
+		},
+		{
+			name:   "policy fetch error",
+			detail: tlsrpt.FailureDetail{ResultType: tlsrpt.ResultSTSPolicyFetchError},
+			want:   "sts-policy-fetch-error: the policy host is unreachable or its cert is invalid; check that https://mta-sts.example.com/.well-known/mta-sts.txt is served with a valid certificate",
+		},
+		{
+			name:   "policy not found",
+			detail: tlsrpt.FailureDetail{ResultType: tlsrpt.ResultSTSPolicyFetchError, FailureReasonCode: "HTTP 404 Not Found"},
+			want:   "sts-policy-fetch-error: publish the policy at https://mta-sts.example.com/.well-known/mta-sts.txt",
+		},
+		{
+			name:   "self-signed certificate",
+			detail: tlsrpt.FailureDetail{ResultType: tlsrpt.ResultCertificateNotTrusted, ReceivingIP: "192.0.2.1", FailureReasonCode: "X509_V_ERR_DEPTH_ZERO_SELF_SIGNED_CERT: self signed certificate"},
+			want:   "certificate-not-trusted on 192.0.2.1: replace the self-signed certificate with one issued by a publicly trusted CA",
+		},
+		{
+			name:   "unrelated reason code",
+			detail: tlsrpt.FailureDetail{ResultType: tlsrpt.ResultSTSPolicyFetchError, FailureReasonCode: "connection refused by mtasts-frontend (tls listener)"},
+			want:   "sts-policy-fetch-error: the policy host is unreachable or its cert is invalid; check that https://mta-sts.example.com/.well-known/mta-sts.txt is served with a valid certificate",
+		},
+		{
+			name:   "reason code of another result type",
+			detail: tlsrpt.FailureDetail{ResultType: tlsrpt.ResultCertificateNotTrusted, FailureReasonCode: "certificate chain validation failed"},
+			want:   "certificate-not-trusted: serve a certificate issued by a publicly trusted CA, including the full intermediate chain",
+		},
+		{
+			name:   "policy host certificate",
+			detail: tlsrpt.FailureDetail{ResultType: tlsrpt.ResultSTSPolicyFetchError, FailureReasonCode: "x509: certificate signed by unknown authority"},
+			want:   "sts-policy-fetch-error: serve the policy host with a valid certificate covering mta-sts.example.com",
+		},
+		{
+			name:   "unknown result type",
+			detail: tlsrpt.FailureDetail{ResultType: tlsrpt.ParseResultType("something-new"), FailureReasonCode: "421 try again later"},
+			want:   "something-new: check the failure reason code reported by the sender: 421 try again later",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tlsrpt.Remediate("example.com", tt.detail).String(); got != tt.want {
+				t.Errorf("Remediate() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailureDetailResultType(t *testing.T) {
+	var policy tlsrpt.TLSPolicy
+	err := json.Unmarshal([]byte(`{
+		"policy": {"policy-type": "sts", "policy-domain": "example.com"},
+		"summary": {"total-successful-session-count": 10, "total-failure-session-count": 1},
+		"failure-details": [{"result-type": "certificate-expired", "receiving-mx-hostname": "mx2.example.com", "failed-session-count": 1}]
+	}`), &policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resultType := policy.FailureDetails[0].ResultType
+	if resultType.Key() != tlsrpt.ResultCertificateExpired.Key() {
+		t.Errorf("ResultType = %s, want certificate-expired", resultType)
+	}
+	if resultType.Category() != tlsrpt.CategoryPolicyFailure {
+		t.Errorf("Category() = %s, want %s", resultType.Category(), tlsrpt.CategoryPolicyFailure)
+	}
+
+	remediations := policy.Remediations()
+	if len(remediations) != 1 || remediations[0].String() != "certificate-expired on mx2.example.com: renew the certificate" {
+		t.Errorf("Remediations() = %v", remediations)
+	}
+
+	content, err := json.Marshal(policy.FailureDetails[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var roundTrip map[string]any
+	if err := json.Unmarshal(content, &roundTrip); err != nil {
+		t.Fatal(err)
+	}
+	if roundTrip["result-type"] != "certificate-expired" {
+		t.Errorf("result-type = %v, want certificate-expired", roundTrip["result-type"])
+	}
+}
@@ -9,19 +9,36 @@ type ResultType struct {
 }
 
 func (r *ResultType) Category() ResultTypeCategory {
+	if r == nil {
+		return ""
+	}
+
 	return r.category
 }
 
 func (r *ResultType) Key() string {
+	if r == nil {
+		return ""
+	}
+
 	return r.key
 }
 
 func (r *ResultType) Detail() string {
+	if r == nil {
+		return ""
+	}
+
 	return r.detail
 }
 
+// Known reports whether the result type is one of the result types of RFC 8460.
+func (r *ResultType) Known() bool {
+	return r != nil && r.detail != ""
+}
+
 func (r *ResultType) String() string {
-	return r.key
+	return r.Key()
 }
 
 func (r ResultType) MarshalJSON() ([]byte, error) {
@@ -16,7 +16,7 @@ type Policy struct {
 	// An encoding of the applied policy as a JSON array
 	// of strings, whether it's a TLSA record ([RFC6698], Section 2.3) or
 	// an MTA-STS Policy.  Examples follow in the next section.
-	PolicyString []string `json:"policy-string"`
+	PolicyString []string `json:"policy-string,omitempty"`
 	// The Policy Domain against which the MTA-STS or DANE
 	// policy is defined.  In the case of Internationalized Domain Names
 	// [RFC5891], the domain MUST consist of the Punycode-encoded
@@ -29,7 +29,7 @@ type Policy struct {
 	// [RFC8461].  In the case of Internationalized Domain Names
 	// [RFC5891], the domain MUST consist of the Punycode-encoded
 	// A-labels [RFC3492] and not the U-labels.
-	MxHost []string `json:"mx-host"`
+	MxHost []string `json:"mx-host,omitempty"`
 }
 
 type Summary struct {
@@ -44,21 +44,21 @@ type Summary struct {
 }
 
 type FailureDetail struct {
-	ResultType   string `json:"result-type"`
-	SendingMTAIP string `json:"sending-mta-ip"`
+	ResultType   *ResultType `json:"result-type"`
+	SendingMTAIP string      `json:"sending-mta-ip,omitempty"`
 	// The hostname of the receiving MTA MX
 	// record with which the Sending MTA attempted to negotiate a
 	// STARTTLS connection.
-	ReceivingMxHostname string `json:"receiving-mx-hostname"`
+	ReceivingMxHostname string `json:"receiving-mx-hostname,omitempty"`
 	// The HELLO (HELO) or Extended HELLO
 	// (EHLO) string from the banner announced during the reported
 	// session.
-	ReceivingMxHelo string `json:"receiving-mx-helo"`
+	ReceivingMxHelo string `json:"receiving-mx-helo,omitempty"`
 	// The destination IP address that was used when
 	// creating the outbound session.  It is provided as a string
 	// representation of an IPv4 (see below) or IPv6 [RFC5952] address in
 	// dot-decimal or colon-hexadecimal notation.
-	ReceivingIP string `json:"receiving-ip"`
+	ReceivingIP string `json:"receiving-ip,omitempty"`
 	// The number of (attempted) sessions that
 	// match the relevant "result-type" for this section (an integer,
 	// encoded as a JSON number).
@@ -67,16 +67,16 @@ type FailureDetail struct {
 	// additional information around the relevant "result-type".  For
 	// example, this URI might host the complete certificate chain
 	// presented during an attempted STARTTLS session.
-	AdditionalInformation string `json:"additional-information"`
+	AdditionalInformation string `json:"additional-information,omitempty"`
 	// A text field to include a TLS-related error
 	// code or error message.
-	FailureReasonCode string `json:"failure-reason-code"`
+	FailureReasonCode string `json:"failure-reason-code,omitempty"`
 }
 
 type TLSPolicy struct {
 	Policy         Policy          `json:"policy"`
 	Summary        Summary         `json:"summary"`
-	FailureDetails []FailureDetail `json:"failure-details"`
+	FailureDetails []FailureDetail `json:"failure-details,omitempty"`
 }
 
 type Report struct {
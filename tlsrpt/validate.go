@@ -0,0 +1,121 @@
+package tlsrpt
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/aldy505/mailweave/internal/validation"
+)
+
+// FieldError describes a problem found on a single field of a report. Field
+// is the path to the offending element, e.g. "policies[0].summary.total-failure-session-count".
+type FieldError = validation.FieldError
+
+// ValidationResult is the outcome of Validate. Errors are problems that make
+// the report unusable, while Warnings are suspicious but tolerable values.
+type ValidationResult = validation.Result
+
+// Validate checks the semantics of a parsed report, on top of the syntax
+// checks done by the parser. A nil report is reported as an error.
+func Validate(report *Report) ValidationResult {
+	var v ValidationResult
+	if report == nil {
+		v.AddError("report", "is nil")
+		return v
+	}
+
+	if report.OrganizationName == "" {
+		v.AddError("organization-name", "must not be empty")
+	}
+	if report.ReportID == "" {
+		v.AddError("report-id", "must not be empty")
+	}
+	if report.ContactInfo == "" {
+		v.AddWarning("contact-info", "is empty")
+	}
+
+	start, end := report.DateRange.StartDateTime, report.DateRange.EndDateTime
+	switch {
+	case start.IsZero():
+		v.AddError("date-range.start-datetime", "must not be empty")
+	case end.IsZero():
+		v.AddError("date-range.end-datetime", "must not be empty")
+	case end.Before(start):
+		v.AddError("date-range.end-datetime", "%s is before start-datetime %s", end, start)
+	default:
+		utcStart := start.UTC()
+		if utcStart.Hour() != 0 || utcStart.Minute() != 0 || utcStart.Second() != 0 {
+			v.AddWarning("date-range.start-datetime", "does not start a full UTC day")
+		}
+	}
+
+	if len(report.Policies) == 0 {
+		v.AddError("policies", "must contain at least one policy")
+	}
+
+	for i, policy := range report.Policies {
+		validatePolicy(&v, fmt.Sprintf("policies[%d]", i), policy)
+	}
+
+	return v
+}
+
+func validatePolicy(v *ValidationResult, field string, policy TLSPolicy) {
+	switch policy.Policy.PolicyType {
+	case "sts", "tlsa":
+		if len(policy.Policy.PolicyString) == 0 {
+			v.AddWarning(field+".policy.policy-string", "is empty for policy-type %s", policy.Policy.PolicyType)
+		}
+	case "no-policy-found":
+	default:
+		v.AddError(field+".policy.policy-type", "unknown policy type %q", policy.Policy.PolicyType)
+	}
+	if policy.Policy.PolicyDomain == "" {
+		v.AddError(field+".policy.policy-domain", "must not be empty")
+	}
+
+	summary := policy.Summary
+	if summary.TotalSuccessfulSessionCount < 0 {
+		v.AddError(field+".summary.total-successful-session-count", "must not be negative, got %d", summary.TotalSuccessfulSessionCount)
+	}
+	if summary.TotalFailureSessionCount < 0 {
+		v.AddError(field+".summary.total-failure-session-count", "must not be negative, got %d", summary.TotalFailureSessionCount)
+	}
+
+	var failedSessions int64
+	for j, detail := range policy.FailureDetails {
+		detailField := fmt.Sprintf("%s.failure-details[%d]", field, j)
+
+		if detail.ResultType.Key() == "" {
+			v.AddError(detailField+".result-type", "must not be empty")
+		} else if !detail.ResultType.Known() {
+			v.AddWarning(detailField+".result-type", "unknown result type %q", detail.ResultType.Key())
+		}
+		if detail.FailedSessionCount < 0 {
+			v.AddError(detailField+".failed-session-count", "must not be negative, got %d", detail.FailedSessionCount)
+		}
+		if detail.SendingMTAIP != "" {
+			if _, err := netip.ParseAddr(detail.SendingMTAIP); err != nil {
+				v.AddError(detailField+".sending-mta-ip", "invalid IP address %q", detail.SendingMTAIP)
+			}
+		}
+		if detail.ReceivingIP != "" {
+			if _, err := netip.ParseAddr(detail.ReceivingIP); err != nil {
+				v.AddError(detailField+".receiving-ip", "invalid IP address %q", detail.ReceivingIP)
+			}
+		}
+
+		failedSessions += detail.FailedSessionCount
+	}
+
+	// Reporters may omit failure details, but they can never account for
+	// more sessions than the summary.
+	switch {
+	case failedSessions > summary.TotalFailureSessionCount:
+		v.AddError(field+".failure-details", "failed-session-count adds up to %d, more than total-failure-session-count %d", failedSessions, summary.TotalFailureSessionCount)
+	case len(policy.FailureDetails) > 0 && failedSessions < summary.TotalFailureSessionCount:
+		v.AddWarning(field+".failure-details", "failed-session-count adds up to %d, less than total-failure-session-count %d", failedSessions, summary.TotalFailureSessionCount)
+	case len(policy.FailureDetails) == 0 && summary.TotalFailureSessionCount > 0:
+		v.AddWarning(field+".failure-details", "missing for %d failed sessions", summary.TotalFailureSessionCount)
+	}
+}
@@ -0,0 +1,146 @@
+package tlsrpt_test
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+func TestValidate(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("fixtures", func(t *testing.T) {
+		entries, err := os.ReadDir(path.Join(pwd, "../testdata/tlsrpt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, entry := range entries {
+			if !strings.HasSuffix(entry.Name(), ".json") {
+				continue
+			}
+
+			t.Run(entry.Name(), func(t *testing.T) {
+				f, err := os.Open(path.Join(pwd, "../testdata/tlsrpt", entry.Name()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+
+				report, err := tlsrpt.ParseReport(f, tlsrpt.CompressionTypeNone)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				result := tlsrpt.Validate(report)
+				if !result.Valid() {
+					t.Errorf("Validate() = %v, want no errors", result.Err())
+				}
+			})
+		}
+	})
+
+	t.Run("invalid report", func(t *testing.T) {
+		const report = `{
+  "organization-name": "Example",
+  "date-range": {
+    "start-datetime": "2025-05-13T00:00:00Z",
+    "end-datetime": "2025-05-12T23:59:59Z"
+  },
+  "contact-info": "tlsrpt@example.org",
+  "report-id": "1",
+  "policies": [
+    {
+      "policy": {
+        "policy-type": "dane",
+        "policy-domain": "example.com"
+      },
+      "summary": {
+        "total-successful-session-count": 10,
+        "total-failure-session-count": 3
+      },
+      "failure-details": [
+        {
+          "result-type": "certificate-expired",
+          "sending-mta-ip": "not-an-ip",
+          "failed-session-count": 5
+        }
+      ]
+    }
+  ]
+}`
+
+		parsed, err := tlsrpt.ParseReport(strings.NewReader(report), tlsrpt.CompressionTypeNone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := tlsrpt.Validate(parsed)
+		if result.Valid() {
+			t.Fatal("Valid() = true, want false")
+		}
+
+		wantErrors := []string{
+			"date-range.end-datetime",
+			"policies[0].policy.policy-type",
+			"policies[0].failure-details[0].sending-mta-ip",
+			"policies[0].failure-details",
+		}
+		if len(result.Errors) != len(wantErrors) {
+			t.Fatalf("Errors = %v, want errors on %v", result.Errors, wantErrors)
+		}
+		for i, field := range wantErrors {
+			if result.Errors[i].Field != field {
+				t.Errorf("Errors[%d].Field = %s, want %s", i, result.Errors[i].Field, field)
+			}
+		}
+	})
+
+	t.Run("missing failure details", func(t *testing.T) {
+		report := &tlsrpt.Report{
+			OrganizationName: "Example",
+			ContactInfo:      "tlsrpt@example.org",
+			ReportID:         "1",
+			Policies: []tlsrpt.TLSPolicy{
+				{
+					Policy:  tlsrpt.Policy{PolicyType: "no-policy-found", PolicyDomain: "example.com"},
+					Summary: tlsrpt.Summary{TotalFailureSessionCount: 2},
+				},
+			},
+		}
+		report.DateRange.StartDateTime = mustParseTime(t, "2025-05-13T00:00:00Z")
+		report.DateRange.EndDateTime = mustParseTime(t, "2025-05-13T23:59:59Z")
+
+		result := tlsrpt.Validate(report)
+		if !result.Valid() {
+			t.Fatalf("Validate() = %v, want no errors", result.Err())
+		}
+		if len(result.Warnings) != 1 || result.Warnings[0].Field != "policies[0].failure-details" {
+			t.Errorf("Warnings = %v, want a warning on policies[0].failure-details", result.Warnings)
+		}
+	})
+
+	t.Run("nil report", func(t *testing.T) {
+		if result := tlsrpt.Validate(nil); result.Valid() {
+			t.Error("Valid() = true, want false")
+		}
+	})
+}
+
+func mustParseTime(t *testing.T, value string) time.Time {
+	t.Helper()
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return parsed
+}
@@ -0,0 +1,111 @@
+package mailweave
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/aldy505/mailweave/dmarc"
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+// NewTlsRptReport converts a parsed TLS-RPT report into a TlsRptReport, with
+// one row per policy.
+//
+// The fields that do not come from the report itself (DomainOwner, ReceivedAt,
+// EmailSender, EmailSubject, ReportFileName and Content) are left for the
+// caller to fill in. The ReportFileName should be set with SetReportFileName,
+// which cross-checks it with ReportFileNameMismatches.
+func NewTlsRptReport(report *tlsrpt.Report) TlsRptReport {
+	tlsRptReport := TlsRptReport{
+		OrganizationName: report.OrganizationName,
+		DomainName:       contactDomain(report.ContactInfo),
+		ReportId:         report.ReportID,
+		ExtraContactInfo: report.ContactInfo,
+		RangeStart:       report.DateRange.StartDateTime,
+		RangeEnd:         report.DateRange.EndDateTime,
+		Rows:             make([]TlsRptReportRow, 0, len(report.Policies)),
+	}
+
+	for _, policy := range report.Policies {
+		row := NewTlsRptReportRow(policy)
+		tlsRptReport.TotalNumberOfSessions += row.SuccessfulSessionCount + row.FailedSessionCount
+		tlsRptReport.Rows = append(tlsRptReport.Rows, row)
+	}
+
+	return tlsRptReport
+}
+
+// NewTlsRptReportRow converts a single policy of a TLS-RPT report into a
+// TlsRptReportRow. The IPAddress is taken from the failure details, as the
+// report has no address per policy: it is the first receiving-ip, or the first
+// sending-mta-ip when no receiving-ip was reported. It is empty for a policy
+// without failures.
+func NewTlsRptReportRow(policy tlsrpt.TLSPolicy) TlsRptReportRow {
+	row := TlsRptReportRow{
+		DomainName:             policy.Policy.PolicyDomain,
+		PolicyType:             policy.Policy.PolicyType,
+		PolicyString:           policy.Policy.PolicyString,
+		MxHost:                 policy.Policy.MxHost,
+		SuccessfulSessionCount: policy.Summary.TotalSuccessfulSessionCount,
+		FailedSessionCount:     policy.Summary.TotalFailureSessionCount,
+		FailureDetails:         make([]TlsRptFailureDetail, 0, len(policy.FailureDetails)),
+	}
+
+	for _, detail := range policy.FailureDetails {
+		row.FailureDetails = append(row.FailureDetails, TlsRptFailureDetail{
+			ResultType:          detail.ResultType.Key(),
+			SendingMTAIP:        detail.SendingMTAIP,
+			ReceivingMxHostname: detail.ReceivingMxHostname,
+			ReceivingMxHelo:     detail.ReceivingMxHelo,
+			ReceivingIP:         detail.ReceivingIP,
+			FailedSessionCount:  detail.FailedSessionCount,
+			FailureReasonCode:   detail.FailureReasonCode,
+			Remediation:         tlsrpt.Remediate(policy.Policy.PolicyDomain, detail).String(),
+		})
+	}
+
+	row.IPAddress = failureDetailsIPAddress(policy.FailureDetails)
+
+	return row
+}
+
+func failureDetailsIPAddress(details []tlsrpt.FailureDetail) string {
+	for _, detail := range details {
+		if detail.ReceivingIP != "" {
+			return detail.ReceivingIP
+		}
+	}
+
+	for _, detail := range details {
+		if detail.SendingMTAIP != "" {
+			return detail.SendingMTAIP
+		}
+	}
+
+	return ""
+}
+
+// contactDomain derives the organizational domain of the reporter from its
+// contact-info, which is usually an email address, but may be a URI.
+func contactDomain(contactInfo string) string {
+	contactInfo = strings.TrimSpace(contactInfo)
+	if u, err := url.Parse(contactInfo); err == nil && u.Host != "" {
+		return dmarc.OrganizationalDomain(u.Hostname())
+	}
+
+	_, domain, ok := strings.Cut(strings.TrimPrefix(contactInfo, "mailto:"), "@")
+	if !ok {
+		return ""
+	}
+
+	return dmarc.OrganizationalDomain(domain)
+}
+
+// NewTlsRptDnsRecord converts a TLS-RPT record parsed with
+// tlsrpt.ParseDNSRecords into a TlsRptDnsRecord.
+func NewTlsRptDnsRecord(record tlsrpt.DNSRecord) TlsRptDnsRecord {
+	return TlsRptDnsRecord{
+		ReportsMailTransport:  record.MailtoURIs,
+		ReportsHttpsTransport: record.HTTPSURIs,
+	}
+}
@@ -0,0 +1,131 @@
+package mailweave_test
+
+import (
+	"os"
+	"path"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aldy505/mailweave"
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+func TestNewTlsRptReport(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("microsoft json", func(t *testing.T) {
+		filename := "microsoft.com!example.com!1739750400!1739836799!133843802167204397.json"
+		f, err := os.Open(path.Join(pwd, "testdata/tlsrpt", filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		parsed, err := tlsrpt.ParseReport(f, tlsrpt.CompressionTypeNone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		report := mailweave.NewTlsRptReport(parsed)
+		if report.OrganizationName != "Microsoft Corporation" {
+			t.Errorf("OrganizationName = %s, want Microsoft Corporation", report.OrganizationName)
+		}
+		if report.DomainName != "microsoft.com" {
+			t.Errorf("DomainName = %s, want microsoft.com", report.DomainName)
+		}
+		if !report.RangeEnd.Equal(time.Date(2025, time.February, 17, 23, 59, 59, 0, time.UTC)) {
+			t.Errorf("RangeEnd = %s, want 2025-02-17 23:59:59 UTC", report.RangeEnd)
+		}
+		if report.TotalNumberOfSessions != 21 {
+			t.Errorf("TotalNumberOfSessions = %d, want 21", report.TotalNumberOfSessions)
+		}
+		if len(report.Rows) != 1 {
+			t.Fatalf("len(Rows) = %d, want 1", len(report.Rows))
+		}
+
+		row := report.Rows[0]
+		if row.DomainName != "example.com" || row.PolicyType != "sts" {
+			t.Errorf("Row = %s %s, want example.com sts", row.DomainName, row.PolicyType)
+		}
+		if row.IPAddress != "192.0.2.4" {
+			t.Errorf("IPAddress = %s, want 192.0.2.4", row.IPAddress)
+		}
+		if row.FailedSessionCount != 21 {
+			t.Errorf("FailedSessionCount = %d, want 21", row.FailedSessionCount)
+		}
+		if len(row.FailureDetails) != 1 {
+			t.Fatalf("len(FailureDetails) = %d, want 1", len(row.FailureDetails))
+		}
+
+		detail := row.FailureDetails[0]
+		if detail.ResultType != "certificate-host-mismatch" {
+			t.Errorf("ResultType = %s, want certificate-host-mismatch", detail.ResultType)
+		}
+		if detail.ReceivingMxHostname != "outgoing-smtp-2.example.com" || detail.ReceivingIP != "192.0.2.4" {
+			t.Errorf("Receiving = %s %s, want outgoing-smtp-2.example.com 192.0.2.4", detail.ReceivingMxHostname, detail.ReceivingIP)
+		}
+		if detail.Remediation == "" {
+			t.Error("Remediation is empty")
+		}
+
+		report.SetReportFileName(filename)
+		if len(report.ReportFileNameMismatches) != 0 {
+			t.Errorf("ReportFileNameMismatches = %v, want none", report.ReportFileNameMismatches)
+		}
+	})
+
+	t.Run("google json without failures", func(t *testing.T) {
+		f, err := os.Open(path.Join(pwd, "testdata/tlsrpt/google.com!example.com!1747094400!1747180799!001.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		parsed, err := tlsrpt.ParseReport(f, tlsrpt.CompressionTypeNone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		report := mailweave.NewTlsRptReport(parsed)
+		if report.DomainName != "google.com" {
+			t.Errorf("DomainName = %s, want google.com", report.DomainName)
+		}
+		if report.TotalNumberOfSessions != parsed.Policies[0].Summary.TotalSuccessfulSessionCount+parsed.Policies[0].Summary.TotalFailureSessionCount {
+			t.Errorf("TotalNumberOfSessions = %d, want the sum of the summary", report.TotalNumberOfSessions)
+		}
+		if len(report.Rows[0].FailureDetails) != 0 {
+			t.Errorf("len(FailureDetails) = %d, want 0", len(report.Rows[0].FailureDetails))
+		}
+		if report.Rows[0].IPAddress != "" {
+			t.Errorf("IPAddress = %s, want empty", report.Rows[0].IPAddress)
+		}
+	})
+
+	t.Run("sending mta ip", func(t *testing.T) {
+		row := mailweave.NewTlsRptReportRow(tlsrpt.TLSPolicy{
+			FailureDetails: []tlsrpt.FailureDetail{{ResultType: tlsrpt.ResultValidationFailure, SendingMTAIP: "198.51.100.7"}},
+		})
+		if row.IPAddress != "198.51.100.7" {
+			t.Errorf("IPAddress = %s, want 198.51.100.7", row.IPAddress)
+		}
+	})
+}
+
+func TestNewTlsRptDnsRecord(t *testing.T) {
+	record, err := tlsrpt.ParseDNSRecord("v=TLSRPTv1; rua=mailto:tlsrpt@example.com,https://reports.example.com/tlsrpt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dnsRecord := mailweave.NewTlsRptDnsRecord(record)
+	if !slices.Equal(dnsRecord.ReportsMailTransport, []string{"mailto:tlsrpt@example.com"}) {
+		t.Errorf("ReportsMailTransport = %v, want [mailto:tlsrpt@example.com]", dnsRecord.ReportsMailTransport)
+	}
+	if !slices.Equal(dnsRecord.ReportsHttpsTransport, []string{"https://reports.example.com/tlsrpt"}) {
+		t.Errorf("ReportsHttpsTransport = %v, want [https://reports.example.com/tlsrpt]", dnsRecord.ReportsHttpsTransport)
+	}
+}
@@ -0,0 +1,117 @@
+package mailweave
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aldy505/mailweave/mailer"
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+// defaultTlsRptHTTPClient sends the reports of https destinations when no
+// client is configured. Unlike http.DefaultClient, it gives up on a slow
+// destination instead of blocking the delivery forever.
+var defaultTlsRptHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// Mailer sends emails. It is implemented by *mailer.SMTP.
+type Mailer interface {
+	Send(ctx context.Context, message mailer.Message) error
+}
+
+// TlsRptReportDelivery delivers the TLS-RPT reports generated by a
+// tlsrpt.Builder to the destinations of the policy domain, as described in
+// RFC 8460 Section 5.
+type TlsRptReportDelivery struct {
+	// The domain of the organization sending the reports, used in the report
+	// filename and the TLS-Report-Submitter header field.
+	Sender string
+	// Sends the reports of mailto destinations.
+	Mailer Mailer
+	// Sends the reports of https destinations. Defaults to a client with a
+	// 30 seconds timeout.
+	HTTPClient *http.Client
+}
+
+// Deliver sends the report to every destination of the TLS-RPT record of its
+// policy domain. It tries every destination, and returns the errors of the
+// failed ones. A nil report is an error.
+func (d *TlsRptReportDelivery) Deliver(ctx context.Context, record TlsRptDnsRecord, report *tlsrpt.Report) error {
+	filename, content, err := tlsrpt.PackageReport(d.Sender, report)
+	if err != nil {
+		return err
+	}
+
+	policyDomain := report.Policies[0].Policy.PolicyDomain
+
+	var errs []error
+	for _, destination := range record.ReportsMailTransport {
+		address := tlsrpt.MailtoAddress(destination)
+		if address == "" {
+			errs = append(errs, fmt.Errorf("delivering to %s: not a valid mailto URI", destination))
+			continue
+		}
+
+		err := d.sendMail(ctx, address, policyDomain, report.ReportID, filename, content)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("delivering to %s: %w", destination, err))
+		}
+	}
+
+	for _, destination := range record.ReportsHttpsTransport {
+		err := d.post(ctx, destination, content)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("delivering to %s: %w", destination, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (d *TlsRptReportDelivery) sendMail(ctx context.Context, address string, policyDomain string, reportId string, filename string, content []byte) error {
+	if d.Mailer == nil {
+		return errors.New("no mailer configured")
+	}
+
+	return d.Mailer.Send(ctx, mailer.Message{
+		To:      []string{address},
+		Subject: fmt.Sprintf("Report Domain: %s Submitter: %s Report-ID: <%s>", policyDomain, d.Sender, reportId),
+		Headers: map[string]string{
+			"TLS-Report-Domain":    policyDomain,
+			"TLS-Report-Submitter": d.Sender,
+		},
+		Body:       fmt.Sprintf("This is an aggregate TLS report from %s for %s.", d.Sender, policyDomain),
+		ReportType: "tlsrpt",
+		Attachments: []mailer.Attachment{
+			{Filename: filename, ContentType: tlsrpt.MediaTypeGZIP, Content: content},
+		},
+	})
+}
+
+func (d *TlsRptReportDelivery) post(ctx context.Context, url string, content []byte) error {
+	client := d.HTTPClient
+	if client == nil {
+		client = defaultTlsRptHTTPClient
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	request.Header.Set("Content-Type", tlsrpt.MediaTypeGZIP)
+
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", response.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package mailweave_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aldy505/mailweave"
+	"github.com/aldy505/mailweave/mailer"
+	"github.com/aldy505/mailweave/tlsrpt"
+)
+
+type fakeMailer struct {
+	messages []mailer.Message
+}
+
+func (f *fakeMailer) Send(ctx context.Context, message mailer.Message) error {
+	f.messages = append(f.messages, message)
+	return nil
+}
+
+func TestTlsRptReportDelivery(t *testing.T) {
+	var received []byte
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		received, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	builder := &tlsrpt.Builder{OrganizationName: "Example Net", ContactInfo: "tlsrpt@example.net"}
+	builder.Add(tlsrpt.Session{
+		Time:   time.Date(2025, time.May, 13, 10, 0, 0, 0, time.UTC),
+		Policy: tlsrpt.Policy{PolicyType: "no-policy-found", PolicyDomain: "example.com"},
+	})
+	reports := builder.Flush(time.Date(2025, time.May, 14, 0, 0, 0, 0, time.UTC))
+	if len(reports) != 1 {
+		t.Fatalf("len(reports) = %d, want 1", len(reports))
+	}
+
+	fake := &fakeMailer{}
+	delivery := &mailweave.TlsRptReportDelivery{Sender: "example.net", Mailer: fake, HTTPClient: server.Client()}
+	record := mailweave.TlsRptDnsRecord{
+		ReportsMailTransport:  []string{"mailto:tlsrpt%40example.com"},
+		ReportsHttpsTransport: []string{server.URL + "/tlsrpt"},
+	}
+
+	if err := delivery.Deliver(context.Background(), record, reports[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.messages) != 1 {
+		t.Fatalf("len(messages) = %d, want 1", len(fake.messages))
+	}
+	message := fake.messages[0]
+	if message.To[0] != "tlsrpt@example.com" {
+		t.Errorf("To = %s, want tlsrpt@example.com", message.To[0])
+	}
+	if message.Headers["TLS-Report-Domain"] != "example.com" {
+		t.Errorf("TLS-Report-Domain = %s, want example.com", message.Headers["TLS-Report-Domain"])
+	}
+	if message.ReportType != "tlsrpt" {
+		t.Errorf("ReportType = %s, want tlsrpt", message.ReportType)
+	}
+	if message.Attachments[0].ContentType != tlsrpt.MediaTypeGZIP {
+		t.Errorf("attachment ContentType = %s, want %s", message.Attachments[0].ContentType, tlsrpt.MediaTypeGZIP)
+	}
+
+	if contentType != tlsrpt.MediaTypeGZIP {
+		t.Errorf("Content-Type = %s, want %s", contentType, tlsrpt.MediaTypeGZIP)
+	}
+	if tlsrpt.DetectCompression(received) != tlsrpt.CompressionTypeGZIP {
+		t.Error("posted report is not gzip compressed")
+	}
+
+	t.Run("invalid mailto destination", func(t *testing.T) {
+		record := mailweave.TlsRptDnsRecord{ReportsMailTransport: []string{"tlsrpt@example.com"}}
+		if err := delivery.Deliver(context.Background(), record, reports[0]); err == nil {
+			t.Error("expected an error for an invalid mailto destination")
+		}
+	})
+
+	t.Run("nil report", func(t *testing.T) {
+		if err := delivery.Deliver(context.Background(), record, nil); err == nil {
+			t.Error("expected an error for a nil report")
+		}
+	})
+
+	t.Run("failing destination", func(t *testing.T) {
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer failing.Close()
+
+		record := mailweave.TlsRptDnsRecord{ReportsHttpsTransport: []string{failing.URL}}
+		if err := delivery.Deliver(context.Background(), record, reports[0]); err == nil {
+			t.Error("expected an error for a failing destination")
+		}
+	})
+}
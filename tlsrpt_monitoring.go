@@ -19,6 +19,20 @@ type TlsRptReportRow struct {
 
 	SuccessfulSessionCount int64
 	FailedSessionCount     int64
+
+	FailureDetails []TlsRptFailureDetail
+}
+
+type TlsRptFailureDetail struct {
+	ResultType          string
+	SendingMTAIP        string
+	ReceivingMxHostname string
+	ReceivingMxHelo     string
+	ReceivingIP         string
+	FailedSessionCount  int64
+	FailureReasonCode   string
+	// Operator guidance derived from the result type and the failure reason code.
+	Remediation string
 }
 
 type TlsRptReport struct {
@@ -36,6 +50,9 @@ type TlsRptReport struct {
 	EmailSender    string
 	EmailSubject   string
 	ReportFileName string
+	// Differences between the report filename and the content of the report,
+	// which flag the report as suspicious. See ReportFileNameMismatches.
+	ReportFileNameMismatches []string
 
 	// Content
 	TotalNumberOfSessions int64